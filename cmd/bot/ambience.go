@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+
+	"github.com/benjamonnguyen/pomomo-go"
+	"github.com/benjamonnguyen/pomomo-go/cmd/bot/models"
+)
+
+// AmbientPlayer loops a track in a voice channel between alerts
+type AmbientPlayer interface {
+	PlayAmbient(gID string, cID pomomo.VoiceChannelID, packets [][]byte)
+	PauseAmbient(gID string, cID pomomo.VoiceChannelID)
+	StopAmbient(gID string, cID pomomo.VoiceChannelID)
+}
+
+// ambience plays the guild's ambient track during the pomodoros of sessions started with it
+type ambience struct {
+	loadFn loadOpusAudio
+	player AmbientPlayer
+}
+
+func shouldPlayAmbient(s models.Session) bool {
+	return s.Settings.Ambient && s.Record.Status == pomomo.SessionRunning && s.Record.CurrentInterval == pomomo.PomodoroInterval
+}
+
+// Silence pauses the track if the session isn't in a running pomodoro, so that it's quiet before break alerts play
+func (a *ambience) Silence(s models.Session) {
+	if shouldPlayAmbient(s) {
+		return
+	}
+	if s.Record.Status == pomomo.SessionEnded || !s.Settings.Ambient {
+		a.player.StopAmbient(s.Record.GuildID, s.Record.VoiceCID)
+		return
+	}
+	a.player.PauseAmbient(s.Record.GuildID, s.Record.VoiceCID)
+}
+
+// Play starts or resumes the track if the session is in a running pomodoro. It's safe to call on every
+// update and picks up a newly uploaded track.
+func (a *ambience) Play(ctx context.Context, s models.Session) {
+	if !shouldPlayAmbient(s) {
+		return
+	}
+	packets := a.loadFn(ctx, s.Record.GuildID, AmbientAudio, 0) // volume only applies to alerts
+	if packets == nil {
+		// the track was removed mid-session
+		a.player.StopAmbient(s.Record.GuildID, s.Record.VoiceCID)
+		return
+	}
+	a.player.PlayAmbient(s.Record.GuildID, s.Record.VoiceCID, packets)
+}
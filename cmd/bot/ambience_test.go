@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/benjamonnguyen/pomomo-go"
+	"github.com/benjamonnguyen/pomomo-go/cmd/bot/models"
+)
+
+// fakeAmbientPlayer records the calls made to it
+type fakeAmbientPlayer struct {
+	calls []string
+}
+
+func (p *fakeAmbientPlayer) PlayAmbient(string, pomomo.VoiceChannelID, [][]byte) {
+	p.calls = append(p.calls, "play")
+}
+
+func (p *fakeAmbientPlayer) PauseAmbient(string, pomomo.VoiceChannelID) {
+	p.calls = append(p.calls, "pause")
+}
+
+func (p *fakeAmbientPlayer) StopAmbient(string, pomomo.VoiceChannelID) {
+	p.calls = append(p.calls, "stop")
+}
+
+func TestAmbience(t *testing.T) {
+	track := [][]byte{{1}}
+	session := func(ambient bool, status pomomo.SessionStatus, interval pomomo.SessionInterval) models.Session {
+		s := models.NewSession("s1", "g1", "t1", "v1", "m1", pomomo.SessionSettingsRecord{Ambient: ambient})
+		s.Record.Status = status
+		s.Record.CurrentInterval = interval
+		return s
+	}
+	tests := []struct {
+		name    string
+		session models.Session
+		track   [][]byte
+		want    []string
+	}{
+		{"pomodoro", session(true, pomomo.SessionRunning, pomomo.PomodoroInterval), track, []string{"play"}},
+		// the track is kept where it was so that it resumes after the break
+		{"break", session(true, pomomo.SessionRunning, pomomo.ShortBreakInterval), track, []string{"pause"}},
+		{"paused", session(true, pomomo.SessionPaused, pomomo.PomodoroInterval), track, []string{"pause"}},
+		{"lobby", session(true, pomomo.SessionLobby, 0), track, []string{"pause"}},
+		{"ended", session(true, pomomo.SessionEnded, pomomo.PomodoroInterval), track, []string{"stop"}},
+		{"not ambient", session(false, pomomo.SessionRunning, pomomo.ShortBreakInterval), track, []string{"stop"}},
+		{"track removed", session(true, pomomo.SessionRunning, pomomo.PomodoroInterval), nil, []string{"stop"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			player := &fakeAmbientPlayer{}
+			a := &ambience{
+				loadFn: func(context.Context, string, audio, int) [][]byte { return tt.track },
+				player: player,
+			}
+			a.Silence(tt.session)
+			a.Play(context.Background(), tt.session)
+			if !slices.Equal(player.calls, tt.want) {
+				t.Errorf("calls = %v, want %v", player.calls, tt.want)
+			}
+		})
+	}
+}
@@ -1,11 +1,17 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"embed"
-	"encoding/binary"
-	"io"
+	"fmt"
+	"maps"
 	"path"
+	"sync"
+	"time"
 
+	"github.com/benjamonnguyen/pomomo-go"
+	"github.com/benjamonnguyen/pomomo-go/dca"
 	"github.com/charmbracelet/log"
 )
 
@@ -15,56 +21,253 @@ const (
 	PomodoroAudio   audio = "pomodoro.dca"
 	LongBreakAudio  audio = "long_break.dca"
 	ShortBreakAudio audio = "short_break.dca"
+	WarningAudio    audio = "warning.dca"
+	// AmbientAudio has no default - it's only played if the guild uploaded one
+	AmbientAudio audio = "ambient.dca"
 )
 
-func newOpusAudioLoader(fs embed.FS) *opusAudioLoader {
-	audioPackets := make(map[audio][][]byte)
-	loadPackets := func(audio audio, fs embed.FS) {
-		log.Info("loading packets", "audio", audio)
-		f, err := fs.Open(path.Join("sounds", string(audio)))
-		if err != nil {
-			panic(err)
-		}
-		defer f.Close() //nolint
-
-		var frameLen int16
-		// Don't wait for the first tick, run immediately.
-		for {
-			err = binary.Read(f, binary.LittleEndian, &frameLen)
-			if err != nil {
-				if err == io.EOF {
-					return
-				}
-				panic("error reading file: " + err.Error())
-			}
+// alertSounds maps the default audio to the alert that guilds can replace it for
+var alertSounds = map[audio]pomomo.AlertSound{
+	PomodoroAudio:   pomomo.PomodoroSound,
+	LongBreakAudio:  pomomo.LongBreakSound,
+	ShortBreakAudio: pomomo.ShortBreakSound,
+	WarningAudio:    pomomo.WarningSound,
+	AmbientAudio:    pomomo.AmbientSound,
+}
 
-			// Read encoded pcm from dca file.
-			packet := make([]byte, frameLen)
-			if err := binary.Read(f, binary.LittleEndian, &packet); err != nil {
-				// Should not be any end of file errors
-				panic(err)
-			}
+const (
+	maxSoundUploadSize   = 1 << 20
+	maxSoundDuration     = 10 * time.Second
+	maxAmbientUploadSize = 4 << 20
+	maxAmbientDuration   = 5 * time.Minute
+)
 
-			// Append encoded pcm data to the buffer.
-			audioPackets[audio] = append(audioPackets[audio], packet)
-		}
+// soundLimits returns the max upload size and duration for the sound - ambient tracks loop so they
+// can be much longer than alerts
+func soundLimits(sound pomomo.AlertSound) (int64, time.Duration) {
+	if sound == pomomo.AmbientSound {
+		return maxAmbientUploadSize, maxAmbientDuration
 	}
+	return maxSoundUploadSize, maxSoundDuration
+}
+
+type SoundRepo interface {
+	UpsertSound(context.Context, pomomo.GuildSoundRecord) error
+	DeleteSounds(ctx context.Context, guildID string, sounds ...pomomo.AlertSound) (int64, error)
+	GetSounds(ctx context.Context, guildID string) ([]pomomo.GuildSoundRecord, error)
+}
+
+// AudioLoader loads alert audio, preferring the guild's uploaded sounds over the defaults
+type AudioLoader interface {
+	// Load returns the audio at volume percent of its own, where zero plays it as is. Audio at other volumes
+	// is rendered in the background and played as is until it's ready.
+	Load(ctx context.Context, guildID string, a audio, volume int) [][]byte
+	// Prepare renders the guild's alerts at volume in the background so that they're ready to play
+	Prepare(guildID string, volume int)
+	// Invalidate drops the guild's cached sounds after they're changed
+	Invalidate(guildID string)
+}
+
+var _ AudioLoader = (*opusAudioLoader)(nil)
+
+func newOpusAudioLoader(fs embed.FS, repo SoundRepo) *opusAudioLoader {
+	audioPackets := make(map[audio][][]byte)
 	for _, a := range []audio{
 		PomodoroAudio,
 		LongBreakAudio,
 		ShortBreakAudio,
+		WarningAudio,
 	} {
-		loadPackets(a, fs)
+		log.Info("loading packets", "audio", a)
+		f, err := fs.Open(path.Join("sounds", string(a)))
+		if err != nil {
+			panic(err)
+		}
+		packets, err := dca.Read(f)
+		_ = f.Close()
+		if err != nil {
+			panic(fmt.Errorf("error reading %s: %w", a, err))
+		}
+		audioPackets[a] = packets
 	}
 	return &opusAudioLoader{
-		audioPackets: audioPackets,
+		audioPackets:  audioPackets,
+		repo:          repo,
+		guildPackets:  make(map[string]map[audio][][]byte),
+		scaledPackets: make(map[scaledAudio][][]byte),
+		rendering:     make(map[scaledAudio]bool),
+		guildVolumes:  make(map[string]map[int]bool),
+		generations:   make(map[string]int),
+		renderSlots:   make(chan struct{}, maxConcurrentRenders),
 	}
 }
 
 type opusAudioLoader struct {
 	audioPackets map[audio][][]byte
+	repo         SoundRepo
+
+	mu sync.Mutex
+	// guild uploads are loaded on first use - guilds without any are cached as empty
+	guildPackets map[string]map[audio][][]byte
+	// volume variants are rendered in the background, keyed by the guild whose upload they're from
+	scaledPackets map[scaledAudio][][]byte
+	rendering     map[scaledAudio]bool
+	// the volumes each guild plays alerts at, which are re-rendered when its sounds change
+	guildVolumes map[string]map[int]bool
+	// incremented on invalidation so that renders of replaced uploads aren't cached
+	generations map[string]int
+	renderSlots chan struct{}
+}
+
+type scaledAudio struct {
+	guildID string // empty for the defaults, which are shared
+	a       audio
+	volume  int
 }
 
-func (m *opusAudioLoader) Load(audio audio) [][]byte {
-	return m.audioPackets[audio]
+// maxConcurrentRenders keeps volume renders, which decode and re-encode the whole sound, from starving alerts
+// of CPU when many sessions start at once
+const maxConcurrentRenders = 2
+
+// prepareTimeout bounds how long Prepare spends loading the guild's sounds
+var prepareTimeout = 30 * time.Second
+
+func (m *opusAudioLoader) Load(ctx context.Context, guildID string, a audio, volume int) [][]byte {
+	key := scaledAudio{guildID, a, volume}
+	packets := m.guildSounds(ctx, guildID)[a]
+	if packets == nil {
+		packets, key.guildID = m.audioPackets[a], ""
+	}
+	if packets == nil || volume <= 0 || volume == pomomo.DefaultVolume {
+		return packets
+	}
+	if scaled := m.scaled(guildID, key, packets); scaled != nil {
+		return scaled
+	}
+	return packets
+}
+
+func (m *opusAudioLoader) Prepare(guildID string, volume int) {
+	if volume <= 0 || volume == pomomo.DefaultVolume {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), prepareTimeout)
+		defer cancel()
+		for _, a := range []audio{PomodoroAudio, ShortBreakAudio, LongBreakAudio, WarningAudio} {
+			// starts rendering whatever isn't ready
+			m.Load(ctx, guildID, a, volume)
+		}
+	}()
+}
+
+// scaled returns the rendered audio for the guild, or nil if it isn't ready yet, in which case it's rendered
+// in the background
+func (m *opusAudioLoader) scaled(guildID string, key scaledAudio, packets [][]byte) [][]byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.guildVolumes[guildID] == nil {
+		m.guildVolumes[guildID] = make(map[int]bool)
+	}
+	m.guildVolumes[guildID][key.volume] = true
+	if scaled, ok := m.scaledPackets[key]; ok {
+		return scaled
+	}
+	if !m.rendering[key] {
+		m.rendering[key] = true
+		go m.render(key, packets, m.generations[key.guildID])
+	}
+	return nil
+}
+
+func (m *opusAudioLoader) render(key scaledAudio, packets [][]byte, generation int) {
+	m.renderSlots <- struct{}{}
+	scaled, err := dca.Scale(packets, float64(key.volume)/100)
+	<-m.renderSlots
+	if err != nil {
+		// cached anyway so that it isn't retried on every alert
+		log.Error("failed to render audio volume - playing as is", "guildID", key.guildID, "audio", key.a, "volume", key.volume, "err", err)
+		scaled = packets
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.generations[key.guildID] != generation {
+		return
+	}
+	delete(m.rendering, key)
+	m.scaledPackets[key] = scaled
+}
+
+func (m *opusAudioLoader) Invalidate(guildID string) {
+	m.mu.Lock()
+	delete(m.guildPackets, guildID)
+	for key := range m.scaledPackets {
+		if key.guildID == guildID {
+			delete(m.scaledPackets, key)
+		}
+	}
+	for key := range m.rendering {
+		if key.guildID == guildID {
+			delete(m.rendering, key)
+		}
+	}
+	m.generations[guildID]++
+	volumes := maps.Clone(m.guildVolumes[guildID])
+	m.mu.Unlock()
+
+	for volume := range volumes {
+		m.Prepare(guildID, volume)
+	}
+}
+
+func (m *opusAudioLoader) guildSounds(ctx context.Context, guildID string) map[audio][][]byte {
+	if guildID == "" || m.repo == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if packets, ok := m.guildPackets[guildID]; ok {
+		return packets
+	}
+
+	sounds, err := m.repo.GetSounds(ctx, guildID)
+	if err != nil {
+		// not cached so that it's retried on the next alert
+		log.Error("failed to get guild sounds - using defaults", "guildID", guildID, "err", err)
+		return nil
+	}
+	packets := make(map[audio][][]byte)
+	for a, alert := range alertSounds {
+		for _, s := range sounds {
+			if s.Sound != alert {
+				continue
+			}
+			p, err := dca.Read(bytes.NewReader(s.DCA))
+			if err != nil {
+				log.Error("failed to read guild sound - using default", "guildID", guildID, "sound", s.Sound, "err", err)
+				continue
+			}
+			packets[a] = p
+		}
+	}
+	m.guildPackets[guildID] = packets
+	return packets
+}
+
+// decodeSound reads the opus frames of an uploaded WAV, Ogg Opus or DCA file and checks that they can be
+// sent as is and are within maxDuration
+func decodeSound(fileName string, data []byte, maxDuration time.Duration) ([][]byte, time.Duration, error) {
+	frames, err := dca.ReadFile(fileName, bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, err
+	}
+	duration, err := dca.Validate(frames)
+	if err != nil {
+		return nil, 0, err
+	}
+	if duration > maxDuration {
+		return nil, 0, fmt.Errorf("this sound can be at most %d seconds long", int(maxDuration.Seconds()))
+	}
+	return frames, duration, nil
 }
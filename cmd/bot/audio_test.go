@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/benjamonnguyen/pomomo-go"
+	"github.com/benjamonnguyen/pomomo-go/dca"
+)
+
+type fakeSoundRepo struct {
+	sounds []pomomo.GuildSoundRecord
+	err    error
+}
+
+func (r *fakeSoundRepo) UpsertSound(_ context.Context, s pomomo.GuildSoundRecord) error {
+	r.sounds = append(r.sounds, s)
+	return nil
+}
+
+func (r *fakeSoundRepo) DeleteSounds(context.Context, string, ...pomomo.AlertSound) (int64, error) {
+	n := len(r.sounds)
+	r.sounds = nil
+	return int64(n), nil
+}
+
+func (r *fakeSoundRepo) GetSounds(_ context.Context, guildID string) ([]pomomo.GuildSoundRecord, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	var sounds []pomomo.GuildSoundRecord
+	for _, s := range r.sounds {
+		if s.GuildID == guildID {
+			sounds = append(sounds, s)
+		}
+	}
+	return sounds, nil
+}
+
+func TestOpusAudioLoaderRendersInBackground(t *testing.T) {
+	ctx := context.Background()
+	m := newOpusAudioLoader(sounds, &fakeSoundRepo{})
+	unscaled := m.audioPackets[WarningAudio]
+
+	if got := m.Load(ctx, "g1", WarningAudio, pomomo.DefaultVolume); !samePackets(got, unscaled) {
+		t.Error("Load() at the default volume isn't the audio as is")
+	}
+	// the alert path never waits on a render
+	if got := m.Load(ctx, "g1", WarningAudio, 50); !samePackets(got, unscaled) {
+		t.Error("Load() before the render finished isn't the audio as is")
+	}
+	scaled := waitRendered(t, m, scaledAudio{"", WarningAudio, 50})
+	if got := m.Load(ctx, "g1", WarningAudio, 50); !samePackets(got, scaled) {
+		t.Error("Load() after the render finished isn't the rendered audio")
+	}
+	// the defaults' renders are shared between guilds
+	if got := m.Load(ctx, "g2", WarningAudio, 50); !samePackets(got, scaled) {
+		t.Error("Load() for another guild isn't the shared render")
+	}
+}
+
+func TestOpusAudioLoaderPrepare(t *testing.T) {
+	m := newOpusAudioLoader(sounds, &fakeSoundRepo{})
+	m.Prepare("g1", pomomo.DefaultVolume)
+	m.Prepare("g1", 150)
+	for _, a := range []audio{PomodoroAudio, ShortBreakAudio, LongBreakAudio, WarningAudio} {
+		waitRendered(t, m, scaledAudio{"", a, 150})
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key := range m.scaledPackets {
+		if key.volume != 150 {
+			t.Errorf("rendered %v, want only volume 150", key)
+		}
+	}
+}
+
+func TestOpusAudioLoaderInvalidate(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeSoundRepo{}
+	m := newOpusAudioLoader(sounds, repo)
+	m.Load(ctx, "g1", PomodoroAudio, 50)
+	waitRendered(t, m, scaledAudio{"", PomodoroAudio, 50})
+
+	// the upload is rendered at the volume the guild was using without another alert
+	upload := m.audioPackets[ShortBreakAudio]
+	encoded, err := dca.Marshal(upload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = repo.UpsertSound(ctx, pomomo.GuildSoundRecord{GuildID: "g1", Sound: pomomo.PomodoroSound, DCA: encoded})
+	m.Invalidate("g1")
+	scaled := waitRendered(t, m, scaledAudio{"g1", PomodoroAudio, 50})
+	if got := m.Load(ctx, "g1", PomodoroAudio, 50); !samePackets(got, scaled) {
+		t.Error("Load() isn't the rendered upload")
+	}
+	if got := m.Load(ctx, "g1", PomodoroAudio, 0); !slices.EqualFunc(got, upload, bytes.Equal) {
+		t.Error("Load() at volume 0 isn't the upload as is")
+	}
+
+	// renders of replaced uploads are dropped
+	_, _ = repo.DeleteSounds(ctx, "g1")
+	m.mu.Lock()
+	stale := m.generations["g1"]
+	m.mu.Unlock()
+	m.Invalidate("g1")
+	m.render(scaledAudio{"g1", PomodoroAudio, 75}, upload, stale)
+	m.mu.Lock()
+	_, ok := m.scaledPackets[scaledAudio{"g1", PomodoroAudio, 75}]
+	m.mu.Unlock()
+	if ok {
+		t.Error("render of a replaced upload was cached")
+	}
+}
+
+func TestOpusAudioLoaderGuildSounds(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeSoundRepo{}
+	m := newOpusAudioLoader(sounds, repo)
+	upload := m.audioPackets[ShortBreakAudio]
+	encoded, err := dca.Marshal(upload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.sounds = []pomomo.GuildSoundRecord{
+		{GuildID: "g1", Sound: pomomo.PomodoroSound, DCA: encoded},
+		{GuildID: "g1", Sound: pomomo.AmbientSound, DCA: encoded},
+		{GuildID: "g1", Sound: pomomo.WarningSound, DCA: []byte("not dca")},
+	}
+
+	tests := []struct {
+		name    string
+		guildID string
+		a       audio
+		want    [][]byte
+	}{
+		{"upload", "g1", PomodoroAudio, upload},
+		{"not uploaded", "g1", LongBreakAudio, m.audioPackets[LongBreakAudio]},
+		{"unreadable upload", "g1", WarningAudio, m.audioPackets[WarningAudio]},
+		{"ambient upload", "g1", AmbientAudio, upload},
+		{"other guild", "g2", PomodoroAudio, m.audioPackets[PomodoroAudio]},
+		// there's no default ambient track
+		{"no ambient", "g2", AmbientAudio, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.Load(ctx, tt.guildID, tt.a, 0); !slices.EqualFunc(got, tt.want, bytes.Equal) {
+				t.Errorf("Load() = %d packets, want %d", len(got), len(tt.want))
+			}
+		})
+	}
+
+	t.Run("repo error", func(t *testing.T) {
+		repo.err = errors.New("database is locked")
+		if got := m.Load(ctx, "g3", PomodoroAudio, 0); !samePackets(got, m.audioPackets[PomodoroAudio]) {
+			t.Error("Load() isn't the default when the guild's sounds can't be read")
+		}
+		// failures aren't cached so the upload is picked up once the repo recovers
+		repo.err = nil
+		repo.sounds = append(repo.sounds, pomomo.GuildSoundRecord{GuildID: "g3", Sound: pomomo.PomodoroSound, DCA: encoded})
+		if got := m.Load(ctx, "g3", PomodoroAudio, 0); !slices.EqualFunc(got, upload, bytes.Equal) {
+			t.Error("Load() isn't the upload after the repo recovered")
+		}
+	})
+}
+
+func TestDecodeSound(t *testing.T) {
+	frames := newOpusAudioLoader(sounds, nil).audioPackets[WarningAudio]
+	duration, err := dca.Validate(frames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := dca.Marshal(frames)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		fileName    string
+		data        []byte
+		maxDuration time.Duration
+		wantErr     bool
+	}{
+		{"dca", "warning.dca", encoded, maxSoundDuration, false},
+		{"extension case", "WARNING.DCA", encoded, maxSoundDuration, false},
+		{"at max duration", "warning.dca", encoded, duration, false},
+		{"too long", "warning.dca", encoded, duration - time.Millisecond, true},
+		{"unsupported type", "warning.mp3", encoded, maxSoundDuration, true},
+		{"corrupt", "warning.dca", []byte("not dca"), maxSoundDuration, true},
+		{"empty", "warning.dca", nil, maxSoundDuration, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotDuration, err := decodeSound(tt.fileName, tt.data, tt.maxDuration)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeSound() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !slices.EqualFunc(got, frames, bytes.Equal) || gotDuration != duration {
+				t.Errorf("decodeSound() = %d frames, %v, want %d frames, %v", len(got), gotDuration, len(frames), duration)
+			}
+		})
+	}
+}
+
+func TestDownloadAttachment(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(bytes.Repeat([]byte{1}, 100))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		path    string
+		limit   int64
+		wantErr bool
+	}{
+		{"under limit", "/sound", 101, false},
+		{"at limit", "/sound", 100, false},
+		{"over limit", "/sound", 99, true},
+		{"not found", "/missing", 100, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := downloadAttachment(context.Background(), srv.Client(), srv.URL+tt.path, tt.limit)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("downloadAttachment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(data) != 100 {
+				t.Errorf("downloaded %d bytes, want 100", len(data))
+			}
+		})
+	}
+}
+
+// waitRendered waits for the audio to be rendered and returns it
+func waitRendered(t *testing.T, m *opusAudioLoader, key scaledAudio) [][]byte {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		m.mu.Lock()
+		scaled, ok := m.scaledPackets[key]
+		m.mu.Unlock()
+		if ok {
+			return scaled
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("%v wasn't rendered", key)
+	return nil
+}
+
+// samePackets reports whether a and b are the same slice rather than equal contents
+func samePackets(a, b [][]byte) bool {
+	return len(a) == len(b) && (len(a) == 0 || &a[0] == &b[0])
+}
@@ -23,21 +23,33 @@ type autoshusher struct {
 }
 
 func (a *autoshusher) Autoshush(ctx context.Context, participants []models.Participant, before, curr models.Session) {
-	if before.Record.CurrentInterval == curr.Record.CurrentInterval {
+	if before.Record.CurrentInterval == curr.Record.CurrentInterval && before.Record.Status == curr.Record.Status {
+		shushChanged := before.Settings.NoMute != curr.Settings.NoMute || before.Settings.NoDeafen != curr.Settings.NoDeafen
+		if shushChanged && curr.Record.Status == pomomo.SessionRunning && curr.Record.CurrentInterval == pomomo.PomodoroInterval {
+			// settings were edited mid-pomodoro - participants are already shushed so there's no voice state to sync
+			a.reshush(ctx, participants, curr)
+		}
 		return
 	}
-	var wg sync.WaitGroup
-	skipped := curr.Stats.Skips > before.Stats.Skips // don't play interval alert if interval was skipped
+	if curr.Record.Status == pomomo.SessionPaused {
+		// nobody is shushed while paused
+		if before.Record.Status != pomomo.SessionPaused {
+			a.unshush(ctx, participants)
+		}
+		return
+	}
+	if before.Record.Status == pomomo.SessionPaused {
+		// resumed - no interval alert since the interval is already underway
+		if curr.Record.CurrentInterval == pomomo.PomodoroInterval {
+			a.shush(ctx, participants, curr)
+		}
+		return
+	}
+
+	skipped := curr.Record.Stats.Skips > before.Record.Stats.Skips // don't play interval alert if interval was skipped
 	if curr.Record.CurrentInterval != pomomo.PomodoroInterval {
 		// unshush before playing
-		for _, p := range participants {
-			wg.Go(func() {
-				if err := restoreVoiceState(ctx, a.vs, p); err != nil {
-					log.Error(err)
-				}
-			})
-		}
-		wg.Wait()
+		a.unshush(ctx, participants)
 		if !skipped {
 			if err := playIntervalAlert(ctx, curr, a.loadFn, a.sendFn); err != nil {
 				log.Error("failed to play interval alert", "guildID", curr.Record.GuildID, "channelID", curr.Record.VoiceCID, "err", err)
@@ -50,39 +62,68 @@ func (a *autoshusher) Autoshush(ctx context.Context, participants []models.Parti
 				log.Error("failed to play interval alert", "guildID", curr.Record.GuildID, "channelID", curr.Record.VoiceCID, "err", err)
 			}
 		}
-		// update voice state in case it's been changed during a break
-		var toUpdate []models.Participant
-		var mu sync.Mutex
-		for _, p := range participants {
-			wg.Go(func() {
-				currVs, err := getVoiceState(ctx, a.vs, p)
+		a.shush(ctx, participants, curr)
+	}
+}
+
+func (a *autoshusher) unshush(ctx context.Context, participants []models.Participant) {
+	var wg sync.WaitGroup
+	for _, p := range participants {
+		wg.Go(func() {
+			if err := restoreVoiceState(ctx, a.vs, p); err != nil {
+				log.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func (a *autoshusher) reshush(ctx context.Context, participants []models.Participant, curr models.Session) {
+	var wg sync.WaitGroup
+	for _, p := range participants {
+		wg.Go(func() {
+			if err := updateVoiceState(ctx, a.vs, !curr.Settings.NoMute, !curr.Settings.NoDeafen, p); err != nil {
+				log.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func (a *autoshusher) shush(ctx context.Context, participants []models.Participant, curr models.Session) {
+	// update voice state in case it's been changed during a break
+	var wg sync.WaitGroup
+	var toUpdate []models.Participant
+	var mu sync.Mutex
+	for _, p := range participants {
+		wg.Go(func() {
+			currVs, err := getVoiceState(ctx, a.vs, p)
+			if err != nil {
+				log.Error("failed GetVoiceState", "err", err, "sid", p.Record.SessionID, "uid", p.Record.UserID)
+				return
+			}
+			if currVs.Mute != p.Record.IsMuted || currVs.Deaf != p.Record.IsDeafened {
+				updated, err := a.pm.UpdateVoiceState(ctx, p.Record.UserID, p.Record.VoiceCID, currVs)
 				if err != nil {
-					log.Error("failed GetVoiceState", "err", err, "sid", p.Record.SessionID, "uid", p.Record.UserID)
+					log.Error("failed UpdateVoiceState in sync", "err", err, "sid", p.Record.SessionID, "uid", p.Record.UserID)
 					return
 				}
-				if currVs.Mute != p.Record.IsMuted || currVs.Deaf != p.Record.IsDeafened {
-					updated, err := a.pm.UpdateVoiceState(ctx, p.Record.UserID, p.Record.VoiceCID, currVs)
-					if err != nil {
-						log.Error("failed UpdateVoiceState in sync", "err", err, "sid", p.Record.SessionID, "uid", p.Record.UserID)
-						return
-					}
-					mu.Lock()
-					defer mu.Unlock()
-					toUpdate = append(toUpdate, updated)
-				} else {
-					mu.Lock()
-					defer mu.Unlock()
-					toUpdate = append(toUpdate, p)
-				}
-			})
-		}
-		wg.Wait()
-		go func() {
-			for _, p := range toUpdate {
-				if err := updateVoiceState(ctx, a.vs, !curr.Settings.NoMute, !curr.Settings.NoDeafen, p); err != nil {
-					log.Error(err)
-				}
+				mu.Lock()
+				defer mu.Unlock()
+				toUpdate = append(toUpdate, updated)
+			} else {
+				mu.Lock()
+				defer mu.Unlock()
+				toUpdate = append(toUpdate, p)
 			}
-		}()
+		})
 	}
+	wg.Wait()
+	go func() {
+		for _, p := range toUpdate {
+			if err := updateVoiceState(ctx, a.vs, !curr.Settings.NoMute, !curr.Settings.NoDeafen, p); err != nil {
+				log.Error(err)
+			}
+		}
+	}()
 }
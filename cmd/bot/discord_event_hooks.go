@@ -2,19 +2,31 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/benjamonnguyen/pomomo-go"
 	"github.com/benjamonnguyen/pomomo-go/cmd/bot/models"
+	"github.com/benjamonnguyen/pomomo-go/dca"
+	"github.com/benjamonnguyen/pomomo-go/sqlite"
 	"github.com/bwmarrin/discordgo"
 	"github.com/charmbracelet/log"
 )
 
 const (
 	defaultErrorMsg = "Looks like something went wrong. Try again in a bit or reach out to support."
+	noSessionMsg    = "Couldn't find an active session in this channel or your voice channel."
+	notHostMsg      = "Only the session host or a moderator can do that."
+	notStartedMsg   = "This session hasn't started yet."
 )
 
-func RemoveParticipantOnVoiceChannelLeave(ctx context.Context, vs VoiceStateAdapter, pm ParticipantsManager, s *discordgo.Session, u *discordgo.VoiceStateUpdate) bool {
+func RemoveParticipantOnVoiceChannelLeave(ctx context.Context, sessionManager SessionManager, vs VoiceStateAdapter, pm ParticipantsManager, sr StatsRecorder, s *discordgo.Session, u *discordgo.VoiceStateUpdate) bool {
 	if u.BeforeUpdate == nil {
 		// don't need to handle joins since participation is removed on leave
 		return false
@@ -23,10 +35,15 @@ func RemoveParticipantOnVoiceChannelLeave(ctx context.Context, vs VoiceStateAdap
 		return false
 	}
 	cid := pomomo.VoiceChannelID(u.BeforeUpdate.ChannelID)
+	// get session before acquiring voice channel lock since session updates acquire it in the reverse order
+	session, err := sessionManager.GetVoiceSession(cid)
 	unlock := pm.AcquireVoiceChannelLock(cid)
 	defer unlock()
 
 	if p := pm.Get(u.UserID, cid); p != (models.Participant{}) {
+		if err == nil {
+			sr.RecordLeave(ctx, p, session)
+		}
 		if err := restoreVoiceState(ctx, vs, p); err != nil {
 			log.Error("failed voice state restore on voice channel leave", "err", err, "gid", u.GuildID, "uid", u.UserID)
 			if _, err := pm.DetachFromChannel(ctx, u.UserID, cid); err != nil {
@@ -68,7 +85,7 @@ func RestoreParticipantVoiceStateOnChannelJoin(ctx context.Context, vs VoiceStat
 	return false
 }
 
-func StartSession(ctx context.Context, sessionManager SessionManager, dm DiscordMessenger, pp ParticipantsManager, s *discordgo.Session, m *discordgo.InteractionCreate) bool {
+func StartSession(ctx context.Context, sessionManager SessionManager, guildRepo GuildSettingsRepo, presetRepo PresetRepo, audioLoader AudioLoader, dm DiscordMessenger, pp ParticipantsManager, sr StatsRecorder, s *discordgo.Session, m *discordgo.InteractionCreate) bool {
 	if m.Type != discordgo.InteractionApplicationCommand {
 		return false
 	}
@@ -78,50 +95,32 @@ func StartSession(ctx context.Context, sessionManager SessionManager, dm Discord
 		return false
 	}
 
-	// Parse command options with defaults
-	settings := pomomo.SessionSettingsRecord{
-		Pomodoro:   20 * time.Minute,
-		ShortBreak: 5 * time.Minute,
-		LongBreak:  15 * time.Minute,
-		Intervals:  4,
-		NoMute:     false,
-		NoDeafen:   false,
+	guildSettings, err := getGuildSettings(ctx, guildRepo, m.GuildID)
+	if err != nil {
+		log.Error("failed to get guild settings - using defaults", "gid", m.GuildID, "err", err)
 	}
-	for _, opt := range data.Options {
-		switch opt.Name {
-		case pomomo.PomodoroOption, pomomo.ShortBreakOption, pomomo.LongBreakOption, pomomo.IntervalsOption:
-			if val, ok := opt.Value.(float64); ok {
-				intVal := int(val)
-				switch opt.Name {
-				case pomomo.PomodoroOption:
-					settings.Pomodoro = time.Duration(intVal) * time.Minute
-				case pomomo.ShortBreakOption:
-					settings.ShortBreak = time.Duration(intVal) * time.Minute
-				case pomomo.LongBreakOption:
-					settings.LongBreak = time.Duration(intVal) * time.Minute
-				case pomomo.IntervalsOption:
-					settings.Intervals = intVal
-				}
-			}
-		case pomomo.NoMuteOption:
-			if val, ok := opt.Value.(bool); ok {
-				settings.NoMute = val
-			}
-		case pomomo.NoDeafenOption:
-			if val, ok := opt.Value.(bool); ok {
-				settings.NoDeafen = val
-			}
+
+	settings, lobby, err := parseStartOptions(ctx, presetRepo, guildSettings, GetUser(m.Interaction).ID, data.Options)
+	if err != nil {
+		if err := dm.RespondEphemeral(m.Interaction, TextDisplay(err.Error())); err != nil {
+			log.Error(err)
 		}
+		return true
 	}
-
-	// TODO multisession
-	if sessionManager.GuildSessionCnt(m.GuildID) > 0 {
-		if _, err := dm.Respond(m.Interaction, false, TextDisplay("Pomomo is limited to one session per server for now.")); err != nil {
+	if settings.Ambient && audioLoader.Load(ctx, m.GuildID, AmbientAudio, 0) == nil {
+		msg := fmt.Sprintf("This server doesn't have an ambient track. Upload one with `/%s %s`.", pomomo.SoundCommand.Name, pomomo.SoundSetSubcommand)
+		if err := dm.RespondEphemeral(m.Interaction, TextDisplay(msg)); err != nil {
 			log.Error(err)
 		}
 		return true
 	}
 
+	if !guildSettings.AllowsTextChannel(pomomo.TextChannelID(m.ChannelID)) {
+		if err := dm.RespondEphemeral(m.Interaction, TextDisplay("Sessions can't be started in this channel.")); err != nil {
+			log.Error(err)
+		}
+		return true
+	}
 	if sessionManager.HasSession(m.ChannelID) {
 		if _, err := dm.Respond(m.Interaction, false, TextDisplay("This channel already has an active session.")); err != nil {
 			log.Error(err)
@@ -139,6 +138,12 @@ func StartSession(ctx context.Context, sessionManager SessionManager, dm Discord
 		}
 		return true
 	}
+	if !guildSettings.AllowsVoiceChannel(pomomo.VoiceChannelID(vs.ChannelID)) {
+		if err := dm.RespondEphemeral(m.Interaction, TextDisplay("Sessions can't be started in your voice channel.")); err != nil {
+			log.Error(err)
+		}
+		return true
+	}
 	if sessionManager.HasVoiceSession(vs.ChannelID) {
 		_, err = dm.Respond(m.Interaction, false, TextDisplay("Your voice channel already has an active session. Please join another voice channel and try again."))
 		if err != nil {
@@ -148,19 +153,27 @@ func StartSession(ctx context.Context, sessionManager SessionManager, dm Discord
 	}
 
 	session := models.NewSession("", m.GuildID, m.ChannelID, vs.ChannelID, "", settings)
-	session.GoNextInterval(false) // initialize fields for display - "real" session is created by sessionManager
+	session.Record.HostUserID = m.Member.User.ID
+	if lobby > 0 {
+		session.OpenLobby(lobby)
+	} else {
+		session.GoNextInterval(false) // initialize fields for display - "real" session is created by sessionManager
+	}
 	msg, err := dm.Respond(m.Interaction, true, SessionMessageComponents(session)...)
 	if err != nil {
 		log.Error(err)
 		return true
 	}
 
+	// alerts play as is until they're rendered at the session's volume
+	audioLoader.Prepare(m.GuildID, settings.Volume)
 	session, err = sessionManager.StartSession(ctx, startSessionRequest{
 		guildID:   m.GuildID,
 		textCID:   m.ChannelID,
 		voiceCID:  vs.ChannelID,
 		messageID: msg.ID,
 		settings:  settings,
+		lobby:     lobby,
 		user: struct {
 			id   string
 			mute bool
@@ -179,6 +192,9 @@ func StartSession(ctx context.Context, sessionManager SessionManager, dm Discord
 		return true
 	}
 	log.Info("started session", "id", session.ID)
+	if p := pp.Get(m.Member.User.ID, session.Record.VoiceCID); p != (models.Participant{}) {
+		sr.RecordJoin(ctx, p)
+	}
 
 	if err := s.ChannelMessagePin(m.ChannelID, msg.ID); err != nil {
 		log.Error("failed to pin message", "err", err)
@@ -188,18 +204,132 @@ func StartSession(ctx context.Context, sessionManager SessionManager, dm Discord
 	return true
 }
 
-func SkipInterval(ctx context.Context, sessionManager SessionManager, dm DiscordMessenger, s *discordgo.Session, m *discordgo.InteractionCreate) bool {
-	if m.Type != discordgo.InteractionMessageComponent {
-		return false
+// parseStartOptions applies the /start options over the guild's defaults. A preset is applied first so that
+// explicit options override it. The returned error is meant for the user.
+func parseStartOptions(ctx context.Context, presetRepo PresetRepo, guildSettings pomomo.GuildSettingsRecord, userID string, options []*discordgo.ApplicationCommandInteractionDataOption) (pomomo.SessionSettingsRecord, time.Duration, error) {
+	settings := guildSettings.SessionDefaults()
+	var lobby time.Duration
+	for _, opt := range options {
+		if opt.Name != pomomo.PresetOption {
+			continue
+		}
+		presets, err := availablePresets(ctx, presetRepo, guildSettings.GuildID, userID)
+		if err != nil {
+			log.Error("failed to get presets", "gid", guildSettings.GuildID, "err", err)
+		}
+		preset, ok := findPreset(presets, opt.StringValue())
+		if !ok {
+			return settings, 0, fmt.Errorf("Couldn't find preset \"%s\".", opt.StringValue())
+		}
+		settings = preset.Apply(settings)
+	}
+	for _, opt := range options {
+		switch opt.Name {
+		case pomomo.PomodoroOption, pomomo.ShortBreakOption, pomomo.LongBreakOption, pomomo.IntervalsOption:
+			if val, ok := opt.Value.(float64); ok {
+				intVal := int(val)
+				switch opt.Name {
+				case pomomo.PomodoroOption:
+					settings.Pomodoro = time.Duration(intVal) * time.Minute
+				case pomomo.ShortBreakOption:
+					settings.ShortBreak = time.Duration(intVal) * time.Minute
+				case pomomo.LongBreakOption:
+					settings.LongBreak = time.Duration(intVal) * time.Minute
+				case pomomo.IntervalsOption:
+					settings.Intervals = intVal
+				}
+			}
+		case pomomo.NoMuteOption:
+			if val, ok := opt.Value.(bool); ok {
+				settings.NoMute = val
+			}
+		case pomomo.NoDeafenOption:
+			if val, ok := opt.Value.(bool); ok {
+				settings.NoDeafen = val
+			}
+		case pomomo.SequenceOption:
+			seq, err := pomomo.ParseIntervalSequence(opt.StringValue())
+			if err != nil {
+				return settings, 0, fmt.Errorf("Invalid sequence: %v.", err)
+			}
+			settings.Sequence = seq
+		case pomomo.FlowtimeOption:
+			if val, ok := opt.Value.(bool); ok && val {
+				settings.Mode = pomomo.FlowtimeMode
+			}
+		case pomomo.BreakRatioOption:
+			if val, ok := opt.Value.(float64); ok {
+				settings.BreakRatio = val / 100
+			}
+		case pomomo.LobbyOption:
+			if val, ok := opt.Value.(float64); ok {
+				lobby = time.Duration(val) * time.Minute
+			}
+		case pomomo.RoundsOption:
+			if val, ok := opt.Value.(float64); ok {
+				settings.GoalRounds = int(val)
+			}
+		case pomomo.WarningOption:
+			if val, ok := opt.Value.(float64); ok {
+				settings.Warning = time.Duration(val) * time.Minute
+			}
+		case pomomo.AmbientOption:
+			if val, ok := opt.Value.(bool); ok {
+				settings.Ambient = val
+			}
+		case pomomo.VolumeOption:
+			if val, ok := opt.Value.(float64); ok {
+				settings.Volume = int(val)
+			}
+		case pomomo.UntilOption:
+			hour, minute, err := pomomo.ParseTimeOfDay(opt.StringValue())
+			if err != nil {
+				return settings, 0, fmt.Errorf("Invalid end time: %v.", err)
+			}
+			// the next occurrence of the time, which may be tomorrow
+			until := pomomo.Recurrence{Days: pomomo.Everyday, Hour: hour, Minute: minute}
+			settings.GoalUntil = until.Next(time.Now(), guildSettings.Location())
+		}
+	}
+	if settings.Mode == pomomo.FlowtimeMode && len(settings.Sequence) > 0 {
+		return settings, 0, fmt.Errorf("Flowtime sessions can't use a sequence.")
 	}
+	return settings, lobby, nil
+}
 
-	data := m.MessageComponentData()
-	id, err := FromCustomID(data.CustomID)
-	if err != nil {
+func SkipInterval(ctx context.Context, sessionManager SessionManager, auth SessionAuthorizer, dm DiscordMessenger, s *discordgo.Session, m *discordgo.InteractionCreate) bool {
+	cid, ok := matchSessionInteraction(sessionManager, s, m, pomomo.SkipCommand.Name, "skip")
+	if !ok {
 		return false
 	}
-	if id.Type != "skip" {
-		return false
+	if !authorizeSessionInteraction(ctx, sessionManager, auth, dm, m, cid) {
+		return true
+	}
+
+	if m.Type == discordgo.InteractionApplicationCommand {
+		if cid == "" {
+			respondNoSession(dm, m)
+			return true
+		}
+		if existing, err := sessionManager.GetSession(cid); err == nil && existing.Record.Status == pomomo.SessionLobby {
+			if _, err := dm.Respond(m.Interaction, false, TextDisplay(notStartedMsg)); err != nil {
+				log.Error(err)
+			}
+			return true
+		}
+		session, err := sessionManager.SkipInterval(ctx, cid)
+		if err != nil {
+			log.Error("failed to skip interval", "err", err)
+			if _, err := dm.Respond(m.Interaction, false, TextDisplay(defaultErrorMsg)); err != nil {
+				log.Error(err)
+			}
+			return true
+		}
+		log.Info("skipped interval", "new", session.Record.CurrentInterval)
+		if _, err := dm.Respond(m.Interaction, false, TextDisplay(fmt.Sprintf("Skipped to %s.", session.Record.CurrentInterval))); err != nil {
+			log.Error(err)
+		}
+		return true
 	}
 
 	followup, err := dm.DeferMessageUpdate(m.Interaction)
@@ -208,7 +338,7 @@ func SkipInterval(ctx context.Context, sessionManager SessionManager, dm Discord
 		return true
 	}
 
-	session, err := sessionManager.SkipInterval(ctx, id.TextCID)
+	session, err := sessionManager.SkipInterval(ctx, cid)
 	if err != nil {
 		log.Error("failed to skip interval", "err", err)
 		components := append(SessionMessageComponents(session), TextDisplay(defaultErrorMsg))
@@ -228,19 +358,115 @@ func SkipInterval(ctx context.Context, sessionManager SessionManager, dm Discord
 	return true
 }
 
-func EndSession(ctx context.Context, sessionManager SessionManager, s *discordgo.Session, m *discordgo.InteractionCreate) bool {
-	if m.Type != discordgo.InteractionMessageComponent {
+// StartNow ends the lobby countdown and starts the first interval
+func StartNow(ctx context.Context, sessionManager SessionManager, auth SessionAuthorizer, dm DiscordMessenger, s *discordgo.Session, m *discordgo.InteractionCreate) bool {
+	cid, ok := matchSessionInteraction(sessionManager, s, m, "", "start_now")
+	if !ok {
 		return false
 	}
+	if !authorizeSessionInteraction(ctx, sessionManager, auth, dm, m, cid) {
+		return true
+	}
 
-	data := m.MessageComponentData()
-	id, err := FromCustomID(data.CustomID)
+	followup, err := dm.DeferMessageUpdate(m.Interaction)
+	if err != nil {
+		log.Error(err)
+		return true
+	}
+	session, err := sessionManager.StartNow(ctx, cid)
 	if err != nil {
+		// leave the session message as is, e.g. a stale button after the countdown expired
+		log.Error("failed to start session from lobby", "textCID", cid, "err", err)
+		return true
+	}
+	log.Info("started session from lobby", "id", session.ID)
+	if _, err := followup(SessionMessageComponents(session)...); err != nil {
+		log.Error(err)
+	}
+	return true
+}
+
+// TakeBreak ends a flowtime pomodoro with a break proportional to the focus
+func TakeBreak(ctx context.Context, sessionManager SessionManager, auth SessionAuthorizer, dm DiscordMessenger, s *discordgo.Session, m *discordgo.InteractionCreate) bool {
+	cid, ok := matchSessionInteraction(sessionManager, s, m, pomomo.BreakCommand.Name, "flowbreak")
+	if !ok {
 		return false
 	}
-	if id.Type != "end" {
+	if !authorizeSessionInteraction(ctx, sessionManager, auth, dm, m, cid) {
+		return true
+	}
+
+	if m.Type == discordgo.InteractionApplicationCommand {
+		if cid == "" {
+			respondNoSession(dm, m)
+			return true
+		}
+		respond := func(msg string) {
+			if _, err := dm.Respond(m.Interaction, false, TextDisplay(msg)); err != nil {
+				log.Error(err)
+			}
+		}
+		if existing, err := sessionManager.GetSession(cid); err == nil && !existing.OpenEnded() {
+			respond("Breaks can only be taken during a flowtime pomodoro.")
+			return true
+		}
+		session, err := sessionManager.TakeBreak(ctx, cid)
+		if err != nil {
+			log.Error("failed to take break", "textCID", cid, "err", err)
+			respond(defaultErrorMsg)
+			return true
+		}
+		log.Info("took flowtime break", "id", session.ID, "break", session.Record.BreakDuration)
+		respond(fmt.Sprintf("Taking a %s break.", formatDuration(session.Record.BreakDuration)))
+		return true
+	}
+
+	followup, err := dm.DeferMessageUpdate(m.Interaction)
+	if err != nil {
+		log.Error(err)
+		return true
+	}
+	session, err := sessionManager.TakeBreak(ctx, cid)
+	if err != nil {
+		// leave the session message as is, e.g. a stale button after the break already started
+		log.Error("failed to take break", "textCID", cid, "err", err)
+		return true
+	}
+	log.Info("took flowtime break", "id", session.ID, "break", session.Record.BreakDuration)
+	if _, err := followup(SessionMessageComponents(session)...); err != nil {
+		log.Error(err)
+	}
+	return true
+}
+
+func EndSession(ctx context.Context, sessionManager SessionManager, auth SessionAuthorizer, dm DiscordMessenger, s *discordgo.Session, m *discordgo.InteractionCreate) bool {
+	cid, ok := matchSessionInteraction(sessionManager, s, m, pomomo.EndCommand.Name, "end")
+	if !ok {
 		return false
 	}
+	if !authorizeSessionInteraction(ctx, sessionManager, auth, dm, m, cid) {
+		return true
+	}
+
+	if m.Type == discordgo.InteractionApplicationCommand {
+		if cid == "" {
+			respondNoSession(dm, m)
+			return true
+		}
+		session, err := sessionManager.EndSession(ctx, cid)
+		if err != nil {
+			log.Error("failed EndSession", "sid", session.ID, "gid", session.Record.GuildID, "err", err)
+			if _, err := dm.Respond(m.Interaction, false, TextDisplay(defaultErrorMsg)); err != nil {
+				log.Error(err)
+			}
+			return true
+		}
+		log.Info("ended session", "id", session.ID)
+		if _, err := dm.Respond(m.Interaction, false, TextDisplay("Ended session.")); err != nil {
+			log.Error(err)
+		}
+		return true
+	}
 
 	if err := s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseDeferredMessageUpdate,
@@ -249,7 +475,7 @@ func EndSession(ctx context.Context, sessionManager SessionManager, s *discordgo
 		return true
 	}
 
-	session, err := sessionManager.EndSession(ctx, id.TextCID)
+	session, err := sessionManager.EndSession(ctx, cid)
 	if err != nil {
 		log.Error("failed EndSession", "sid", session.ID, "gid", session.Record.GuildID, "err", err)
 		return true
@@ -258,17 +484,9 @@ func EndSession(ctx context.Context, sessionManager SessionManager, s *discordgo
 	return true
 }
 
-func JoinSession(ctx context.Context, sessionManager SessionManager, a Autoshusher, pp ParticipantsManager, dm DiscordMessenger, s *discordgo.Session, m *discordgo.InteractionCreate) bool {
-	if m.Type != discordgo.InteractionMessageComponent {
-		return false
-	}
-
-	data := m.MessageComponentData()
-	id, err := FromCustomID(data.CustomID)
-	if err != nil {
-		return false
-	}
-	if id.Type != "join" {
+func JoinSession(ctx context.Context, sessionManager SessionManager, a Autoshusher, pp ParticipantsManager, sr StatsRecorder, dm DiscordMessenger, s *discordgo.Session, m *discordgo.InteractionCreate) bool {
+	cid, ok := matchSessionInteraction(sessionManager, s, m, pomomo.JoinCommand.Name, "join")
+	if !ok {
 		return false
 	}
 
@@ -279,7 +497,13 @@ func JoinSession(ctx context.Context, sessionManager SessionManager, a Autoshush
 	}
 
 	// Get the session
-	session, err := sessionManager.GetSession(id.TextCID)
+	if cid == "" {
+		if _, err := followup(TextDisplay(noSessionMsg)); err != nil {
+			log.Error(err)
+		}
+		return true
+	}
+	session, err := sessionManager.GetSession(cid)
 	if err != nil {
 		log.Error("failed to get session", "err", err)
 		if _, err := followup(TextDisplay(defaultErrorMsg)); err != nil {
@@ -352,6 +576,7 @@ func JoinSession(ctx context.Context, sessionManager SessionManager, a Autoshush
 		return true
 	}
 
+	sr.RecordJoin(ctx, participant)
 	go func() {
 		a.Autoshush(ctx, []models.Participant{participant}, models.Session{}, session)
 	}()
@@ -364,3 +589,1159 @@ func JoinSession(ctx context.Context, sessionManager SessionManager, a Autoshush
 	log.Info("user joined session", "userID", m.Member.User.ID, "cid", session.Record.VoiceCID, "sessionID", session.ID)
 	return true
 }
+
+// LeaveSession handles /leave and the session card's Leave button. It stops shushing the caller and restores their
+// voice state but leaves them in the voice channel.
+func LeaveSession(ctx context.Context, sessionManager SessionManager, vs VoiceStateAdapter, pp ParticipantsManager, sr StatsRecorder, dm DiscordMessenger, s *discordgo.Session, m *discordgo.InteractionCreate) bool {
+	cid, ok := matchSessionInteraction(sessionManager, s, m, pomomo.LeaveCommand.Name, "leave")
+	if !ok {
+		return false
+	}
+
+	followup, err := dm.DeferMessageCreate(m.Interaction, true)
+	if err != nil {
+		log.Error(err)
+		return true
+	}
+	reply := func(msg string) {
+		if _, err := followup(TextDisplay(msg)); err != nil {
+			log.Error(err)
+		}
+	}
+
+	if cid == "" {
+		reply(noSessionMsg)
+		return true
+	}
+	// get session before acquiring voice channel lock since session updates acquire it in the reverse order
+	session, err := sessionManager.GetSession(cid)
+	if err != nil {
+		log.Error("failed to get session", "err", err)
+		reply(defaultErrorMsg)
+		return true
+	}
+
+	uid := GetUser(m.Interaction).ID
+	unlock := pp.AcquireVoiceChannelLock(session.Record.VoiceCID)
+	defer unlock()
+	p := pp.Get(uid, session.Record.VoiceCID)
+	if p == (models.Participant{}) {
+		reply("You aren't in this session.")
+		return true
+	}
+
+	// user stays in the voice channel, just without being shushed
+	if err := restoreVoiceState(ctx, vs, p); err != nil {
+		log.Error("failed voice state restore on leave", "err", err, "gid", m.GuildID, "uid", uid)
+		reply(defaultErrorMsg)
+		return true
+	}
+	if err := pp.Delete(ctx, p.ID); err != nil {
+		log.Error("failed participant delete on leave", "err", err, "gid", m.GuildID, "uid", uid)
+		reply(defaultErrorMsg)
+		return true
+	}
+	sr.RecordLeave(ctx, p, session)
+	log.Info("user left session", "userID", uid, "cid", session.Record.VoiceCID, "sessionID", session.ID)
+	reply("Left session.")
+	return true
+}
+
+func ShowStatus(ctx context.Context, sessionManager SessionManager, dm DiscordMessenger, s *discordgo.Session, m *discordgo.InteractionCreate) bool {
+	cid, ok := matchSessionInteraction(sessionManager, s, m, pomomo.StatusCommand.Name, "")
+	if !ok {
+		return false
+	}
+
+	session, err := sessionManager.GetSession(cid)
+	if err != nil {
+		respondNoSession(dm, m)
+		return true
+	}
+	session.Greeting = ""
+	if _, err := dm.Respond(m.Interaction, false, SessionMessageComponents(session)...); err != nil {
+		log.Error(err)
+	}
+	return true
+}
+
+func ShowSettings(ctx context.Context, sessionManager SessionManager, dm DiscordMessenger, s *discordgo.Session, m *discordgo.InteractionCreate) bool {
+	cid, ok := matchSessionInteraction(sessionManager, s, m, pomomo.SettingsCommand.Name, "")
+	if !ok {
+		return false
+	}
+
+	session, err := sessionManager.GetSession(cid)
+	if err != nil {
+		respondNoSession(dm, m)
+		return true
+	}
+	if _, err := dm.Respond(m.Interaction, false, SessionSettingsComponents(session)...); err != nil {
+		log.Error(err)
+	}
+	return true
+}
+
+// EditSettings prompts for how to apply the edit, then opens the settings modal
+func EditSettings(ctx context.Context, sessionManager SessionManager, auth SessionAuthorizer, dm DiscordMessenger, s *discordgo.Session, m *discordgo.InteractionCreate) bool {
+	if m.Type != discordgo.InteractionMessageComponent {
+		return false
+	}
+	id, err := FromCustomID(m.MessageComponentData().CustomID)
+	if err != nil || (id.Type != "settings" && id.Type != "settings_rescale" && id.Type != "settings_keep") {
+		return false
+	}
+	if !authorizeSessionInteraction(ctx, sessionManager, auth, dm, m, id.TextCID) {
+		return true
+	}
+
+	session, err := sessionManager.GetSession(id.TextCID)
+	if err != nil {
+		if err := dm.RespondEphemeral(m.Interaction, TextDisplay(noSessionMsg)); err != nil {
+			log.Error(err)
+		}
+		return true
+	}
+	switch {
+	case id.Type == "settings" && (session.Settings.Mode == pomomo.FlowtimeMode || session.Record.Status == pomomo.SessionLobby):
+		// flowtime intervals aren't derived from settings and lobbies have no interval underway, so there's nothing to rescale
+		id.Type = "settings_keep"
+		err = dm.RespondModal(m.Interaction, id.ToCustomID(), "Session Settings", SettingsModalComponents(session)...)
+	case id.Type == "settings":
+		err = dm.RespondEphemeral(m.Interaction, SettingsEditPromptComponents(session)...)
+	default:
+		// modal is submitted with the same ID so that it knows whether to rescale
+		err = dm.RespondModal(m.Interaction, id.ToCustomID(), "Session Settings", SettingsModalComponents(session)...)
+	}
+	if err != nil {
+		log.Error(err)
+	}
+	return true
+}
+
+func SubmitSettings(ctx context.Context, sessionManager SessionManager, auth SessionAuthorizer, dm DiscordMessenger, s *discordgo.Session, m *discordgo.InteractionCreate) bool {
+	if m.Type != discordgo.InteractionModalSubmit {
+		return false
+	}
+	data := m.ModalSubmitData()
+	id, err := FromCustomID(data.CustomID)
+	if err != nil || (id.Type != "settings_rescale" && id.Type != "settings_keep") {
+		return false
+	}
+	if !authorizeSessionInteraction(ctx, sessionManager, auth, dm, m, id.TextCID) {
+		return true
+	}
+
+	respond := func(msg string) {
+		if err := dm.RespondEphemeral(m.Interaction, TextDisplay(msg)); err != nil {
+			log.Error(err)
+		}
+	}
+	session, err := sessionManager.GetSession(id.TextCID)
+	if err != nil {
+		respond(noSessionMsg)
+		return true
+	}
+	settings, err := parseSettingsModal(ModalSubmitValues(data), session.Settings)
+	if err != nil {
+		respond(err.Error())
+		return true
+	}
+
+	session, err = sessionManager.UpdateSettings(ctx, id.TextCID, settings, id.Type == "settings_rescale")
+	if err != nil {
+		log.Error("failed to update settings", "textCID", id.TextCID, "err", err)
+		respond(defaultErrorMsg)
+		return true
+	}
+	log.Info("updated session settings", "id", session.ID, "rescale", id.Type == "settings_rescale")
+	respond("Updated session settings.")
+	return true
+}
+
+// parseSettingsModal validates the submitted settings modal values, applying them over base
+func parseSettingsModal(values map[string]string, base pomomo.SessionSettingsRecord) (pomomo.SessionSettingsRecord, error) {
+	settings := base
+	if ratio, ok := values[pomomo.BreakRatioOption]; ok {
+		percent, err := strconv.Atoi(strings.TrimSpace(ratio))
+		if err != nil || percent < 1 || percent > 100 {
+			return settings, fmt.Errorf("Break must be a percentage from 1 to 100.")
+		}
+		settings.BreakRatio = float64(percent) / 100
+		return parseAlertInputs(values, settings)
+	}
+	if seq, ok := values[settingsSequenceInput]; ok {
+		parsed, err := pomomo.ParseIntervalSequence(seq)
+		if err == nil && len(parsed) == 0 {
+			err = fmt.Errorf("sequence needs at least one step")
+		}
+		if err != nil {
+			return settings, fmt.Errorf("Invalid sequence: %v.", err)
+		}
+		settings.Sequence = parsed
+		return parseAlertInputs(values, settings)
+	}
+
+	maxIntervalMinutes := int(pomomo.MaxIntervalDuration.Minutes())
+	parseMinutes := func(s string) (time.Duration, bool) {
+		minutes, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || minutes < 1 || minutes > maxIntervalMinutes {
+			return 0, false
+		}
+		return time.Duration(minutes) * time.Minute, true
+	}
+	pomodoro, ok := parseMinutes(values[pomomo.PomodoroOption])
+	if !ok {
+		return settings, fmt.Errorf("Pomodoro must be a number of minutes from 1 to %d.", maxIntervalMinutes)
+	}
+	settings.Pomodoro = pomodoro
+	breaksErr := fmt.Errorf("Breaks must be the short and long break minutes from 1 to %d, e.g. \"5, 15\".", maxIntervalMinutes)
+	breaks := strings.FieldsFunc(values[settingsBreaksInput], func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	if len(breaks) != 2 {
+		return settings, breaksErr
+	}
+	shortBreak, shortOK := parseMinutes(breaks[0])
+	longBreak, longOK := parseMinutes(breaks[1])
+	if !shortOK || !longOK {
+		return settings, breaksErr
+	}
+	settings.ShortBreak, settings.LongBreak = shortBreak, longBreak
+	intervals, err := strconv.Atoi(strings.TrimSpace(values[pomomo.IntervalsOption]))
+	if err != nil || intervals < 1 || intervals > 20 {
+		return settings, fmt.Errorf("Intervals must be a number from 1 to 20.")
+	}
+	settings.Intervals = intervals
+	return parseAlertInputs(values, settings)
+}
+
+// parseAlertInputs parses the inputs that every settings modal has
+func parseAlertInputs(values map[string]string, settings pomomo.SessionSettingsRecord) (pomomo.SessionSettingsRecord, error) {
+	volume, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(values[pomomo.VolumeOption]), "%"))
+	if err != nil || volume < pomomo.MinVolume || volume > pomomo.MaxVolume {
+		return settings, fmt.Errorf("Alert volume must be a percentage from %d to %d.", pomomo.MinVolume, pomomo.MaxVolume)
+	}
+	settings.Volume = volume
+	return parseShushInput(values[settingsShushInput], settings)
+}
+
+func parseShushInput(input string, settings pomomo.SessionSettingsRecord) (pomomo.SessionSettingsRecord, error) {
+	settings.NoMute, settings.NoDeafen = true, true
+	for _, field := range strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	}) {
+		switch field {
+		case "mute":
+			settings.NoMute = false
+		case "deafen":
+			settings.NoDeafen = false
+		default:
+			return settings, fmt.Errorf("Shush accepts \"mute\", \"deafen\", or both.")
+		}
+	}
+	return settings, nil
+}
+
+func PauseSession(ctx context.Context, sessionManager SessionManager, auth SessionAuthorizer, dm DiscordMessenger, s *discordgo.Session, m *discordgo.InteractionCreate) bool {
+	cid, ok := matchSessionInteraction(sessionManager, s, m, pomomo.PauseCommand.Name, "pause")
+	if !ok {
+		return false
+	}
+	if !authorizeSessionInteraction(ctx, sessionManager, auth, dm, m, cid) {
+		return true
+	}
+	togglePause(ctx, sessionManager, dm, m, cid, true)
+	return true
+}
+
+func ResumeSession(ctx context.Context, sessionManager SessionManager, auth SessionAuthorizer, dm DiscordMessenger, s *discordgo.Session, m *discordgo.InteractionCreate) bool {
+	cid, ok := matchSessionInteraction(sessionManager, s, m, pomomo.ResumeCommand.Name, "resume")
+	if !ok {
+		return false
+	}
+	if !authorizeSessionInteraction(ctx, sessionManager, auth, dm, m, cid) {
+		return true
+	}
+	togglePause(ctx, sessionManager, dm, m, cid, false)
+	return true
+}
+
+// matchSessionInteraction matches either the slash command or the session message button, returning the
+// text channel of the targeted session. Commands target the session in the current text channel,
+// falling back to the caller's voice channel, and return an empty cid if neither has a session.
+func matchSessionInteraction(sessionManager SessionManager, s *discordgo.Session, m *discordgo.InteractionCreate, commandName, customIDType string) (pomomo.TextChannelID, bool) {
+	switch m.Type {
+	case discordgo.InteractionApplicationCommand:
+		if m.ApplicationCommandData().Name != commandName {
+			return "", false
+		}
+		if sessionManager.HasSession(m.ChannelID) {
+			return pomomo.TextChannelID(m.ChannelID), true
+		}
+		vs, err := s.State.VoiceState(m.GuildID, GetUser(m.Interaction).ID)
+		if err != nil {
+			return "", true
+		}
+		session, err := sessionManager.GetVoiceSession(pomomo.VoiceChannelID(vs.ChannelID))
+		if err != nil {
+			return "", true
+		}
+		return session.Record.TextCID, true
+	case discordgo.InteractionMessageComponent:
+		if customIDType == "" {
+			return "", false
+		}
+		id, err := FromCustomID(m.MessageComponentData().CustomID)
+		if err != nil || id.Type != customIDType {
+			return "", false
+		}
+		return id.TextCID, true
+	}
+	return "", false
+}
+
+// authorizeSessionInteraction responds with an ephemeral refusal if the user can't manage the session.
+// Missing sessions are authorized so that the caller can surface them.
+func authorizeSessionInteraction(ctx context.Context, sessionManager SessionManager, auth SessionAuthorizer, dm DiscordMessenger, m *discordgo.InteractionCreate, cid pomomo.TextChannelID) bool {
+	if cid == "" {
+		return true
+	}
+	session, err := sessionManager.GetSession(cid)
+	if err != nil || auth.CanManage(ctx, session, m.Member) {
+		return true
+	}
+	log.Debug("refused session interaction", "uid", GetUser(m.Interaction).ID, "sid", session.ID)
+	if err := dm.RespondEphemeral(m.Interaction, TextDisplay(notHostMsg)); err != nil {
+		log.Error(err)
+	}
+	return false
+}
+
+func respondNoSession(dm DiscordMessenger, m *discordgo.InteractionCreate) {
+	if _, err := dm.Respond(m.Interaction, false, TextDisplay(noSessionMsg)); err != nil {
+		log.Error(err)
+	}
+}
+
+func togglePause(ctx context.Context, sessionManager SessionManager, dm DiscordMessenger, m *discordgo.InteractionCreate, cid pomomo.TextChannelID, pause bool) {
+	toggleFn := sessionManager.ResumeSession
+	if pause {
+		toggleFn = sessionManager.PauseSession
+	}
+
+	if m.Type == discordgo.InteractionMessageComponent {
+		followup, err := dm.DeferMessageUpdate(m.Interaction)
+		if err != nil {
+			log.Error(err)
+			return
+		}
+		session, err := toggleFn(ctx, cid)
+		if err != nil {
+			// leave the session message as is
+			log.Error("failed to toggle pause", "pause", pause, "textCID", cid, "err", err)
+			return
+		}
+		if _, err := followup(SessionMessageComponents(session)...); err != nil {
+			log.Error(err)
+		}
+		return
+	}
+
+	respond := func(msg string) {
+		if _, err := dm.Respond(m.Interaction, false, TextDisplay(msg)); err != nil {
+			log.Error(err)
+		}
+	}
+	existing, err := sessionManager.GetSession(cid)
+	if err != nil {
+		respond(noSessionMsg)
+		return
+	}
+	if existing.Record.Status == pomomo.SessionLobby {
+		respond(notStartedMsg)
+		return
+	}
+	if pause && existing.Record.Status == pomomo.SessionPaused {
+		respond("This session is already paused.")
+		return
+	}
+	if !pause && existing.Record.Status != pomomo.SessionPaused {
+		respond("This session isn't paused.")
+		return
+	}
+
+	session, err := toggleFn(ctx, cid)
+	if err != nil {
+		log.Error("failed to toggle pause", "pause", pause, "textCID", cid, "err", err)
+		respond(defaultErrorMsg)
+		return
+	}
+	if pause {
+		log.Info("paused session", "id", session.ID)
+		respond("Paused session.")
+	} else {
+		log.Info("resumed session", "id", session.ID)
+		respond("Resumed session.")
+	}
+}
+
+func TransferHost(ctx context.Context, sessionManager SessionManager, auth SessionAuthorizer, pp ParticipantsManager, dm DiscordMessenger, s *discordgo.Session, m *discordgo.InteractionCreate) bool {
+	cid, ok := matchSessionInteraction(sessionManager, s, m, pomomo.TransferCommand.Name, "")
+	if !ok {
+		return false
+	}
+	if cid == "" {
+		respondNoSession(dm, m)
+		return true
+	}
+	if !authorizeSessionInteraction(ctx, sessionManager, auth, dm, m, cid) {
+		return true
+	}
+
+	var uid string
+	for _, opt := range m.ApplicationCommandData().Options {
+		if opt.Name == pomomo.UserOption {
+			uid, _ = opt.Value.(string)
+		}
+	}
+	session, err := sessionManager.GetSession(cid)
+	if err != nil {
+		respondNoSession(dm, m)
+		return true
+	}
+	unlock := pp.AcquireVoiceChannelLock(session.Record.VoiceCID)
+	isParticipant := pp.Get(uid, session.Record.VoiceCID) != (models.Participant{})
+	unlock()
+	if !isParticipant {
+		if err := dm.RespondEphemeral(m.Interaction, TextDisplay(fmt.Sprintf("<@%s> isn't in this session.", uid))); err != nil {
+			log.Error(err)
+		}
+		return true
+	}
+
+	session, err = sessionManager.TransferHost(ctx, cid, uid)
+	if err != nil {
+		log.Error("failed to transfer host", "sid", session.ID, "uid", uid, "err", err)
+		if _, err := dm.Respond(m.Interaction, false, TextDisplay(defaultErrorMsg)); err != nil {
+			log.Error(err)
+		}
+		return true
+	}
+	log.Info("transferred host", "sid", session.ID, "uid", uid)
+	if _, err := dm.Respond(m.Interaction, false, TextDisplay(fmt.Sprintf("<@%s> is now hosting.", uid))); err != nil {
+		log.Error(err)
+	}
+	return true
+}
+
+func ConfigureGuild(ctx context.Context, guildRepo GuildSettingsRepo, scheduleRepo ScheduleRepo, dm DiscordMessenger, s *discordgo.Session, m *discordgo.InteractionCreate) bool {
+	if m.Type != discordgo.InteractionApplicationCommand {
+		return false
+	}
+
+	data := m.ApplicationCommandData()
+	if data.Name != pomomo.ConfigCommand.Name || len(data.Options) == 0 {
+		return false
+	}
+
+	record, err := getGuildSettings(ctx, guildRepo, m.GuildID)
+	if err != nil {
+		log.Error("failed to get guild settings", "gid", m.GuildID, "err", err)
+		if err := dm.RespondEphemeral(m.Interaction, TextDisplay(defaultErrorMsg)); err != nil {
+			log.Error(err)
+		}
+		return true
+	}
+
+	subcommand := data.Options[0]
+	var msg string
+	switch subcommand.Name {
+	case pomomo.ConfigModeratorRoleSubcommand:
+		record.ModeratorRoleID = ""
+		for _, opt := range subcommand.Options {
+			if opt.Name == pomomo.RoleOption {
+				record.ModeratorRoleID, _ = opt.Value.(string)
+			}
+		}
+		msg = "Cleared moderator role."
+		if record.ModeratorRoleID != "" {
+			msg = fmt.Sprintf("Members with <@&%s> can now manage any session.", record.ModeratorRoleID)
+		}
+	case pomomo.ConfigDefaultsSubcommand:
+		for _, opt := range subcommand.Options {
+			switch opt.Name {
+			case pomomo.PomodoroOption:
+				record.DefaultPomodoro = time.Duration(opt.IntValue()) * time.Minute
+			case pomomo.ShortBreakOption:
+				record.DefaultShortBreak = time.Duration(opt.IntValue()) * time.Minute
+			case pomomo.LongBreakOption:
+				record.DefaultLongBreak = time.Duration(opt.IntValue()) * time.Minute
+			case pomomo.IntervalsOption:
+				record.DefaultIntervals = int(opt.IntValue())
+			case pomomo.NoMuteOption:
+				record.DefaultNoMute = opt.BoolValue()
+			case pomomo.NoDeafenOption:
+				record.DefaultNoDeafen = opt.BoolValue()
+			case pomomo.VolumeOption:
+				record.DefaultVolume = int(opt.IntValue())
+			}
+		}
+		msg = "Updated session defaults."
+	case pomomo.ConfigMaxLengthSubcommand:
+		for _, opt := range subcommand.Options {
+			if opt.Name == pomomo.MinutesOption {
+				record.MaxSessionDuration = time.Duration(opt.IntValue()) * time.Minute
+			}
+		}
+		msg = "Sessions can now run indefinitely."
+		if record.MaxSessionDuration > 0 {
+			msg = fmt.Sprintf("New sessions will end after %s.", formatDuration(record.MaxSessionDuration))
+		}
+	case pomomo.ConfigAllowChannelSubcommand, pomomo.ConfigDisallowChannelSubcommand:
+		var cid string
+		for _, opt := range subcommand.Options {
+			if opt.Name == pomomo.ChannelOption {
+				cid, _ = opt.Value.(string)
+			}
+		}
+		if data.Resolved == nil || data.Resolved.Channels[cid] == nil {
+			if err := dm.RespondEphemeral(m.Interaction, TextDisplay("Couldn't find that channel. Try choosing it again.")); err != nil {
+				log.Error(err)
+			}
+			return true
+		}
+		ch := data.Resolved.Channels[cid]
+		allow := subcommand.Name == pomomo.ConfigAllowChannelSubcommand
+		if ch.Type == discordgo.ChannelTypeGuildVoice {
+			record.AllowedVoiceCIDs = toggleID(record.AllowedVoiceCIDs, pomomo.VoiceChannelID(cid), allow)
+		} else {
+			record.AllowedTextCIDs = toggleID(record.AllowedTextCIDs, pomomo.TextChannelID(cid), allow)
+		}
+		msg = fmt.Sprintf("Sessions are now allowed in <#%s>.", cid)
+		if !allow {
+			msg = fmt.Sprintf("<#%s> is no longer an allowed channel.", cid)
+		}
+	case pomomo.ConfigTimezoneSubcommand:
+		var name string
+		for _, opt := range subcommand.Options {
+			if opt.Name == pomomo.TimezoneOption {
+				name = strings.TrimSpace(opt.StringValue())
+			}
+		}
+		loc, err := time.LoadLocation(name)
+		if err != nil || name == "" {
+			if err := dm.RespondEphemeral(m.Interaction, TextDisplay(fmt.Sprintf("Unknown timezone \"%s\". Use an IANA name such as America/New_York.", name))); err != nil {
+				log.Error(err)
+			}
+			return true
+		}
+		record.Timezone = loc.String()
+		if err := reschedule(ctx, scheduleRepo, m.GuildID, loc); err != nil {
+			log.Error("failed to reschedule guild schedules", "gid", m.GuildID, "err", err)
+		}
+		msg = fmt.Sprintf("Schedules now run in %s.", loc)
+	case pomomo.ConfigShowSubcommand:
+		if err := dm.RespondEphemeral(m.Interaction, GuildSettingsComponents(record)...); err != nil {
+			log.Error(err)
+		}
+		return true
+	default:
+		return false
+	}
+
+	if _, err := guildRepo.UpsertGuildSettings(ctx, record); err != nil {
+		log.Error("failed to update guild settings", "gid", m.GuildID, "err", err)
+		msg = defaultErrorMsg
+	}
+	if err := dm.RespondEphemeral(m.Interaction, TextDisplay(msg)); err != nil {
+		log.Error(err)
+	}
+	return true
+}
+
+// toggleID adds or removes id from ids
+func toggleID[T ~string](ids []T, id T, add bool) []T {
+	ids = slices.DeleteFunc(ids, func(existing T) bool {
+		return existing == id
+	})
+	if add {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func ManagePresets(ctx context.Context, presetRepo PresetRepo, guildRepo GuildSettingsRepo, dm DiscordMessenger, s *discordgo.Session, m *discordgo.InteractionCreate) bool {
+	if m.Type != discordgo.InteractionApplicationCommand {
+		return false
+	}
+
+	data := m.ApplicationCommandData()
+	if data.Name != pomomo.PresetCommand.Name || len(data.Options) == 0 {
+		return false
+	}
+
+	respond := func(components ...discordgo.MessageComponent) {
+		if err := dm.RespondEphemeral(m.Interaction, components...); err != nil {
+			log.Error(err)
+		}
+	}
+	uid := GetUser(m.Interaction).ID
+	subcommand := data.Options[0]
+	var name string
+	scope := pomomo.PresetScopeUser
+	for _, opt := range subcommand.Options {
+		switch opt.Name {
+		case pomomo.NameOption:
+			name = strings.TrimSpace(opt.StringValue())
+		case pomomo.ScopeOption:
+			scope = opt.StringValue()
+		}
+	}
+	owner := uid
+	if scope == pomomo.PresetScopeGuild {
+		if m.Member == nil || m.Member.Permissions&(discordgo.PermissionAdministrator|discordgo.PermissionManageGuild) == 0 {
+			respond(TextDisplay("Only server managers can change server presets."))
+			return true
+		}
+		owner = ""
+	}
+
+	switch subcommand.Name {
+	case pomomo.PresetSaveSubcommand:
+		if _, builtin := findPreset(pomomo.BuiltinPresets, name); builtin || name == "" {
+			respond(TextDisplay(fmt.Sprintf("\"%s\" can't be used as a preset name.", name)))
+			return true
+		}
+		guildSettings, err := getGuildSettings(ctx, guildRepo, m.GuildID)
+		if err != nil {
+			log.Error("failed to get guild settings - using defaults", "gid", m.GuildID, "err", err)
+		}
+		defaults := guildSettings.SessionDefaults()
+		preset := pomomo.SessionPresetRecord{
+			GuildID:    m.GuildID,
+			UserID:     owner,
+			Name:       name,
+			Pomodoro:   defaults.Pomodoro,
+			ShortBreak: defaults.ShortBreak,
+			LongBreak:  defaults.LongBreak,
+			Intervals:  defaults.Intervals,
+			NoMute:     defaults.NoMute,
+			NoDeafen:   defaults.NoDeafen,
+		}
+		for _, opt := range subcommand.Options {
+			switch opt.Name {
+			case pomomo.PomodoroOption:
+				preset.Pomodoro = time.Duration(opt.IntValue()) * time.Minute
+			case pomomo.ShortBreakOption:
+				preset.ShortBreak = time.Duration(opt.IntValue()) * time.Minute
+			case pomomo.LongBreakOption:
+				preset.LongBreak = time.Duration(opt.IntValue()) * time.Minute
+			case pomomo.IntervalsOption:
+				preset.Intervals = int(opt.IntValue())
+			case pomomo.NoMuteOption:
+				preset.NoMute = opt.BoolValue()
+			case pomomo.NoDeafenOption:
+				preset.NoDeafen = opt.BoolValue()
+			}
+		}
+		if err := presetRepo.UpsertPreset(ctx, preset); err != nil {
+			log.Error("failed to save preset", "gid", m.GuildID, "uid", owner, "name", name, "err", err)
+			respond(TextDisplay(defaultErrorMsg))
+			return true
+		}
+		respond(TextDisplay(fmt.Sprintf("Saved preset **%s** · %s", name, presetSummary(preset))))
+	case pomomo.PresetDeleteSubcommand:
+		err := presetRepo.DeletePreset(ctx, m.GuildID, owner, name)
+		if err == sqlite.ErrNotFound {
+			respond(TextDisplay(fmt.Sprintf("Couldn't find preset \"%s\".", name)))
+			return true
+		}
+		if err != nil {
+			log.Error("failed to delete preset", "gid", m.GuildID, "uid", owner, "name", name, "err", err)
+			respond(TextDisplay(defaultErrorMsg))
+			return true
+		}
+		respond(TextDisplay(fmt.Sprintf("Deleted preset **%s**.", name)))
+	case pomomo.PresetListSubcommand:
+		presets, err := availablePresets(ctx, presetRepo, m.GuildID, uid)
+		if err != nil {
+			log.Error("failed to get presets", "gid", m.GuildID, "uid", uid, "err", err)
+		}
+		respond(PresetsMessageComponents(presets)...)
+	default:
+		return false
+	}
+	return true
+}
+
+// maxAutocompleteChoices is Discord's limit on autocomplete results
+const maxAutocompleteChoices = 25
+
+// AutocompletePreset suggests presets for /start, /schedule create and /preset delete
+func AutocompletePreset(ctx context.Context, presetRepo PresetRepo, dm DiscordMessenger, s *discordgo.Session, m *discordgo.InteractionCreate) bool {
+	if m.Type != discordgo.InteractionApplicationCommandAutocomplete {
+		return false
+	}
+
+	data := m.ApplicationCommandData()
+	options := data.Options
+	deleting := false
+	switch data.Name {
+	case pomomo.StartCommand.Name:
+	case pomomo.ScheduleCommand.Name:
+		if len(options) == 0 || options[0].Name != pomomo.ScheduleCreateSubcommand {
+			return false
+		}
+		options = options[0].Options
+	case pomomo.PresetCommand.Name:
+		if len(options) == 0 || options[0].Name != pomomo.PresetDeleteSubcommand {
+			return false
+		}
+		options = options[0].Options
+		deleting = true
+	default:
+		return false
+	}
+
+	var typed string
+	scope := pomomo.PresetScopeUser
+	for _, opt := range options {
+		if opt.Focused {
+			typed = strings.ToLower(opt.StringValue())
+		}
+		if opt.Name == pomomo.ScopeOption {
+			scope = opt.StringValue()
+		}
+	}
+
+	uid := GetUser(m.Interaction).ID
+	presets, err := availablePresets(ctx, presetRepo, m.GuildID, uid)
+	if err != nil {
+		log.Error("failed to get presets", "gid", m.GuildID, "uid", uid, "err", err)
+	}
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, p := range presets {
+		if deleting && (p.GuildID == "" || (p.UserID == "") != (scope == pomomo.PresetScopeGuild)) {
+			// only saved presets in the chosen scope can be deleted
+			continue
+		}
+		if !strings.Contains(strings.ToLower(p.Name), typed) || len(choices) == maxAutocompleteChoices {
+			continue
+		}
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  fmt.Sprintf("%s (%s)", p.Name, presetSummary(p)),
+			Value: p.Name,
+		})
+	}
+	if err := dm.RespondAutocomplete(m.Interaction, choices...); err != nil {
+		log.Error(err)
+	}
+	return true
+}
+
+func ManageSchedules(ctx context.Context, scheduleRepo ScheduleRepo, guildRepo GuildSettingsRepo, presetRepo PresetRepo, dm DiscordMessenger, s *discordgo.Session, m *discordgo.InteractionCreate) bool {
+	if m.Type != discordgo.InteractionApplicationCommand {
+		return false
+	}
+
+	data := m.ApplicationCommandData()
+	if data.Name != pomomo.ScheduleCommand.Name || len(data.Options) == 0 {
+		return false
+	}
+
+	respond := func(components ...discordgo.MessageComponent) {
+		if err := dm.RespondEphemeral(m.Interaction, components...); err != nil {
+			log.Error(err)
+		}
+	}
+	guildSettings, err := getGuildSettings(ctx, guildRepo, m.GuildID)
+	if err != nil {
+		log.Error("failed to get guild settings - using defaults", "gid", m.GuildID, "err", err)
+	}
+	loc := guildSettings.Location()
+
+	subcommand := data.Options[0]
+	switch subcommand.Name {
+	case pomomo.ScheduleCreateSubcommand:
+		uid := GetUser(m.Interaction).ID
+		schedule := pomomo.SessionScheduleRecord{
+			GuildID:       m.GuildID,
+			TextCID:       pomomo.TextChannelID(m.ChannelID),
+			CreatorUserID: uid,
+		}
+		var days, at string
+		for _, opt := range subcommand.Options {
+			switch opt.Name {
+			case pomomo.DaysOption:
+				days = opt.StringValue()
+			case pomomo.TimeOption:
+				at = opt.StringValue()
+			case pomomo.VoiceOption:
+				schedule.VoiceCID = pomomo.VoiceChannelID(opt.StringValue())
+			case pomomo.ChannelOption:
+				schedule.TextCID = pomomo.TextChannelID(opt.StringValue())
+			case pomomo.PingOption:
+				schedule.PingRoleID = opt.StringValue()
+			case pomomo.PresetOption:
+				schedule.PresetName = strings.TrimSpace(opt.StringValue())
+			}
+		}
+		recurrence, err := pomomo.ParseRecurrence(days, at)
+		if err != nil {
+			respond(TextDisplay(fmt.Sprintf("Invalid schedule: %v.", err)))
+			return true
+		}
+		schedule.Recurrence = recurrence
+		if !guildSettings.AllowsTextChannel(schedule.TextCID) || !guildSettings.AllowsVoiceChannel(schedule.VoiceCID) {
+			respond(TextDisplay("Sessions can't be started in those channels."))
+			return true
+		}
+		if schedule.PresetName != "" {
+			presets, err := availablePresets(ctx, presetRepo, m.GuildID, uid)
+			if err != nil {
+				log.Error("failed to get presets", "gid", m.GuildID, "err", err)
+			}
+			preset, ok := findPreset(presets, schedule.PresetName)
+			if !ok {
+				respond(TextDisplay(fmt.Sprintf("Couldn't find preset \"%s\".", schedule.PresetName)))
+				return true
+			}
+			schedule.PresetName = preset.Name
+		}
+		schedule.NextFireAt = recurrence.Next(time.Now(), loc)
+
+		existing, err := scheduleRepo.InsertSchedule(ctx, schedule)
+		if err != nil {
+			log.Error("failed to create schedule", "gid", m.GuildID, "err", err)
+			respond(TextDisplay(defaultErrorMsg))
+			return true
+		}
+		log.Info("created schedule", "id", existing.ID, "gid", m.GuildID)
+		respond(TextDisplay(fmt.Sprintf("Scheduled sessions in <#%s> · %s (%s) · first <t:%d:R>", schedule.VoiceCID, recurrence, loc, schedule.NextFireAt.Unix())))
+	case pomomo.ScheduleDeleteSubcommand:
+		var id pomomo.ScheduleID
+		for _, opt := range subcommand.Options {
+			if opt.Name == pomomo.ScheduleOption {
+				id = pomomo.ScheduleID(opt.StringValue())
+			}
+		}
+		deleted, err := scheduleRepo.DeleteSchedule(ctx, m.GuildID, id)
+		if err == sqlite.ErrNotFound {
+			respond(TextDisplay("Couldn't find that schedule."))
+			return true
+		}
+		if err != nil {
+			log.Error("failed to delete schedule", "gid", m.GuildID, "id", id, "err", err)
+			respond(TextDisplay(defaultErrorMsg))
+			return true
+		}
+		log.Info("deleted schedule", "id", id, "gid", m.GuildID)
+		respond(TextDisplay(fmt.Sprintf("Deleted schedule **%s** in <#%s>.", deleted.Recurrence, deleted.VoiceCID)))
+	case pomomo.ScheduleListSubcommand:
+		schedules, err := scheduleRepo.GetSchedules(ctx, m.GuildID)
+		if err != nil {
+			log.Error("failed to get schedules", "gid", m.GuildID, "err", err)
+			respond(TextDisplay(defaultErrorMsg))
+			return true
+		}
+		respond(SchedulesMessageComponents(schedules, loc)...)
+	default:
+		return false
+	}
+	return true
+}
+
+// AutocompleteSchedule suggests the guild's schedules for /schedule delete
+func AutocompleteSchedule(ctx context.Context, scheduleRepo ScheduleRepo, dm DiscordMessenger, s *discordgo.Session, m *discordgo.InteractionCreate) bool {
+	if m.Type != discordgo.InteractionApplicationCommandAutocomplete {
+		return false
+	}
+
+	data := m.ApplicationCommandData()
+	if data.Name != pomomo.ScheduleCommand.Name || len(data.Options) == 0 || data.Options[0].Name != pomomo.ScheduleDeleteSubcommand {
+		return false
+	}
+
+	var typed string
+	for _, opt := range data.Options[0].Options {
+		if opt.Focused {
+			typed = strings.ToLower(opt.StringValue())
+		}
+	}
+
+	schedules, err := scheduleRepo.GetSchedules(ctx, m.GuildID)
+	if err != nil {
+		log.Error("failed to get schedules", "gid", m.GuildID, "err", err)
+	}
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, schedule := range schedules {
+		// choices can't render channel mentions
+		channel := string(schedule.VoiceCID)
+		if ch, err := s.State.Channel(channel); err == nil {
+			channel = ch.Name
+		}
+		name := fmt.Sprintf("%s · %s", schedule.Recurrence, channel)
+		if !strings.Contains(strings.ToLower(name), typed) || len(choices) == maxAutocompleteChoices {
+			continue
+		}
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  name,
+			Value: string(schedule.ID),
+		})
+	}
+	if err := dm.RespondAutocomplete(m.Interaction, choices...); err != nil {
+		log.Error(err)
+	}
+	return true
+}
+
+func ShowStats(ctx context.Context, statsRepo ParticipantStatsRepo, dm DiscordMessenger, s *discordgo.Session, m *discordgo.InteractionCreate) bool {
+	if m.Type != discordgo.InteractionApplicationCommand {
+		return false
+	}
+
+	data := m.ApplicationCommandData()
+	if data.Name != pomomo.StatsCommand.Name || len(data.Options) == 0 {
+		return false
+	}
+
+	subcommand := data.Options[0]
+	rng := pomomo.StatsRangeWeek
+	user := GetUser(m.Interaction)
+	for _, opt := range subcommand.Options {
+		switch opt.Name {
+		case pomomo.RangeOption:
+			rng = pomomo.StatsRange(opt.StringValue())
+		case pomomo.UserOption:
+			uid, _ := opt.Value.(string)
+			user = &discordgo.User{ID: uid}
+			if data.Resolved != nil && data.Resolved.Users[uid] != nil {
+				user = data.Resolved.Users[uid]
+			}
+		}
+	}
+	since := rng.Since(time.Now())
+
+	var components []discordgo.MessageComponent
+	switch subcommand.Name {
+	case pomomo.StatsMeSubcommand, pomomo.StatsUserSubcommand:
+		summary, err := statsRepo.GetUserStats(ctx, m.GuildID, user.ID, since)
+		if err != nil {
+			log.Error("failed to get user stats", "gid", m.GuildID, "uid", user.ID, "err", err)
+			components = append(components, TextDisplay(defaultErrorMsg))
+			break
+		}
+		streak, err := statsRepo.GetStreak(ctx, m.GuildID, user.ID)
+		if err != nil && err != sqlite.ErrNotFound {
+			log.Error("failed to get user streak", "gid", m.GuildID, "uid", user.ID, "err", err)
+		}
+		components = UserStatsMessageComponents(user.DisplayName(), rng, summary, streak)
+	case pomomo.StatsServerSubcommand:
+		summary, err := statsRepo.GetGuildStats(ctx, m.GuildID, since)
+		if err != nil {
+			log.Error("failed to get guild stats", "gid", m.GuildID, "err", err)
+			components = append(components, TextDisplay(defaultErrorMsg))
+			break
+		}
+		components = GuildStatsMessageComponents(rng, summary)
+	default:
+		return false
+	}
+
+	if _, err := dm.Respond(m.Interaction, false, components...); err != nil {
+		log.Error(err)
+	}
+	return true
+}
+
+const leaderboardSize = 10
+
+func ShowLeaderboard(ctx context.Context, statsRepo ParticipantStatsRepo, dm DiscordMessenger, s *discordgo.Session, m *discordgo.InteractionCreate) bool {
+	if m.Type != discordgo.InteractionApplicationCommand {
+		return false
+	}
+
+	data := m.ApplicationCommandData()
+	if data.Name != pomomo.LeaderboardCommand.Name {
+		return false
+	}
+
+	metric := pomomo.LeaderboardFocus
+	rng := pomomo.StatsRangeWeek
+	for _, opt := range data.Options {
+		switch opt.Name {
+		case pomomo.MetricOption:
+			metric = pomomo.LeaderboardMetric(opt.StringValue())
+		case pomomo.RangeOption:
+			rng = pomomo.StatsRange(opt.StringValue())
+		}
+	}
+
+	var entries []pomomo.LeaderboardEntry
+	var streaks []pomomo.ParticipantStreakRecord
+	var err error
+	if metric == pomomo.LeaderboardStreak {
+		streaks, err = statsRepo.GetStreakLeaderboard(ctx, m.GuildID, leaderboardSize)
+	} else {
+		entries, err = statsRepo.GetLeaderboard(ctx, m.GuildID, metric, rng.Since(time.Now()), leaderboardSize)
+	}
+	components := LeaderboardMessageComponents(metric, rng, entries, streaks)
+	if err != nil {
+		log.Error("failed to get leaderboard", "gid", m.GuildID, "metric", metric, "err", err)
+		components = []discordgo.MessageComponent{TextDisplay(defaultErrorMsg)}
+	}
+
+	if _, err := dm.Respond(m.Interaction, false, components...); err != nil {
+		log.Error(err)
+	}
+	return true
+}
+
+// ManageSounds handles /sound to replace the guild's alert sounds with uploads or reset them
+func ManageSounds(ctx context.Context, soundRepo SoundRepo, audioLoader AudioLoader, dm DiscordMessenger, s *discordgo.Session, m *discordgo.InteractionCreate) bool {
+	if m.Type != discordgo.InteractionApplicationCommand {
+		return false
+	}
+
+	data := m.ApplicationCommandData()
+	if data.Name != pomomo.SoundCommand.Name || len(data.Options) == 0 {
+		return false
+	}
+
+	subcommand := data.Options[0]
+	var sound pomomo.AlertSound
+	var attachmentID string
+	for _, opt := range subcommand.Options {
+		switch opt.Name {
+		case pomomo.SoundOption:
+			sound = pomomo.AlertSound(opt.StringValue())
+		case pomomo.FileOption:
+			attachmentID = opt.StringValue()
+		}
+	}
+
+	switch subcommand.Name {
+	case pomomo.SoundSetSubcommand:
+		// downloading and decoding can outlast the interaction deadline
+		followup, err := dm.DeferMessageCreate(m.Interaction, true)
+		if err != nil {
+			log.Error(err)
+			return true
+		}
+		reply := func(msg string) {
+			if _, err := followup(TextDisplay(msg)); err != nil {
+				log.Error(err)
+			}
+		}
+
+		var attachment *discordgo.MessageAttachment
+		if data.Resolved != nil {
+			attachment = data.Resolved.Attachments[attachmentID]
+		}
+		if attachment == nil {
+			reply("Couldn't find the uploaded file.")
+			return true
+		}
+		maxSize, maxDuration := soundLimits(sound)
+		if int64(attachment.Size) > maxSize {
+			reply(fmt.Sprintf("%s sound files can be at most %d KB.", sound, maxSize>>10))
+			return true
+		}
+		file, err := downloadAttachment(ctx, s.Client, attachment.URL, maxSize)
+		if err != nil {
+			log.Error("failed to download sound", "gid", m.GuildID, "url", attachment.URL, "err", err)
+			reply(defaultErrorMsg)
+			return true
+		}
+		packets, duration, err := decodeSound(attachment.Filename, file, maxDuration)
+		if err != nil {
+			log.Debug("rejected sound upload", "gid", m.GuildID, "file", attachment.Filename, "err", err)
+			reply(fmt.Sprintf("Couldn't use %s: %v.", attachment.Filename, err))
+			return true
+		}
+
+		encoded, err := dca.Marshal(packets)
+		if err != nil {
+			reply(fmt.Sprintf("Couldn't use %s: %v.", attachment.Filename, err))
+			return true
+		}
+		err = soundRepo.UpsertSound(ctx, pomomo.GuildSoundRecord{
+			GuildID:        m.GuildID,
+			Sound:          sound,
+			UploaderUserID: GetUser(m.Interaction).ID,
+			FileName:       attachment.Filename,
+			DCA:            encoded,
+			Duration:       duration,
+		})
+		if err != nil {
+			log.Error("failed to save sound", "gid", m.GuildID, "sound", sound, "err", err)
+			reply(defaultErrorMsg)
+			return true
+		}
+		audioLoader.Invalidate(m.GuildID)
+		log.Info("set guild sound", "gid", m.GuildID, "sound", sound, "duration", duration)
+		if sound == pomomo.AmbientSound {
+			reply(fmt.Sprintf("Sessions started with `%s` now loop %s (%.1fs) during pomodoros.", pomomo.AmbientOption, attachment.Filename, duration.Seconds()))
+		} else {
+			reply(fmt.Sprintf("The **%s** alert now plays %s (%.1fs).", sound, attachment.Filename, duration.Seconds()))
+		}
+	case pomomo.SoundResetSubcommand:
+		respond := func(msg string) {
+			if err := dm.RespondEphemeral(m.Interaction, TextDisplay(msg)); err != nil {
+				log.Error(err)
+			}
+		}
+		var toReset []pomomo.AlertSound
+		if sound != "" {
+			toReset = append(toReset, sound)
+		}
+		n, err := soundRepo.DeleteSounds(ctx, m.GuildID, toReset...)
+		if err != nil {
+			log.Error("failed to reset sounds", "gid", m.GuildID, "sound", sound, "err", err)
+			respond(defaultErrorMsg)
+			return true
+		}
+		audioLoader.Invalidate(m.GuildID)
+		log.Info("reset guild sounds", "gid", m.GuildID, "sound", sound, "count", n)
+		switch {
+		case n == 0:
+			respond("This server is already using the default sounds.")
+		case sound == pomomo.AmbientSound:
+			respond("This server's ambient track was removed.")
+		case sound != "":
+			respond(fmt.Sprintf("The **%s** alert is back to the default sound.", sound))
+		default:
+			respond("All alerts are back to the default sounds.")
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// downloadAttachment fetches an interaction attachment, failing if it's larger than limit bytes
+func downloadAttachment(ctx context.Context, client *http.Client, url string, limit int64) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() //nolint
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("attachment is over %d bytes", limit)
+	}
+	return data, nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/benjamonnguyen/pomomo-go"
+	"github.com/bwmarrin/discordgo"
+)
+
+// startOption builds a /start option the way discordgo decodes it, with numbers as float64
+func startOption(name string, value any) *discordgo.ApplicationCommandInteractionDataOption {
+	opt := &discordgo.ApplicationCommandInteractionDataOption{Name: name, Value: value}
+	switch v := value.(type) {
+	case int:
+		opt.Type, opt.Value = discordgo.ApplicationCommandOptionInteger, float64(v)
+	case bool:
+		opt.Type = discordgo.ApplicationCommandOptionBoolean
+	case string:
+		opt.Type = discordgo.ApplicationCommandOptionString
+	}
+	return opt
+}
+
+func TestParseStartOptions(t *testing.T) {
+	guild := pomomo.NewGuildSettingsRecord("g1")
+	guild.DefaultPomodoro = 50 * time.Minute
+	guild.DefaultShortBreak = 10 * time.Minute
+	guild.DefaultLongBreak = 30 * time.Minute
+	guild.DefaultIntervals = 2
+	guild.DefaultNoMute = true
+	guild.DefaultVolume = 80
+	guild.MaxSessionDuration = 3 * time.Hour
+	defaults := guild.SessionDefaults()
+	presets := &fakePresetRepo{presets: []pomomo.SessionPresetRecord{
+		{GuildID: "g1", UserID: "u1", Name: "Study", Pomodoro: 45 * time.Minute, ShortBreak: 15 * time.Minute, LongBreak: 20 * time.Minute, Intervals: 3, NoDeafen: true},
+	}}
+	with := func(edit func(*pomomo.SessionSettingsRecord)) pomomo.SessionSettingsRecord {
+		s := defaults
+		edit(&s)
+		return s
+	}
+
+	tests := []struct {
+		name      string
+		guild     pomomo.GuildSettingsRecord
+		options   []*discordgo.ApplicationCommandInteractionDataOption
+		want      pomomo.SessionSettingsRecord
+		wantLobby time.Duration
+		wantErr   bool
+	}{
+		{"builtin defaults", pomomo.NewGuildSettingsRecord("g1"), nil, pomomo.SessionSettingsRecord{
+			Pomodoro: 20 * time.Minute, ShortBreak: 5 * time.Minute, LongBreak: 15 * time.Minute, Intervals: 4,
+			BreakRatio: pomomo.DefaultBreakRatio, Volume: pomomo.DefaultVolume,
+		}, 0, false},
+		// the guild's max session length applies to every session
+		{"guild defaults and limits", guild, nil, with(func(s *pomomo.SessionSettingsRecord) {
+			s.Pomodoro, s.ShortBreak, s.LongBreak, s.Intervals = 50*time.Minute, 10*time.Minute, 30*time.Minute, 2
+			s.NoMute, s.Volume, s.MaxDuration = true, 80, 3*time.Hour
+		}), 0, false},
+		{"options over guild defaults", guild, []*discordgo.ApplicationCommandInteractionDataOption{
+			startOption(pomomo.PomodoroOption, 25),
+			startOption(pomomo.IntervalsOption, 4),
+			startOption(pomomo.NoMuteOption, false),
+			startOption(pomomo.NoDeafenOption, true),
+			startOption(pomomo.VolumeOption, 120),
+			startOption(pomomo.LobbyOption, 5),
+		}, with(func(s *pomomo.SessionSettingsRecord) {
+			s.Pomodoro, s.Intervals, s.NoMute, s.NoDeafen, s.Volume = 25*time.Minute, 4, false, true, 120
+		}), 5 * time.Minute, false},
+		{"sequence", guild, []*discordgo.ApplicationCommandInteractionDataOption{
+			startOption(pomomo.SequenceOption, "warmup 10, focus 50"),
+		}, with(func(s *pomomo.SessionSettingsRecord) {
+			s.Sequence = pomomo.IntervalSequence{{Interval: pomomo.WarmUpInterval, Duration: 10 * time.Minute}, {Interval: pomomo.PomodoroInterval, Duration: 50 * time.Minute}}
+		}), 0, false},
+		{"flowtime", guild, []*discordgo.ApplicationCommandInteractionDataOption{
+			startOption(pomomo.FlowtimeOption, true),
+			startOption(pomomo.BreakRatioOption, 25),
+		}, with(func(s *pomomo.SessionSettingsRecord) {
+			s.Mode, s.BreakRatio = pomomo.FlowtimeMode, 0.25
+		}), 0, false},
+		// presets replace the guild's default durations but not its limits
+		{"preset over guild defaults", guild, []*discordgo.ApplicationCommandInteractionDataOption{
+			startOption(pomomo.PresetOption, "study"),
+		}, with(func(s *pomomo.SessionSettingsRecord) {
+			s.Pomodoro, s.ShortBreak, s.LongBreak, s.Intervals = 45*time.Minute, 15*time.Minute, 20*time.Minute, 3
+			s.NoMute, s.NoDeafen = false, true
+		}), 0, false},
+		// explicit options override the preset wherever they're given
+		{"options over preset", guild, []*discordgo.ApplicationCommandInteractionDataOption{
+			startOption(pomomo.PomodoroOption, 60),
+			startOption(pomomo.PresetOption, "Study"),
+			startOption(pomomo.NoMuteOption, true),
+		}, with(func(s *pomomo.SessionSettingsRecord) {
+			s.Pomodoro, s.ShortBreak, s.LongBreak, s.Intervals = 60*time.Minute, 15*time.Minute, 20*time.Minute, 3
+			s.NoMute, s.NoDeafen = true, true
+		}), 0, false},
+		{"builtin preset", guild, []*discordgo.ApplicationCommandInteractionDataOption{
+			startOption(pomomo.PresetOption, "52/17"),
+		}, pomomo.BuiltinPresets[1].Apply(defaults), 0, false},
+		{"unknown preset", guild, []*discordgo.ApplicationCommandInteractionDataOption{
+			startOption(pomomo.PresetOption, "nap"),
+		}, pomomo.SessionSettingsRecord{}, 0, true},
+		{"invalid sequence", guild, []*discordgo.ApplicationCommandInteractionDataOption{
+			startOption(pomomo.SequenceOption, "focus"),
+		}, pomomo.SessionSettingsRecord{}, 0, true},
+		{"flowtime sequence", guild, []*discordgo.ApplicationCommandInteractionDataOption{
+			startOption(pomomo.FlowtimeOption, true),
+			startOption(pomomo.SequenceOption, "focus 50"),
+		}, pomomo.SessionSettingsRecord{}, 0, true},
+		{"invalid until", guild, []*discordgo.ApplicationCommandInteractionDataOption{
+			startOption(pomomo.UntilOption, "25:00"),
+		}, pomomo.SessionSettingsRecord{}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, lobby, err := parseStartOptions(context.Background(), presets, tt.guild, "u1", tt.options)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseStartOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseStartOptions() = %+v, want %+v", got, tt.want)
+			}
+			if lobby != tt.wantLobby {
+				t.Errorf("lobby = %v, want %v", lobby, tt.wantLobby)
+			}
+		})
+	}
+
+	t.Run("until in the guild's timezone", func(t *testing.T) {
+		g := guild
+		g.Timezone = "Asia/Tokyo"
+		got, _, err := parseStartOptions(context.Background(), nil, g, "u1", []*discordgo.ApplicationCommandInteractionDataOption{
+			startOption(pomomo.UntilOption, "17:30"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		until := got.GoalUntil.In(g.Location())
+		if until.Hour() != 17 || until.Minute() != 30 || !until.After(time.Now()) || time.Until(until) > 24*time.Hour {
+			t.Errorf("GoalUntil = %v, want the next 17:30 in %v", until, g.Timezone)
+		}
+	})
+}
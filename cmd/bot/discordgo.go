@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/benjamonnguyen/pomomo-go"
 	"github.com/benjamonnguyen/pomomo-go/cmd/bot/models"
@@ -13,8 +15,14 @@ import (
 )
 
 type DiscordMessenger interface {
+	SendChannelMessage(cID pomomo.TextChannelID, components ...discordgo.MessageComponent) (*discordgo.Message, error)
+	PinChannelMessage(cID pomomo.TextChannelID, messageID string) error
 	EditChannelMessage(cID pomomo.TextChannelID, messageID string, components ...discordgo.MessageComponent) (*discordgo.Message, error)
+	DeleteChannelMessage(cID pomomo.TextChannelID, messageID string) error
 	Respond(it *discordgo.Interaction, wait bool, components ...discordgo.MessageComponent) (*discordgo.Message, error)
+	RespondEphemeral(it *discordgo.Interaction, components ...discordgo.MessageComponent) error
+	RespondModal(it *discordgo.Interaction, customID, title string, components ...discordgo.MessageComponent) error
+	RespondAutocomplete(it *discordgo.Interaction, choices ...*discordgo.ApplicationCommandOptionChoice) error
 	EditResponse(it *discordgo.Interaction, components ...discordgo.MessageComponent) (*discordgo.Message, error)
 	DeferMessageCreate(it *discordgo.Interaction, ephemeral bool) (followup, error)
 	DeferMessageUpdate(it *discordgo.Interaction) (followup, error)
@@ -30,6 +38,21 @@ type messenger struct {
 	client *discordgo.Session
 }
 
+// SendChannelMessage sends a message outside of an interaction. Unlike responses, role mentions ping.
+func (m *messenger) SendChannelMessage(cID pomomo.TextChannelID, components ...discordgo.MessageComponent) (*discordgo.Message, error) {
+	return m.client.ChannelMessageSendComplex(string(cID), &discordgo.MessageSend{
+		Flags:      discordgo.MessageFlagsIsComponentsV2,
+		Components: components,
+		AllowedMentions: &discordgo.MessageAllowedMentions{
+			Parse: []discordgo.AllowedMentionType{discordgo.AllowedMentionTypeRoles},
+		},
+	})
+}
+
+func (m *messenger) PinChannelMessage(cID pomomo.TextChannelID, messageID string) error {
+	return m.client.ChannelMessagePin(string(cID), messageID)
+}
+
 func (m *messenger) EditChannelMessage(cID pomomo.TextChannelID, messageID string, components ...discordgo.MessageComponent) (*discordgo.Message, error) {
 	return m.client.ChannelMessageEditComplex(&discordgo.MessageEdit{
 		Channel:    string(cID),
@@ -39,6 +62,10 @@ func (m *messenger) EditChannelMessage(cID pomomo.TextChannelID, messageID strin
 	})
 }
 
+func (m *messenger) DeleteChannelMessage(cID pomomo.TextChannelID, messageID string) error {
+	return m.client.ChannelMessageDelete(string(cID), messageID)
+}
+
 // Respond returns message only when wait == true
 func (m *messenger) Respond(it *discordgo.Interaction, wait bool, components ...discordgo.MessageComponent) (*discordgo.Message, error) {
 	if err := m.client.InteractionRespond(it, &discordgo.InteractionResponse{
@@ -46,6 +73,8 @@ func (m *messenger) Respond(it *discordgo.Interaction, wait bool, components ...
 		Data: &discordgo.InteractionResponseData{
 			Flags:      discordgo.MessageFlagsIsComponentsV2,
 			Components: components,
+			// mentions are for display only
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
 		},
 	}); err != nil {
 		return nil, err
@@ -56,6 +85,38 @@ func (m *messenger) Respond(it *discordgo.Interaction, wait bool, components ...
 	return nil, nil
 }
 
+// RespondEphemeral responds with a message only visible to the interacting user
+func (m *messenger) RespondEphemeral(it *discordgo.Interaction, components ...discordgo.MessageComponent) error {
+	return m.client.InteractionRespond(it, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags:           discordgo.MessageFlagsIsComponentsV2 | discordgo.MessageFlagsEphemeral,
+			Components:      components,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	})
+}
+
+func (m *messenger) RespondModal(it *discordgo.Interaction, customID, title string, components ...discordgo.MessageComponent) error {
+	return m.client.InteractionRespond(it, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID:   customID,
+			Title:      title,
+			Components: components,
+		},
+	})
+}
+
+func (m *messenger) RespondAutocomplete(it *discordgo.Interaction, choices ...*discordgo.ApplicationCommandOptionChoice) error {
+	return m.client.InteractionRespond(it, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: choices,
+		},
+	})
+}
+
 func (m *messenger) EditResponse(it *discordgo.Interaction, components ...discordgo.MessageComponent) (*discordgo.Message, error) {
 	return m.client.InteractionResponseEdit(it, &discordgo.WebhookEdit{
 		Components: &components,
@@ -178,13 +239,11 @@ const (
 
 func SessionMessageComponents(s models.Session) []discordgo.MessageComponent {
 	if s.Record.Status == pomomo.SessionEnded {
-		return []discordgo.MessageComponent{
-			TextDisplay(getFarewell()),
-		}
+		return SessionSummaryComponents(s, nil)
 	}
-	// action row
+	// action rows
 
-	actionRow := discordgo.ActionsRow{
+	participantRow := discordgo.ActionsRow{
 		Components: []discordgo.MessageComponent{
 			discordgo.Button{
 				Label: "Join",
@@ -195,18 +254,28 @@ func SessionMessageComponents(s models.Session) []discordgo.MessageComponent {
 				}.ToCustomID(),
 			},
 			discordgo.Button{
-				Label: "End",
-				Style: discordgo.DangerButton,
+				Label: "Leave",
+				Style: discordgo.SecondaryButton,
 				CustomID: InteractionID{
-					Type:    "end",
+					Type:    "leave",
 					TextCID: s.Record.TextCID,
 				}.ToCustomID(),
 			},
+		},
+	}
+	if s.Record.Status == pomomo.SessionLobby {
+		return lobbyMessageComponents(s, participantRow)
+	}
+	controlRow := discordgo.ActionsRow{
+		Components: []discordgo.MessageComponent{
+			skipButton(s),
+			pauseButton(s),
+			settingsButton(s),
 			discordgo.Button{
-				Label: "Skip",
-				Style: discordgo.SecondaryButton,
+				Label: "End",
+				Style: discordgo.DangerButton,
 				CustomID: InteractionID{
-					Type:    "skip",
+					Type:    "end",
 					TextCID: s.Record.TextCID,
 				}.ToCustomID(),
 			},
@@ -219,21 +288,30 @@ func SessionMessageComponents(s models.Session) []discordgo.MessageComponent {
 		fmt.Sprintf("%s: %d min", pomomo.PomodoroInterval, int(s.Settings.Pomodoro.Minutes())),
 		fmt.Sprintf("%s: %d min", pomomo.ShortBreakInterval, int(s.Settings.ShortBreak.Minutes())),
 		fmt.Sprintf("%s: %d min", pomomo.LongBreakInterval, int(s.Settings.LongBreak.Minutes())),
-		fmt.Sprintf("%s: %d | %d", "Interval", s.Stats.CompletedPomodoros%s.Settings.Intervals, s.Settings.Intervals),
+		fmt.Sprintf("%s: %d | %d", "Interval", s.Record.Stats.CompletedPomodoros%s.Settings.Intervals, s.Settings.Intervals),
 	}
-	switch s.Record.CurrentInterval {
-	case pomomo.PomodoroInterval:
+	switch {
+	case s.Settings.Mode == pomomo.FlowtimeMode:
+		settingsTextParts = flowtimeTextParts(s)
+	case len(s.Settings.Sequence) > 0:
+		settingsTextParts = sequenceTextParts(s)
+	case s.Record.CurrentInterval == pomomo.PomodoroInterval:
 		settingsTextParts[1] = fmt.Sprintf("**%s**\n%s", settingsTextParts[1], timerBar(s))
-	case pomomo.ShortBreakInterval:
+	case s.Record.CurrentInterval == pomomo.ShortBreakInterval:
 		settingsTextParts[2] = fmt.Sprintf("**%s**\n%s", settingsTextParts[2], timerBar(s))
-	case pomomo.LongBreakInterval:
+	case s.Record.CurrentInterval == pomomo.LongBreakInterval:
 		settingsTextParts[3] = fmt.Sprintf("**%s**\n%s", settingsTextParts[3], timerBar(s))
 	default:
 		settingsTextParts = append(settingsTextParts, timerBar(s))
 	}
+	settingsTextParts = append(settingsTextParts, goalTextParts(s)...)
 	accentColor := ColorGreen
 	if s.Record.Status == pomomo.SessionPaused {
 		accentColor = ColorLightGrey
+		settingsTextParts = append(settingsTextParts, "-# Paused")
+	}
+	if s.Record.HostUserID != "" {
+		settingsTextParts = append(settingsTextParts, fmt.Sprintf("-# Hosted by <@%s>", s.Record.HostUserID))
 	}
 	settingsContainer := discordgo.Container{
 		Components: []discordgo.MessageComponent{
@@ -249,16 +327,527 @@ func SessionMessageComponents(s models.Session) []discordgo.MessageComponent {
 	if s.Greeting != "" {
 		components = append(components, TextDisplay(s.Greeting))
 	}
-	components = append(components, settingsContainer, actionRow)
+	components = append(components, settingsContainer, participantRow, controlRow)
 	return components
 }
 
+// goalTextParts shows progress towards the session's goals, if any
+func goalTextParts(s models.Session) []string {
+	var parts []string
+	if s.Settings.GoalRounds > 0 {
+		parts = append(parts, fmt.Sprintf("Goal: %d | %d pomodoros", min(s.Record.Stats.CompletedPomodoros, s.Settings.GoalRounds), s.Settings.GoalRounds))
+	}
+	if !s.Settings.GoalUntil.IsZero() {
+		parts = append(parts, fmt.Sprintf("Goal: until <t:%d:t>", s.Settings.GoalUntil.Unix()))
+	}
+	return parts
+}
+
+// IntervalWarningComponents warns that the current interval is about to end
+func IntervalWarningComponents(s models.Session) []discordgo.MessageComponent {
+	endsAt := s.Record.IntervalStartedAt.Add(s.Record.TimeRemainingAtStart)
+	return []discordgo.MessageComponent{
+		TextDisplay(fmt.Sprintf(":hourglass: %s ends <t:%d:R>", s.Record.CurrentInterval, endsAt.Unix())),
+	}
+}
+
+// lobbyMessageComponents counts down to the first interval with a button for the host to start early
+func lobbyMessageComponents(s models.Session, participantRow discordgo.ActionsRow) []discordgo.MessageComponent {
+	startsAt := s.Record.IntervalStartedAt.Add(s.Record.TimeRemainingAtStart)
+	textParts := []string{
+		"### Lobby",
+		fmt.Sprintf("Starting <t:%d:R> - join to take part!", startsAt.Unix()),
+		fmt.Sprintf("Settings: %s", settingsSummary(s.Settings)),
+		fmt.Sprintf("Voice channel: <#%s>", s.Record.VoiceCID),
+	}
+	textParts = append(textParts, goalTextParts(s)...)
+	if s.Record.HostUserID != "" {
+		textParts = append(textParts, fmt.Sprintf("-# Hosted by <@%s>", s.Record.HostUserID))
+	}
+	controlRow := discordgo.ActionsRow{
+		Components: []discordgo.MessageComponent{
+			discordgo.Button{
+				Label: "Start now",
+				Style: discordgo.SuccessButton,
+				CustomID: InteractionID{
+					Type:    "start_now",
+					TextCID: s.Record.TextCID,
+				}.ToCustomID(),
+			},
+			settingsButton(s),
+			discordgo.Button{
+				Label: "End",
+				Style: discordgo.DangerButton,
+				CustomID: InteractionID{
+					Type:    "end",
+					TextCID: s.Record.TextCID,
+				}.ToCustomID(),
+			},
+		},
+	}
+
+	var components []discordgo.MessageComponent
+	if s.Greeting != "" {
+		components = append(components, TextDisplay(s.Greeting))
+	}
+	return append(components,
+		discordgo.Container{
+			Components: []discordgo.MessageComponent{
+				TextDisplay(strings.Join(textParts, "\n")),
+			},
+			AccentColor: ColorBlurple.ToInt(),
+		},
+		participantRow,
+		controlRow,
+	)
+}
+
+// sequenceTextParts lists each step of the session's sequence with the timer bar under the current step
+func sequenceTextParts(s models.Session) []string {
+	parts := []string{"### Session Settings"}
+	for i, step := range s.Settings.Sequence {
+		row := fmt.Sprintf("%s: %d min", step.Interval, int(step.Duration.Minutes()))
+		if i == s.Record.SequenceIndex {
+			row = fmt.Sprintf("**%s**\n%s", row, timerBar(s))
+		}
+		parts = append(parts, row)
+	}
+	return parts
+}
+
+// flowtimeTextParts shows the elapsed focus during open-ended pomodoros and the timer bar during breaks
+func flowtimeTextParts(s models.Session) []string {
+	parts := []string{
+		"### Session Settings",
+		fmt.Sprintf("Mode: %s", s.Settings.Mode),
+		fmt.Sprintf("Break: %d%% of focus", breakRatioPercent(s.Settings)),
+	}
+	if s.OpenEnded() {
+		return append(parts, fmt.Sprintf("**%s**\nFocused for %s", s.Record.CurrentInterval, formatDuration(s.Elapsed())))
+	}
+	return append(parts, fmt.Sprintf("**%s: %s**\n%s", s.Record.CurrentInterval, formatDuration(s.CurrentDuration()), timerBar(s)))
+}
+
+func breakRatioPercent(settings pomomo.SessionSettingsRecord) int {
+	return int(math.Round(settings.BreakRatio * 100))
+}
+
+// SessionSettingsComponents lists the session's settings without the timer or controls
+func SessionSettingsComponents(s models.Session) []discordgo.MessageComponent {
+	onOff := func(b bool) string {
+		if b {
+			return "off"
+		}
+		return "on"
+	}
+	textParts := []string{
+		"### Session Settings",
+		fmt.Sprintf("%s: %d min", pomomo.PomodoroInterval, int(s.Settings.Pomodoro.Minutes())),
+		fmt.Sprintf("%s: %d min", pomomo.ShortBreakInterval, int(s.Settings.ShortBreak.Minutes())),
+		fmt.Sprintf("%s: %d min", pomomo.LongBreakInterval, int(s.Settings.LongBreak.Minutes())),
+		fmt.Sprintf("Intervals: %d", s.Settings.Intervals),
+	}
+	if len(s.Settings.Sequence) > 0 {
+		textParts = []string{
+			"### Session Settings",
+			fmt.Sprintf("Sequence: %s", s.Settings.Sequence),
+		}
+	}
+	if s.Settings.Mode == pomomo.FlowtimeMode {
+		textParts = []string{
+			"### Session Settings",
+			fmt.Sprintf("Mode: %s", s.Settings.Mode),
+			fmt.Sprintf("Break: %d%% of focus", breakRatioPercent(s.Settings)),
+		}
+	}
+	textParts = append(textParts,
+		fmt.Sprintf("Mute: %s", onOff(s.Settings.NoMute)),
+		fmt.Sprintf("Deafen: %s", onOff(s.Settings.NoDeafen)),
+		fmt.Sprintf("Voice channel: <#%s>", s.Record.VoiceCID),
+	)
+	if s.Settings.MaxDuration > 0 {
+		textParts = append(textParts, fmt.Sprintf("Max length: %s", formatDuration(s.Settings.MaxDuration)))
+	}
+	if s.Settings.Warning > 0 {
+		textParts = append(textParts, fmt.Sprintf("Warning: %s before intervals end", formatDuration(s.Settings.Warning)))
+	}
+	if s.Settings.Ambient {
+		textParts = append(textParts, "Ambient: on")
+	}
+	if s.Settings.Volume > 0 && s.Settings.Volume != pomomo.DefaultVolume {
+		textParts = append(textParts, fmt.Sprintf("Volume: %d%%", s.Settings.Volume))
+	}
+	textParts = append(textParts, goalTextParts(s)...)
+	return []discordgo.MessageComponent{
+		discordgo.Container{
+			Components: []discordgo.MessageComponent{
+				TextDisplay(strings.Join(textParts, "\n")),
+			},
+			AccentColor: ColorGreen.ToInt(),
+		},
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{settingsButton(s)},
+		},
+	}
+}
+
+func GuildSettingsComponents(g pomomo.GuildSettingsRecord) []discordgo.MessageComponent {
+	var textCIDs, voiceCIDs []string
+	for _, cid := range g.AllowedTextCIDs {
+		textCIDs = append(textCIDs, fmt.Sprintf("<#%s>", cid))
+	}
+	for _, cid := range g.AllowedVoiceCIDs {
+		voiceCIDs = append(voiceCIDs, fmt.Sprintf("<#%s>", cid))
+	}
+	channels := func(mentions []string) string {
+		if len(mentions) == 0 {
+			return "any"
+		}
+		return strings.Join(mentions, ", ")
+	}
+	maxLength := "none"
+	if g.MaxSessionDuration > 0 {
+		maxLength = formatDuration(g.MaxSessionDuration)
+	}
+	moderatorRole := "none"
+	if g.ModeratorRoleID != "" {
+		moderatorRole = fmt.Sprintf("<@&%s>", g.ModeratorRoleID)
+	}
+	defaults := g.SessionDefaults()
+
+	textParts := []string{
+		"### Server Configuration",
+		fmt.Sprintf("Moderator role: %s", moderatorRole),
+		fmt.Sprintf("Timezone: %s", g.Location()),
+		fmt.Sprintf("Default %s: %d min", pomomo.PomodoroInterval, int(defaults.Pomodoro.Minutes())),
+		fmt.Sprintf("Default %s: %d min", pomomo.ShortBreakInterval, int(defaults.ShortBreak.Minutes())),
+		fmt.Sprintf("Default %s: %d min", pomomo.LongBreakInterval, int(defaults.LongBreak.Minutes())),
+		fmt.Sprintf("Default intervals: %d", defaults.Intervals),
+		fmt.Sprintf("Default no mute: %t", defaults.NoMute),
+		fmt.Sprintf("Default no deafen: %t", defaults.NoDeafen),
+		fmt.Sprintf("Default volume: %d%%", defaults.Volume),
+		fmt.Sprintf("Max session length: %s", maxLength),
+		fmt.Sprintf("Allowed text channels: %s", channels(textCIDs)),
+		fmt.Sprintf("Allowed voice channels: %s", channels(voiceCIDs)),
+	}
+	return []discordgo.MessageComponent{
+		discordgo.Container{
+			Components: []discordgo.MessageComponent{
+				TextDisplay(strings.Join(textParts, "\n")),
+			},
+			AccentColor: ColorBlurple.ToInt(),
+		},
+	}
+}
+
+// SchedulesMessageComponents lists the guild's schedules with their next start times
+func SchedulesMessageComponents(schedules []pomomo.ExistingSessionScheduleRecord, loc *time.Location) []discordgo.MessageComponent {
+	textParts := []string{fmt.Sprintf("### Schedules (%s)", loc)}
+	if len(schedules) == 0 {
+		textParts = append(textParts, "No sessions are scheduled. Create one with `/schedule create`.")
+	}
+	for _, s := range schedules {
+		textParts = append(textParts, scheduleSummary(s))
+	}
+	return []discordgo.MessageComponent{
+		discordgo.Container{
+			Components: []discordgo.MessageComponent{
+				TextDisplay(strings.Join(textParts, "\n")),
+			},
+			AccentColor: ColorGreen.ToInt(),
+		},
+	}
+}
+
+func scheduleSummary(s pomomo.ExistingSessionScheduleRecord) string {
+	summary := fmt.Sprintf("**%s** · <#%s> · next <t:%d:R>", s.Recurrence, s.VoiceCID, s.NextFireAt.Unix())
+	if s.PresetName != "" {
+		summary += fmt.Sprintf(" · %s", s.PresetName)
+	}
+	if s.PingRoleID != "" {
+		summary += fmt.Sprintf(" · pings <@&%s>", s.PingRoleID)
+	}
+	return summary
+}
+
+func PresetsMessageComponents(presets []pomomo.SessionPresetRecord) []discordgo.MessageComponent {
+	sections := []struct {
+		title   string
+		matches func(pomomo.SessionPresetRecord) bool
+	}{
+		{"### Your Presets", func(p pomomo.SessionPresetRecord) bool { return p.GuildID != "" && p.UserID != "" }},
+		{"### Server Presets", func(p pomomo.SessionPresetRecord) bool { return p.GuildID != "" && p.UserID == "" }},
+		{"### Built-in Presets", func(p pomomo.SessionPresetRecord) bool { return p.GuildID == "" }},
+	}
+	var textParts []string
+	for _, section := range sections {
+		var rows []string
+		for _, p := range presets {
+			if section.matches(p) {
+				rows = append(rows, fmt.Sprintf("**%s** · %s", p.Name, presetSummary(p)))
+			}
+		}
+		if len(rows) > 0 {
+			textParts = append(textParts, section.title)
+			textParts = append(textParts, rows...)
+		}
+	}
+	return []discordgo.MessageComponent{
+		discordgo.Container{
+			Components: []discordgo.MessageComponent{
+				TextDisplay(strings.Join(textParts, "\n")),
+			},
+			AccentColor: ColorGreen.ToInt(),
+		},
+	}
+}
+
+// settingsSummary condenses session settings to a line, e.g. "25/5/15 ×4"
+func settingsSummary(s pomomo.SessionSettingsRecord) string {
+	switch {
+	case s.Mode == pomomo.FlowtimeMode:
+		return fmt.Sprintf("%s, break %d%% of focus", s.Mode, breakRatioPercent(s))
+	case len(s.Sequence) > 0:
+		return s.Sequence.String()
+	}
+	return fmt.Sprintf("%d/%d/%d ×%d", int(s.Pomodoro.Minutes()), int(s.ShortBreak.Minutes()), int(s.LongBreak.Minutes()), s.Intervals)
+}
+
+// presetSummary formats durations in minutes as pomodoro/short break/long break
+func presetSummary(p pomomo.SessionPresetRecord) string {
+	return fmt.Sprintf("%d/%d/%d ×%d", int(p.Pomodoro.Minutes()), int(p.ShortBreak.Minutes()), int(p.LongBreak.Minutes()), p.Intervals)
+}
+
+// SettingsEditPromptComponents asks whether an edit should rescale the current interval's time remaining
+func SettingsEditPromptComponents(s models.Session) []discordgo.MessageComponent {
+	return []discordgo.MessageComponent{
+		TextDisplay(fmt.Sprintf("How should the new settings apply to the current %s?", s.Record.CurrentInterval)),
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label: "Rescale time remaining",
+					Style: discordgo.PrimaryButton,
+					CustomID: InteractionID{
+						Type:    "settings_rescale",
+						TextCID: s.Record.TextCID,
+					}.ToCustomID(),
+				},
+				discordgo.Button{
+					Label: "Keep time remaining",
+					Style: discordgo.SecondaryButton,
+					CustomID: InteractionID{
+						Type:    "settings_keep",
+						TextCID: s.Record.TextCID,
+					}.ToCustomID(),
+				},
+			},
+		},
+	}
+}
+
+const (
+	settingsShushInput    = "shush"
+	settingsSequenceInput = "sequence"
+	settingsBreaksInput   = "breaks"
+)
+
+const (
+	settingsShushLabel  = "Shush during pomodoros (mute, deafen)"
+	settingsVolumeLabel = "Alert volume (%)"
+)
+
+// SettingsModalComponents returns the settings modal's inputs pre-filled with the session's settings
+func SettingsModalComponents(s models.Session) []discordgo.MessageComponent {
+	var shush []string
+	if !s.Settings.NoMute {
+		shush = append(shush, "mute")
+	}
+	if !s.Settings.NoDeafen {
+		shush = append(shush, "deafen")
+	}
+	input := func(customID, label, value string) discordgo.ActionsRow {
+		return discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.TextInput{
+					CustomID:  customID,
+					Label:     label,
+					Style:     discordgo.TextInputShort,
+					Value:     value,
+					Required:  customID != settingsShushInput,
+					MaxLength: 20,
+				},
+			},
+		}
+	}
+	volume := s.Settings.Volume
+	if volume <= 0 {
+		volume = pomomo.DefaultVolume
+	}
+	if s.Settings.Mode == pomomo.FlowtimeMode {
+		return []discordgo.MessageComponent{
+			input(pomomo.BreakRatioOption, "Break (% of focus)", strconv.Itoa(breakRatioPercent(s.Settings))),
+			input(settingsShushInput, settingsShushLabel, strings.Join(shush, ", ")),
+			input(pomomo.VolumeOption, settingsVolumeLabel, strconv.Itoa(volume)),
+		}
+	}
+	if len(s.Settings.Sequence) > 0 {
+		return []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:    settingsSequenceInput,
+						Label:       "Sequence (interval and minutes per step)",
+						Style:       discordgo.TextInputParagraph,
+						Value:       s.Settings.Sequence.String(),
+						Placeholder: "warmup 10, focus 50, break 10, focus 50, long 30",
+						Required:    true,
+						MaxLength:   400,
+					},
+				},
+			},
+			input(settingsShushInput, settingsShushLabel, strings.Join(shush, ", ")),
+			input(pomomo.VolumeOption, settingsVolumeLabel, strconv.Itoa(volume)),
+		}
+	}
+	minutes := func(d time.Duration) string {
+		return strconv.Itoa(int(d.Minutes()))
+	}
+	// modals fit 5 inputs, so both breaks share one
+	return []discordgo.MessageComponent{
+		input(pomomo.PomodoroOption, "Pomodoro (minutes)", minutes(s.Settings.Pomodoro)),
+		input(settingsBreaksInput, "Breaks (short, long minutes)", minutes(s.Settings.ShortBreak)+", "+minutes(s.Settings.LongBreak)),
+		input(pomomo.IntervalsOption, "Intervals between long breaks", strconv.Itoa(s.Settings.Intervals)),
+		input(settingsShushInput, settingsShushLabel, strings.Join(shush, ", ")),
+		input(pomomo.VolumeOption, settingsVolumeLabel, strconv.Itoa(volume)),
+	}
+}
+
+// ModalSubmitValues maps the submitted modal's text input IDs to their values
+func ModalSubmitValues(data discordgo.ModalSubmitInteractionData) map[string]string {
+	values := make(map[string]string)
+	var collect func(components []discordgo.MessageComponent)
+	collect = func(components []discordgo.MessageComponent) {
+		for _, c := range components {
+			switch c := c.(type) {
+			case *discordgo.ActionsRow:
+				collect(c.Components)
+			case discordgo.ActionsRow:
+				collect(c.Components)
+			case *discordgo.TextInput:
+				values[c.CustomID] = c.Value
+			case discordgo.TextInput:
+				values[c.CustomID] = c.Value
+			}
+		}
+	}
+	collect(data.Components)
+	return values
+}
+
+func settingsButton(s models.Session) discordgo.Button {
+	return discordgo.Button{
+		Label: "Settings",
+		Style: discordgo.SecondaryButton,
+		CustomID: InteractionID{
+			Type:    "settings",
+			TextCID: s.Record.TextCID,
+		}.ToCustomID(),
+	}
+}
+
+// SessionSummaryComponents replaces the session message once the session has ended
+func SessionSummaryComponents(s models.Session, participants []pomomo.SessionParticipantStatsRecord) []discordgo.MessageComponent {
+	textParts := []string{"### Session Summary"}
+	if !s.CreatedAt.IsZero() {
+		endedAt := s.Record.EndedAt
+		if endedAt.IsZero() {
+			endedAt = time.Now()
+		}
+		textParts = append(textParts, fmt.Sprintf("Duration: %s", formatDuration(endedAt.Sub(s.CreatedAt))))
+	}
+	textParts = append(textParts,
+		fmt.Sprintf("Pomodoros completed: %d", s.Record.Stats.CompletedPomodoros),
+		fmt.Sprintf("Long breaks taken: %d", s.Record.Stats.LongBreaks),
+		fmt.Sprintf("Skips: %d", s.Record.Stats.Skips),
+	)
+	if s.HasGoal() {
+		if s.GoalReached() {
+			textParts = append(textParts, "Goal met! :tada:")
+		} else {
+			textParts = append(textParts, goalTextParts(s)...)
+		}
+	}
+	if len(participants) > 0 {
+		textParts = append(textParts, "### Focus Time")
+		for _, p := range participants {
+			row := fmt.Sprintf("<@%s> · %s", p.UserID, formatDuration(p.FocusTime))
+			if !p.LeftAt.IsZero() {
+				row += " (left early)"
+			}
+			textParts = append(textParts, row)
+		}
+	}
+
+	return []discordgo.MessageComponent{
+		TextDisplay(getFarewell()),
+		discordgo.Container{
+			Components: []discordgo.MessageComponent{
+				TextDisplay(strings.Join(textParts, "\n")),
+			},
+			AccentColor: ColorDarkGrey.ToInt(),
+		},
+	}
+}
+
+// skipButton takes a break instead of skipping during open-ended pomodoros
+func skipButton(s models.Session) discordgo.Button {
+	if s.OpenEnded() {
+		return discordgo.Button{
+			Label: "Take a break",
+			Style: discordgo.SuccessButton,
+			CustomID: InteractionID{
+				Type:    "flowbreak",
+				TextCID: s.Record.TextCID,
+			}.ToCustomID(),
+		}
+	}
+	return discordgo.Button{
+		Label: "Skip",
+		Style: discordgo.SecondaryButton,
+		CustomID: InteractionID{
+			Type:    "skip",
+			TextCID: s.Record.TextCID,
+		}.ToCustomID(),
+	}
+}
+
+func pauseButton(s models.Session) discordgo.Button {
+	if s.Record.Status == pomomo.SessionPaused {
+		return discordgo.Button{
+			Label: "Resume",
+			Style: discordgo.SuccessButton,
+			CustomID: InteractionID{
+				Type:    "resume",
+				TextCID: s.Record.TextCID,
+			}.ToCustomID(),
+		}
+	}
+	return discordgo.Button{
+		Label: "Pause",
+		Style: discordgo.SecondaryButton,
+		CustomID: InteractionID{
+			Type:    "pause",
+			TextCID: s.Record.TextCID,
+		}.ToCustomID(),
+	}
+}
+
 func timerBar(s models.Session) string {
 	const length = 20
 	filledChar := timerBarFilledChar
 	emptyChar := timerBarEmptyChar
 	remaining := s.TimeRemaining().Minutes()
-	if remaining <= 0 {
+	if remaining <= 0 || s.CurrentDuration() <= 0 {
 		return strings.Repeat(emptyChar, length)
 	}
 	percentage := remaining / s.CurrentDuration().Minutes()
@@ -266,6 +855,117 @@ func timerBar(s models.Session) string {
 	return strings.Repeat(filledChar, filled) + strings.Repeat(emptyChar, length-filled)
 }
 
+func UserStatsMessageComponents(name string, rng pomomo.StatsRange, summary pomomo.StatsSummary, streak pomomo.ParticipantStreakRecord) []discordgo.MessageComponent {
+	now := time.Now()
+	extra := []string{
+		fmt.Sprintf("Current streak: %s", pluralize(streak.Streak(now), "day")),
+		fmt.Sprintf("Longest streak: %s", pluralize(streak.LongestStreak, "day")),
+	}
+	return statsMessageComponents(name+"'s Stats", rng, summary, ColorBlue, extra...)
+}
+
+func GuildStatsMessageComponents(rng pomomo.StatsRange, summary pomomo.StatsSummary) []discordgo.MessageComponent {
+	return statsMessageComponents("Server Stats", rng, summary, ColorGold, fmt.Sprintf("Members: %d", summary.Members))
+}
+
+func statsMessageComponents(title string, rng pomomo.StatsRange, summary pomomo.StatsSummary, accentColor Color, extra ...string) []discordgo.MessageComponent {
+	if summary.ActiveDays == 0 {
+		return []discordgo.MessageComponent{
+			TextDisplay(fmt.Sprintf("No focus stats recorded for %s yet.", strings.ToLower(rng.String()))),
+		}
+	}
+
+	textParts := []string{
+		fmt.Sprintf("### %s", title),
+		fmt.Sprintf("-# %s", rng),
+		fmt.Sprintf("Focus time: %s", formatDuration(summary.FocusTime)),
+		fmt.Sprintf("Pomodoros completed: %d", summary.CompletedPomodoros),
+		fmt.Sprintf("Breaks taken: %d", summary.BreaksTaken),
+		fmt.Sprintf("Active days: %d", summary.ActiveDays),
+	}
+	textParts = append(textParts, extra...)
+	return []discordgo.MessageComponent{
+		discordgo.Container{
+			Components: []discordgo.MessageComponent{
+				TextDisplay(strings.Join(textParts, "\n")),
+			},
+			AccentColor: accentColor.ToInt(),
+		},
+	}
+}
+
+func LeaderboardMessageComponents(metric pomomo.LeaderboardMetric, rng pomomo.StatsRange, entries []pomomo.LeaderboardEntry, streaks []pomomo.ParticipantStreakRecord) []discordgo.MessageComponent {
+	var title string
+	var rows []string
+	switch metric {
+	case pomomo.LeaderboardStreak:
+		title = "Streak Leaderboard"
+		now := time.Now()
+		for i, streak := range streaks {
+			rows = append(rows, fmt.Sprintf("%s <@%s> · %s", leaderboardRank(i), streak.UserID, pluralize(streak.Streak(now), "day")))
+		}
+	case pomomo.LeaderboardPomodoros:
+		title = "Pomodoro Leaderboard"
+		for i, e := range entries {
+			rows = append(rows, fmt.Sprintf("%s <@%s> · %s", leaderboardRank(i), e.UserID, pluralize(e.CompletedPomodoros, "pomodoro")))
+		}
+	default:
+		title = "Focus Leaderboard"
+		for i, e := range entries {
+			rows = append(rows, fmt.Sprintf("%s <@%s> · %s", leaderboardRank(i), e.UserID, formatDuration(e.FocusTime)))
+		}
+	}
+	if len(rows) == 0 {
+		return []discordgo.MessageComponent{
+			TextDisplay("Nobody is on the leaderboard yet. Start a session to claim the top spot!"),
+		}
+	}
+
+	subtitle := fmt.Sprintf("-# %s", rng)
+	if metric == pomomo.LeaderboardStreak {
+		subtitle = "-# Consecutive days with a completed pomodoro"
+	}
+	return []discordgo.MessageComponent{
+		discordgo.Container{
+			Components: []discordgo.MessageComponent{
+				TextDisplay(fmt.Sprintf("### %s\n%s\n%s", title, subtitle, strings.Join(rows, "\n"))),
+			},
+			AccentColor: ColorPurple.ToInt(),
+		},
+	}
+}
+
+func leaderboardRank(i int) string {
+	switch i {
+	case 0:
+		return ":first_place:"
+	case 1:
+		return ":second_place:"
+	case 2:
+		return ":third_place:"
+	default:
+		return fmt.Sprintf("**%d.**", i+1)
+	}
+}
+
+func pluralize(n int, unit string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, unit)
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
+
+// formatDuration formats d to the minute, e.g. "2h 5m"
+func formatDuration(d time.Duration) string {
+	d = d.Truncate(time.Minute)
+	h := int(d.Hours())
+	mins := int(d.Minutes()) % 60
+	if h == 0 {
+		return fmt.Sprintf("%dm", mins)
+	}
+	return fmt.Sprintf("%dh %dm", h, mins)
+}
+
 var greetings = []string{
 	"Howdy howdy! Let's do this thang :cowboy:",
 	"Hey there! Let's get started :books:",
@@ -284,6 +984,5 @@ func getGreeting() string {
 }
 
 func getFarewell() string {
-	// TODO display stats in end message
 	return farewells[rand.Intn(len(farewells))]
 }
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benjamonnguyen/pomomo-go"
+	"github.com/benjamonnguyen/pomomo-go/cmd/bot/models"
+	"github.com/bwmarrin/discordgo"
+)
+
+// containerText returns the text of the message's container
+func containerText(t *testing.T, components []discordgo.MessageComponent) string {
+	t.Helper()
+	for _, c := range components {
+		if container, ok := c.(discordgo.Container); ok {
+			var text []string
+			for _, cc := range container.Components {
+				if td, ok := cc.(discordgo.TextDisplay); ok {
+					text = append(text, td.Content)
+				}
+			}
+			return strings.Join(text, "\n")
+		}
+	}
+	t.Fatal("message has no container")
+	return ""
+}
+
+func TestSessionSummaryComponentsDuration(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name               string
+		createdAt, endedAt time.Time
+		want               string
+	}{
+		{"ended", now.Add(-5 * time.Hour), now.Add(-5*time.Hour + 90*time.Minute), "Duration: 1h 30m"},
+		{"ending now", now.Add(-45 * time.Minute), time.Time{}, "Duration: 45m"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := models.NewSession("s1", "g1", "t1", "v1", "m1", pomomo.SessionSettingsRecord{})
+			s.CreatedAt = tt.createdAt
+			s.Record.EndedAt = tt.endedAt
+			s.Record.Status = pomomo.SessionEnded
+			if text := containerText(t, SessionSummaryComponents(s, nil)); !strings.Contains(text, tt.want) {
+				t.Errorf("summary = %q, want %q", text, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+
+	"github.com/benjamonnguyen/pomomo-go"
+	"github.com/benjamonnguyen/pomomo-go/sqlite"
+)
+
+type GuildSettingsRepo interface {
+	UpsertGuildSettings(context.Context, pomomo.GuildSettingsRecord) (pomomo.ExistingGuildSettingsRecord, error)
+	GetGuildSettings(ctx context.Context, guildID string) (pomomo.ExistingGuildSettingsRecord, error)
+}
+
+// getGuildSettings falls back to the default settings for guilds that haven't configured anything.
+// Defaults are also returned alongside any error so that callers can carry on.
+func getGuildSettings(ctx context.Context, repo GuildSettingsRepo, guildID string) (pomomo.GuildSettingsRecord, error) {
+	existing, err := repo.GetGuildSettings(ctx, guildID)
+	if err == sqlite.ErrNotFound {
+		return pomomo.NewGuildSettingsRecord(guildID), nil
+	}
+	if err != nil {
+		return pomomo.NewGuildSettingsRecord(guildID), err
+	}
+	return existing.GuildSettingsRecord, nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/benjamonnguyen/pomomo-go"
+)
+
+func TestGetGuildSettings(t *testing.T) {
+	configured := pomomo.NewGuildSettingsRecord("g1")
+	configured.DefaultPomodoro = 50 * time.Minute
+	configured.AllowedTextCIDs = []pomomo.TextChannelID{"t1"}
+	repo := &fakeGuildSettingsRepo{settings: map[string]pomomo.GuildSettingsRecord{"g1": configured}}
+
+	tests := []struct {
+		name    string
+		guildID string
+		err     error
+		want    pomomo.GuildSettingsRecord
+		wantErr bool
+	}{
+		{"configured", "g1", nil, configured, false},
+		{"not configured", "g2", nil, pomomo.NewGuildSettingsRecord("g2"), false},
+		// callers carry on with the defaults
+		{"failed", "g1", errors.New("database is locked"), pomomo.NewGuildSettingsRecord("g1"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo.err = tt.err
+			got, err := getGuildSettings(context.Background(), repo, tt.guildID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getGuildSettings() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getGuildSettings() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -22,6 +22,9 @@ import (
 	dg "github.com/bwmarrin/discordgo"
 	"github.com/charmbracelet/log"
 	_ "modernc.org/sqlite"
+
+	// guild timezones shouldn't depend on the host's zoneinfo
+	_ "time/tzdata"
 )
 
 //go:embed migrations/*.sql
@@ -44,6 +47,7 @@ func main() {
 	var dbURL, botToken, botName string
 	var shardID, shardCnt string
 	var logLvl, logFile string
+	var retentionDays string
 	panicif(conf.GetMany([]cfg.Key{
 		pomomo.DatabaseURLKey,
 		pomomo.BotTokenKey,
@@ -52,8 +56,10 @@ func main() {
 		pomomo.ShardCountKey,
 		pomomo.LogLevelKey,
 		pomomo.LogFileKey,
+		pomomo.SessionRetentionDaysKey,
 	}, &dbURL, &botToken, &botName,
-		&shardID, &shardCnt, &logLvl, &logFile))
+		&shardID, &shardCnt, &logLvl, &logFile,
+		&retentionDays))
 
 	// logger
 	log.SetReportCaller(true)
@@ -92,6 +98,11 @@ func main() {
 	// repos
 	sessionRepo := sqlite.NewSessionRepo(dbGetter, *log.Default())
 	participantRepo := sqlite.NewParticipantRepo(dbGetter, *log.Default())
+	participantStatsRepo := sqlite.NewParticipantStatsRepo(dbGetter, *log.Default())
+	guildSettingsRepo := sqlite.NewGuildSettingsRepo(dbGetter, *log.Default())
+	presetRepo := sqlite.NewPresetRepo(dbGetter, *log.Default())
+	scheduleRepo := sqlite.NewScheduleRepo(dbGetter, *log.Default())
+	soundRepo := sqlite.NewSoundRepo(dbGetter, *log.Default())
 
 	// set up discord cl
 	cl, err := dg.New("Bot " + botToken)
@@ -114,42 +125,55 @@ func main() {
 	cl.ShouldReconnectVoiceOnSessionError = true
 
 	dm := NewDiscordMessenger(cl)
-	discordAdapter := discordgo.NewDiscordAdapter(cl)
+	// session messages are edited through the editor so that frequent updates are coalesced
+	editor := newMessageEditor(dm)
+	discordAdapter := discordgo.NewDiscordAdapter(cl, discordgo.NewVoiceConnectionManager(cl, discordgo.DefaultVoiceIdleTimeout))
 
 	// participant manager
 	pm := NewParticipantManager(participantRepo, *log.Default())
 
+	// stats
+	statsRecorder := NewStatsRecorder(participantStatsRepo, *log.Default())
+
+	// permissions
+	authorizer := NewSessionAuthorizer(guildSettingsRepo, pm)
+
 	// audio
-	opusAudioLoader := newOpusAudioLoader(sounds)
+	opusAudioLoader := newOpusAudioLoader(sounds, soundRepo)
 	autoshusher := &autoshusher{
 		loadFn: opusAudioLoader.Load,
 		sendFn: discordAdapter.SendOpusAudio,
 		pm:     pm,
 		vs:     discordAdapter,
 	}
+	ambience := &ambience{
+		loadFn: opusAudioLoader.Load,
+		player: discordAdapter,
+	}
 
 	// session manager
 	sessionManager := NewSessionManager(topCtx, sessionRepo, pm, tx)
 	sessionManager.AfterUpdate(func(ctx context.Context, before, curr models.Session) {
 		if curr.Record.Status == pomomo.SessionEnded {
+			unlock := pm.AcquireVoiceChannelLock(curr.Record.VoiceCID)
+			defer unlock()
+			participants := pm.GetAll(curr.Record.VoiceCID)
+			statsRecorder.RecordUpdate(ctx, participants, before, curr)
+
 			var wg sync.WaitGroup
 			wg.Go(func() {
 				// handle channel message cleanup
-				_, err := dm.EditChannelMessage(curr.Record.TextCID, curr.Record.MessageID, SessionMessageComponents(curr)...)
+				participantStats, err := participantStatsRepo.GetSessionParticipantStats(ctx, curr.ID)
 				if err != nil {
-					log.Error("failed to edit discord channel message", "channelID", curr.Record.VoiceCID, "messageID", curr.Record.MessageID, "sessionID", curr.ID, "err", err)
+					log.Error("failed to get session participant stats", "sessionID", curr.ID, "err", err)
 				}
+				editor.EditFinal(curr.Record.TextCID, curr.Record.MessageID, SessionSummaryComponents(curr, participantStats)...)
 				if err := cl.ChannelMessageUnpin(string(curr.Record.TextCID), curr.Record.MessageID); err != nil {
 					log.Error("failed to unpin discord channel message", "channelID", curr.Record.VoiceCID, "messageID", curr.Record.MessageID, "sessionID", curr.ID, "err", err)
 				}
 			})
 			wg.Go(func() {
 				// handle participant cleanup
-				unlock := pm.AcquireVoiceChannelLock(curr.Record.VoiceCID)
-				defer unlock()
-				participants := pm.GetAll(curr.Record.VoiceCID)
-
-				var wgg sync.WaitGroup
 				for _, p := range participants {
 					if err := restoreVoiceState(ctx, discordAdapter, p); err != nil {
 						log.Error(err)
@@ -158,15 +182,14 @@ func main() {
 						log.Error(err)
 					}
 				}
-				wgg.Wait()
-
-				cnt := sessionManager.GuildSessionCnt(curr.Record.GuildID)
-				if cnt == 0 {
-					if conn := cl.VoiceConnections[string(curr.Record.GuildID)]; conn != nil {
-						if err := conn.Disconnect(); err != nil {
-							log.Error(err)
-						}
-					}
+
+				// the ended session is already uncached so any left share the guild's voice connection
+				if sessionManager.GuildSessionCnt(curr.Record.GuildID) > 0 {
+					discordAdapter.StopAmbient(curr.Record.GuildID, curr.Record.VoiceCID)
+					return
+				}
+				if err := discordAdapter.DisconnectVoice(curr.Record.GuildID, curr.Record.VoiceCID); err != nil {
+					log.Error(err)
 				}
 			})
 			wg.Wait()
@@ -177,8 +200,10 @@ func main() {
 		defer unlock()
 		participants := pm.GetAll(curr.Record.VoiceCID)
 
-		// end empty session
-		if len(participants) == 0 {
+		// end empty session - lobbies and scheduled sessions get a chance to fill up first
+		empty := len(participants) == 0 && curr.Record.Status != pomomo.SessionLobby && !awaitingParticipants(curr)
+		deserted := lobbyDeserted(before, curr, participants)
+		if empty || deserted {
 			// start go routine so that we don't get deadlocked from a recursive trigger
 			go func() {
 				_, err := sessionManager.EndSession(ctx, curr.Record.TextCID)
@@ -186,39 +211,95 @@ func main() {
 					log.Error("failed to end empty session", "sid", curr.ID, "err", err)
 					return
 				}
+				if deserted {
+					log.Info("dropped session - nobody joined the host before the lobby countdown ran out", "sid", curr.ID, "hostStayed", len(participants) > 0)
+					return
+				}
 				log.Debug("ended empty session", "sid", curr.ID)
 			}()
 			return
 		}
 
+		// update timer bar
+		editor.Edit(curr.Record.TextCID, curr.Record.MessageID, SessionMessageComponents(curr)...)
+
+		if curr.Settings.Volume != before.Settings.Volume {
+			// covers restored sessions and volume changes
+			opusAudioLoader.Prepare(curr.Record.GuildID, curr.Settings.Volume)
+		}
+
 		var wg sync.WaitGroup
 		wg.Go(func() {
-			// update timer bar
-			_, err := dm.EditChannelMessage(curr.Record.TextCID, curr.Record.MessageID, SessionMessageComponents(curr)...)
-			if err != nil {
-				log.Error("failed to edit discord channel message",
-					"channelID", curr.Record.VoiceCID, "messageID", curr.Record.MessageID, "sessionID", curr.ID, "err", err)
-			}
+			// alerts preempt the ambient track anyway but this keeps it from playing a moment around them
+			ambience.Silence(curr)
+			autoshusher.Autoshush(ctx, participants, before, curr)
+			ambience.Play(ctx, curr)
 		})
 
 		wg.Go(func() {
-			autoshusher.Autoshush(ctx, participants, before, curr)
-			// TODO persist participant stats
+			statsRecorder.RecordUpdate(ctx, participants, before, curr)
 		})
 
 		wg.Wait()
 	})
 
+	sessionManager.OnIntervalWarning(func(ctx context.Context, s models.Session) {
+		unlock := pm.AcquireVoiceChannelLock(s.Record.VoiceCID)
+		participants := pm.GetAll(s.Record.VoiceCID)
+		unlock()
+		if len(participants) == 0 {
+			return
+		}
+
+		var wg sync.WaitGroup
+		wg.Go(func() {
+			if err := playWarningAlert(ctx, s, opusAudioLoader.Load, discordAdapter.SendOpusAudio); err != nil {
+				log.Error("failed to play warning alert", "guildID", s.Record.GuildID, "channelID", s.Record.VoiceCID, "err", err)
+			}
+		})
+		wg.Go(func() {
+			msg, err := dm.SendChannelMessage(s.Record.TextCID, IntervalWarningComponents(s)...)
+			if err != nil {
+				log.Error("failed to send warning message", "channelID", s.Record.TextCID, "sessionID", s.ID, "err", err)
+				return
+			}
+			// the warning is stale once the interval ends or changes
+			<-ctx.Done()
+			if err := dm.DeleteChannelMessage(s.Record.TextCID, msg.ID); err != nil {
+				log.Error("failed to delete warning message", "channelID", s.Record.TextCID, "messageID", msg.ID, "err", err)
+			}
+		})
+		wg.Wait()
+	})
+
 	// discord event hooks
 	cl.AddHandler(func(s *dg.Session, u *dg.VoiceStateUpdate) {
 		_ = RestoreParticipantVoiceStateOnChannelJoin(topCtx, discordAdapter, pm, s, u) ||
-			RemoveParticipantOnVoiceChannelLeave(topCtx, discordAdapter, pm, s, u)
+			RemoveParticipantOnVoiceChannelLeave(topCtx, sessionManager, discordAdapter, pm, statsRecorder, s, u)
 	})
 	cl.AddHandler(func(s *dg.Session, m *dg.InteractionCreate) {
-		_ = StartSession(topCtx, sessionManager, dm, pm, s, m) ||
-			SkipInterval(topCtx, sessionManager, dm, s, m) ||
-			EndSession(topCtx, sessionManager, s, m) ||
-			JoinSession(topCtx, sessionManager, autoshusher, pm, dm, s, m)
+		_ = StartSession(topCtx, sessionManager, guildSettingsRepo, presetRepo, opusAudioLoader, dm, pm, statsRecorder, s, m) ||
+			SkipInterval(topCtx, sessionManager, authorizer, dm, s, m) ||
+			TakeBreak(topCtx, sessionManager, authorizer, dm, s, m) ||
+			StartNow(topCtx, sessionManager, authorizer, dm, s, m) ||
+			EndSession(topCtx, sessionManager, authorizer, dm, s, m) ||
+			PauseSession(topCtx, sessionManager, authorizer, dm, s, m) ||
+			ResumeSession(topCtx, sessionManager, authorizer, dm, s, m) ||
+			JoinSession(topCtx, sessionManager, autoshusher, pm, statsRecorder, dm, s, m) ||
+			LeaveSession(topCtx, sessionManager, discordAdapter, pm, statsRecorder, dm, s, m) ||
+			ShowStatus(topCtx, sessionManager, dm, s, m) ||
+			ShowSettings(topCtx, sessionManager, dm, s, m) ||
+			EditSettings(topCtx, sessionManager, authorizer, dm, s, m) ||
+			SubmitSettings(topCtx, sessionManager, authorizer, dm, s, m) ||
+			TransferHost(topCtx, sessionManager, authorizer, pm, dm, s, m) ||
+			ConfigureGuild(topCtx, guildSettingsRepo, scheduleRepo, dm, s, m) ||
+			ManagePresets(topCtx, presetRepo, guildSettingsRepo, dm, s, m) ||
+			AutocompletePreset(topCtx, presetRepo, dm, s, m) ||
+			ManageSchedules(topCtx, scheduleRepo, guildSettingsRepo, presetRepo, dm, s, m) ||
+			AutocompleteSchedule(topCtx, scheduleRepo, dm, s, m) ||
+			ManageSounds(topCtx, soundRepo, opusAudioLoader, dm, s, m) ||
+			ShowStats(topCtx, participantStatsRepo, dm, s, m) ||
+			ShowLeaderboard(topCtx, participantStatsRepo, dm, s, m)
 	})
 
 	// start up
@@ -228,6 +309,16 @@ func main() {
 	panicif(pm.RestoreCache(initTimeout))
 	panicif(sessionManager.RestoreSessions(initTimeout))
 	initTimeoutC()
+
+	// session history
+	days, err := strconv.Atoi(retentionDays)
+	panicif(err)
+	pruner := newSessionPruner(sessionRepo, tx, time.Duration(days)*24*time.Hour)
+	go pruner.Run(topCtx)
+
+	// schedules are fired after sessions are restored so that busy channels are skipped
+	scheduler := newSessionScheduler(scheduleRepo, guildSettingsRepo, presetRepo, sessionManager, dm)
+	go scheduler.Run(topCtx)
 	log.Info(botName + " running. Press CTRL-C to exit.")
 
 	// graceful shutdown
@@ -266,7 +357,7 @@ func main() {
 }
 
 type (
-	loadOpusAudio func(audio) [][]byte
+	loadOpusAudio func(ctx context.Context, guildID string, a audio, volume int) [][]byte
 	sendOpusAudio func(context.Context, [][]byte, string, pomomo.VoiceChannelID) error
 )
 
@@ -282,10 +373,10 @@ func playIntervalAlert(
 			a = PomodoroAudio
 		case pomomo.LongBreakInterval:
 			a = LongBreakAudio
-		case pomomo.ShortBreakInterval:
+		case pomomo.ShortBreakInterval, pomomo.WarmUpInterval:
 			a = ShortBreakAudio
 		}
-		data := loadFn(a)
+		data := loadFn(ctx, s.Record.GuildID, a, s.Settings.Volume)
 		if data == nil {
 			return fmt.Errorf("no data for audio %s", a)
 		}
@@ -297,6 +388,17 @@ func playIntervalAlert(
 	return nil
 }
 
+func playWarningAlert(
+	ctx context.Context, s models.Session,
+	loadFn loadOpusAudio, sendFn sendOpusAudio,
+) error {
+	data := loadFn(ctx, s.Record.GuildID, WarningAudio, s.Settings.Volume)
+	if data == nil {
+		return fmt.Errorf("no data for audio %s", WarningAudio)
+	}
+	return sendFn(ctx, data, s.Record.GuildID, s.Record.VoiceCID)
+}
+
 type VoiceStateAdapter interface {
 	UpdateVoiceState(gid, uid string, mute, deaf bool) error
 	GetVoiceState(gid, uid string) (pomomo.VoiceState, error)
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/benjamonnguyen/pomomo-go"
+	"github.com/bwmarrin/discordgo"
+	"github.com/charmbracelet/log"
+)
+
+// finalEditRetention is how long late edits are dropped after a message's final edit
+var finalEditRetention = time.Minute
+
+// messageEditor coalesces edits to the same message. At most one edit per message is in flight and
+// only the latest queued content is sent after it, so bursts of updates cost a single edit. Content
+// that matches the last sent edit is skipped.
+type messageEditor struct {
+	dm DiscordMessenger
+
+	mu       sync.Mutex
+	messages map[string]*messageEdits
+}
+
+type messageEdits struct {
+	cID        pomomo.TextChannelID
+	pending    []discordgo.MessageComponent
+	hasPending bool
+	inFlight   bool
+	final      bool   // edits queued after the final one are dropped
+	last       string // content of the last sent edit - only accessed by the flushing goroutine
+}
+
+func newMessageEditor(dm DiscordMessenger) *messageEditor {
+	return &messageEditor{
+		dm:       dm,
+		messages: make(map[string]*messageEdits),
+	}
+}
+
+// Edit queues an edit to the message, replacing any queued edit that hasn't been sent yet
+func (e *messageEditor) Edit(cID pomomo.TextChannelID, messageID string, components ...discordgo.MessageComponent) {
+	e.queue(cID, messageID, false, components)
+}
+
+// EditFinal queues the last edit to the message, e.g. a session summary, which later edits can't overwrite
+func (e *messageEditor) EditFinal(cID pomomo.TextChannelID, messageID string, components ...discordgo.MessageComponent) {
+	e.queue(cID, messageID, true, components)
+}
+
+func (e *messageEditor) queue(cID pomomo.TextChannelID, messageID string, final bool, components []discordgo.MessageComponent) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	m := e.messages[messageID]
+	if m == nil {
+		m = &messageEdits{cID: cID}
+		e.messages[messageID] = m
+	}
+	if m.final {
+		return
+	}
+	m.pending, m.hasPending, m.final = components, true, final
+	if !m.inFlight {
+		m.inFlight = true
+		go e.flush(messageID, m)
+	}
+}
+
+// flush sends queued edits until there are none left
+func (e *messageEditor) flush(messageID string, m *messageEdits) {
+	for {
+		e.mu.Lock()
+		if !m.hasPending {
+			m.inFlight = false
+			if m.final {
+				time.AfterFunc(finalEditRetention, func() {
+					e.forget(messageID, m)
+				})
+			}
+			e.mu.Unlock()
+			return
+		}
+		components := m.pending
+		m.pending, m.hasPending = nil, false
+		e.mu.Unlock()
+
+		content, err := json.Marshal(components)
+		if err == nil && string(content) == m.last {
+			continue
+		}
+		if _, err := e.dm.EditChannelMessage(m.cID, messageID, components...); err != nil {
+			log.Error("failed to edit discord channel message", "channelID", m.cID, "messageID", messageID, "err", err)
+			continue
+		}
+		m.last = string(content)
+	}
+}
+
+func (e *messageEditor) forget(messageID string, m *messageEdits) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.messages[messageID] == m {
+		delete(e.messages, messageID)
+	}
+}
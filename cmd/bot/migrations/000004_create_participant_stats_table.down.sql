@@ -0,0 +1,3 @@
+DROP INDEX participant_stats_guild_id_day_idx;
+DROP INDEX participant_stats_user_id_idx;
+DROP TABLE IF EXISTS participant_stats;
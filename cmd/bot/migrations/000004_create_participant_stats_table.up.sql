@@ -0,0 +1,14 @@
+CREATE TABLE participant_stats (
+    guild_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    day INTEGER NOT NULL,
+    focus_duration INTEGER NOT NULL,
+    completed_pomodoros INTEGER NOT NULL,
+    breaks_taken INTEGER NOT NULL,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+    PRIMARY KEY (guild_id, user_id, day)
+);
+
+CREATE INDEX participant_stats_guild_id_day_idx ON participant_stats (guild_id, day);
+CREATE INDEX participant_stats_user_id_idx ON participant_stats (user_id);
@@ -0,0 +1,3 @@
+DROP INDEX participant_streaks_guild_id_idx;
+DROP INDEX participant_streaks_user_id_idx;
+DROP TABLE IF EXISTS participant_streaks;
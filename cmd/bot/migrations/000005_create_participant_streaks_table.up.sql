@@ -0,0 +1,13 @@
+CREATE TABLE participant_streaks (
+    guild_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    current_streak INTEGER NOT NULL,
+    longest_streak INTEGER NOT NULL,
+    last_active_day INTEGER NOT NULL,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+    PRIMARY KEY (guild_id, user_id)
+);
+
+CREATE INDEX participant_streaks_guild_id_idx ON participant_streaks (guild_id, last_active_day);
+CREATE INDEX participant_streaks_user_id_idx ON participant_streaks (user_id);
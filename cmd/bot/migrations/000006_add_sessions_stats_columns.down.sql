@@ -0,0 +1,3 @@
+ALTER TABLE sessions DROP COLUMN completed_pomodoros;
+ALTER TABLE sessions DROP COLUMN skips;
+ALTER TABLE sessions DROP COLUMN long_breaks;
@@ -0,0 +1,3 @@
+ALTER TABLE sessions ADD COLUMN completed_pomodoros INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE sessions ADD COLUMN skips INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE sessions ADD COLUMN long_breaks INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+DROP INDEX session_participant_stats_session_id_idx;
+DROP TABLE IF EXISTS session_participant_stats;
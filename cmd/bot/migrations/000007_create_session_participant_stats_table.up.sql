@@ -0,0 +1,13 @@
+CREATE TABLE session_participant_stats (
+    session_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    focus_duration INTEGER NOT NULL,
+    joined_at INTEGER NOT NULL,
+    left_at INTEGER NOT NULL,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+    PRIMARY KEY (session_id, user_id),
+    FOREIGN KEY(session_id) REFERENCES sessions(id) ON DELETE CASCADE
+);
+
+CREATE INDEX session_participant_stats_session_id_idx ON session_participant_stats (session_id);
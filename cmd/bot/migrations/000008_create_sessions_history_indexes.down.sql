@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS guild_created_at_idx;
+DROP INDEX IF EXISTS status_updated_at_idx;
@@ -0,0 +1,2 @@
+CREATE INDEX guild_created_at_idx ON sessions (guild_id, created_at);
+CREATE INDEX status_updated_at_idx ON sessions (status, updated_at);
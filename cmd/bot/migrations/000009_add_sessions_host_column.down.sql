@@ -0,0 +1 @@
+ALTER TABLE sessions DROP COLUMN host_user_id;
@@ -0,0 +1 @@
+ALTER TABLE sessions ADD COLUMN host_user_id TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,6 @@
+CREATE TABLE guild_settings (
+    guild_id TEXT PRIMARY KEY,
+    moderator_role_id TEXT NOT NULL DEFAULT '',
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+);
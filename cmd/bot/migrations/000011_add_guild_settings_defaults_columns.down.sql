@@ -0,0 +1,10 @@
+ALTER TABLE session_settings DROP COLUMN max_duration;
+ALTER TABLE guild_settings DROP COLUMN allowed_voice_channel_ids;
+ALTER TABLE guild_settings DROP COLUMN allowed_text_channel_ids;
+ALTER TABLE guild_settings DROP COLUMN max_session_duration;
+ALTER TABLE guild_settings DROP COLUMN default_no_deafen;
+ALTER TABLE guild_settings DROP COLUMN default_no_mute;
+ALTER TABLE guild_settings DROP COLUMN default_intervals;
+ALTER TABLE guild_settings DROP COLUMN default_long_break_duration;
+ALTER TABLE guild_settings DROP COLUMN default_short_break_duration;
+ALTER TABLE guild_settings DROP COLUMN default_pomodoro_duration;
@@ -0,0 +1,10 @@
+ALTER TABLE guild_settings ADD COLUMN default_pomodoro_duration INTEGER NOT NULL DEFAULT 1200;
+ALTER TABLE guild_settings ADD COLUMN default_short_break_duration INTEGER NOT NULL DEFAULT 300;
+ALTER TABLE guild_settings ADD COLUMN default_long_break_duration INTEGER NOT NULL DEFAULT 900;
+ALTER TABLE guild_settings ADD COLUMN default_intervals INTEGER NOT NULL DEFAULT 4;
+ALTER TABLE guild_settings ADD COLUMN default_no_mute BOOL NOT NULL DEFAULT 0;
+ALTER TABLE guild_settings ADD COLUMN default_no_deafen BOOL NOT NULL DEFAULT 0;
+ALTER TABLE guild_settings ADD COLUMN max_session_duration INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE guild_settings ADD COLUMN allowed_text_channel_ids TEXT NOT NULL DEFAULT '';
+ALTER TABLE guild_settings ADD COLUMN allowed_voice_channel_ids TEXT NOT NULL DEFAULT '';
+ALTER TABLE session_settings ADD COLUMN max_duration INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,14 @@
+CREATE TABLE session_presets (
+    guild_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    pomodoro_duration INTEGER NOT NULL,
+    short_break_duration INTEGER NOT NULL,
+    long_break_duration INTEGER NOT NULL,
+    intervals INTEGER NOT NULL,
+    no_mute BOOL NOT NULL,
+    no_deafen BOOL NOT NULL,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+    PRIMARY KEY (guild_id, user_id, name)
+);
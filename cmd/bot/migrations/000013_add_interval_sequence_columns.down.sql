@@ -0,0 +1,2 @@
+ALTER TABLE sessions DROP COLUMN sequence_index;
+ALTER TABLE session_settings DROP COLUMN sequence;
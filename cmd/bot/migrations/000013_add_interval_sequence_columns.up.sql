@@ -0,0 +1,2 @@
+ALTER TABLE session_settings ADD COLUMN sequence TEXT NOT NULL DEFAULT '';
+ALTER TABLE sessions ADD COLUMN sequence_index INTEGER NOT NULL DEFAULT 0;
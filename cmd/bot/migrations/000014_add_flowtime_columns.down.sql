@@ -0,0 +1,3 @@
+ALTER TABLE sessions DROP COLUMN break_duration;
+ALTER TABLE session_settings DROP COLUMN break_ratio;
+ALTER TABLE session_settings DROP COLUMN mode;
@@ -0,0 +1,3 @@
+ALTER TABLE session_settings ADD COLUMN mode INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE session_settings ADD COLUMN break_ratio REAL NOT NULL DEFAULT 0.2;
+ALTER TABLE sessions ADD COLUMN break_duration INTEGER NOT NULL DEFAULT 0;
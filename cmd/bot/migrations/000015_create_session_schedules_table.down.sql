@@ -0,0 +1,5 @@
+DROP INDEX session_schedules_next_fire_at_idx;
+DROP INDEX session_schedules_guild_id_idx;
+DROP TABLE session_schedules;
+ALTER TABLE sessions DROP COLUMN schedule_id;
+ALTER TABLE guild_settings DROP COLUMN timezone;
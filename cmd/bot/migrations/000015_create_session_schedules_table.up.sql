@@ -0,0 +1,19 @@
+ALTER TABLE guild_settings ADD COLUMN timezone TEXT NOT NULL DEFAULT '';
+ALTER TABLE sessions ADD COLUMN schedule_id TEXT NOT NULL DEFAULT '';
+CREATE TABLE session_schedules (
+    id TEXT PRIMARY KEY,
+    guild_id TEXT NOT NULL,
+    text_channel_id TEXT NOT NULL,
+    voice_channel_id TEXT NOT NULL,
+    creator_user_id TEXT NOT NULL,
+    weekdays INTEGER NOT NULL,
+    hour INTEGER NOT NULL,
+    minute INTEGER NOT NULL,
+    ping_role_id TEXT NOT NULL DEFAULT '',
+    preset_name TEXT NOT NULL DEFAULT '',
+    next_fire_at INTEGER NOT NULL,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+);
+CREATE INDEX session_schedules_guild_id_idx ON session_schedules (guild_id);
+CREATE INDEX session_schedules_next_fire_at_idx ON session_schedules (next_fire_at);
@@ -0,0 +1,2 @@
+ALTER TABLE session_settings DROP COLUMN goal_until;
+ALTER TABLE session_settings DROP COLUMN goal_rounds;
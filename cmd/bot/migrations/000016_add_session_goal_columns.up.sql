@@ -0,0 +1,2 @@
+ALTER TABLE session_settings ADD COLUMN goal_rounds INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE session_settings ADD COLUMN goal_until INTEGER NOT NULL DEFAULT 0;
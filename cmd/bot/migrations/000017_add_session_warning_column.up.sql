@@ -0,0 +1 @@
+ALTER TABLE session_settings ADD COLUMN warning INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,11 @@
+CREATE TABLE guild_sounds (
+    guild_id TEXT NOT NULL,
+    sound TEXT NOT NULL,
+    uploader_user_id TEXT NOT NULL,
+    file_name TEXT NOT NULL,
+    dca BLOB NOT NULL,
+    duration_ms INTEGER NOT NULL,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+    PRIMARY KEY (guild_id, sound)
+);
@@ -0,0 +1 @@
+ALTER TABLE session_settings DROP COLUMN ambient;
@@ -0,0 +1 @@
+ALTER TABLE session_settings ADD COLUMN ambient BOOL NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+ALTER TABLE guild_settings DROP COLUMN default_volume;
+ALTER TABLE session_settings DROP COLUMN volume;
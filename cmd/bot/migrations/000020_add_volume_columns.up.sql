@@ -0,0 +1,2 @@
+ALTER TABLE session_settings ADD COLUMN volume INTEGER NOT NULL DEFAULT 100;
+ALTER TABLE guild_settings ADD COLUMN default_volume INTEGER NOT NULL DEFAULT 100;
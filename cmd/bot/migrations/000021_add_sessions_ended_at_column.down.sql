@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS status_ended_at_idx;
+CREATE INDEX status_updated_at_idx ON sessions (status, updated_at);
+ALTER TABLE sessions DROP COLUMN ended_at;
@@ -0,0 +1,4 @@
+ALTER TABLE sessions ADD COLUMN ended_at INTEGER NOT NULL DEFAULT 0;
+UPDATE sessions SET ended_at = updated_at WHERE status = 3;
+DROP INDEX IF EXISTS status_updated_at_idx;
+CREATE INDEX status_ended_at_idx ON sessions (status, ended_at);
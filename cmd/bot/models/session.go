@@ -13,17 +13,12 @@ type Participant struct {
 	StartedIntervalAt time.Time
 }
 
-type SessionStats struct {
-	CompletedPomodoros int
-	Skips              int
-}
-
 type Session struct {
-	ID       pomomo.SessionID
-	Settings pomomo.SessionSettingsRecord
-	Stats    SessionStats // TODO maybe extract stats, handle updates in an update hook
-	Record   pomomo.SessionRecord
-	Greeting string
+	ID        pomomo.SessionID
+	Settings  pomomo.SessionSettingsRecord
+	Record    pomomo.SessionRecord
+	Greeting  string
+	CreatedAt time.Time
 }
 
 func SessionFromExistingRecords(record pomomo.ExistingSessionRecord, settings pomomo.ExistingSessionSettingsRecord) Session {
@@ -31,9 +26,10 @@ func SessionFromExistingRecords(record pomomo.ExistingSessionRecord, settings po
 		panic("missing required IDs")
 	}
 	return Session{
-		ID:       record.ID,
-		Record:   record.SessionRecord,
-		Settings: settings.SessionSettingsRecord,
+		ID:        record.ID,
+		Record:    record.SessionRecord,
+		Settings:  settings.SessionSettingsRecord,
+		CreatedAt: record.CreatedAt,
 	}
 }
 
@@ -57,10 +53,88 @@ func NewSession(sessionID, guildID, textCID, voiceCID, messageID string, setting
 }
 
 func (s Session) TimeRemaining() time.Duration {
+	if s.Record.Status == pomomo.SessionPaused {
+		// time remaining is frozen while paused
+		return s.Record.TimeRemainingAtStart
+	}
 	return s.Record.TimeRemainingAtStart - time.Since(s.Record.IntervalStartedAt)
 }
 
+// Expired reports whether the session has run past its max duration
+func (s Session) Expired() bool {
+	return s.Settings.MaxDuration > 0 && !s.CreatedAt.IsZero() && time.Since(s.CreatedAt) >= s.Settings.MaxDuration
+}
+
+// HasGoal reports whether the session ends itself once a goal is reached
+func (s Session) HasGoal() bool {
+	return s.Settings.GoalRounds > 0 || !s.Settings.GoalUntil.IsZero()
+}
+
+// GoalReached reports whether the session has completed its target pomodoros or run until its end time
+func (s Session) GoalReached() bool {
+	if s.Settings.GoalRounds > 0 && s.Record.Stats.CompletedPomodoros >= s.Settings.GoalRounds {
+		return true
+	}
+	return !s.Settings.GoalUntil.IsZero() && !time.Now().Before(s.Settings.GoalUntil)
+}
+
+// NextTransitionAt returns when the session next changes on its own - its lobby or current interval ends,
+// it expires or it reaches its end time - or the zero time if it won't
+func (s Session) NextTransitionAt() time.Time {
+	var next time.Time
+	earliest := func(t time.Time) {
+		if !t.IsZero() && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+	if s.Record.Status == pomomo.SessionLobby || s.Record.Status == pomomo.SessionRunning && !s.OpenEnded() {
+		earliest(s.Record.IntervalStartedAt.Add(s.Record.TimeRemainingAtStart))
+	}
+	if s.Settings.MaxDuration > 0 && !s.CreatedAt.IsZero() {
+		earliest(s.CreatedAt.Add(s.Settings.MaxDuration))
+	}
+	earliest(s.Settings.GoalUntil)
+	return next
+}
+
+// WarningAt returns when participants should be warned that the current interval is ending,
+// or the zero time if there's nothing to warn about
+func (s Session) WarningAt() time.Time {
+	if s.Settings.Warning <= 0 || s.Record.Status != pomomo.SessionRunning || s.OpenEnded() {
+		return time.Time{}
+	}
+	if s.CurrentDuration() <= s.Settings.Warning {
+		// the warning would come before the interval starts
+		return time.Time{}
+	}
+	return s.Record.IntervalStartedAt.Add(s.Record.TimeRemainingAtStart - s.Settings.Warning)
+}
+
+// OpenEnded reports whether the current interval counts up until the user takes a break
+func (s Session) OpenEnded() bool {
+	return s.Settings.Mode == pomomo.FlowtimeMode && s.Record.CurrentInterval == pomomo.PomodoroInterval
+}
+
+// Elapsed returns how long the current interval has run, excluding pauses
+func (s Session) Elapsed() time.Duration {
+	if s.OpenEnded() {
+		// open-ended intervals start with no time remaining and go negative from there
+		return -s.TimeRemaining()
+	}
+	return s.CurrentDuration() - s.TimeRemaining()
+}
+
+// CurrentDuration returns the length of the current interval, which is zero for open-ended intervals
 func (s Session) CurrentDuration() time.Duration {
+	if s.Settings.Mode == pomomo.FlowtimeMode {
+		if s.Record.CurrentInterval == pomomo.PomodoroInterval {
+			return 0
+		}
+		return s.Record.BreakDuration
+	}
+	if len(s.Settings.Sequence) > 0 {
+		return s.Settings.Sequence[s.Record.SequenceIndex%len(s.Settings.Sequence)].Duration
+	}
 	switch s.Record.CurrentInterval {
 	case pomomo.PomodoroInterval:
 		return s.Settings.Pomodoro
@@ -74,19 +148,38 @@ func (s Session) CurrentDuration() time.Duration {
 }
 
 func (s *Session) GoNextInterval(shouldUpdateStats bool) {
+	openEnded := s.OpenEnded()
 	// TODO could be external
 	if shouldUpdateStats {
 		if s.Record.CurrentInterval == pomomo.PomodoroInterval {
-			s.Stats.CompletedPomodoros++
+			s.Record.Stats.CompletedPomodoros++
 		}
 	}
 
 	// update interval
 	var next pomomo.SessionInterval
-	if s.Record.CurrentInterval == pomomo.PomodoroInterval {
+	if s.Settings.Mode == pomomo.FlowtimeMode {
+		if openEnded {
+			focus := float64(max(s.Elapsed(), 0))
+			s.Record.BreakDuration = time.Duration(focus * s.Settings.BreakRatio).Round(time.Second)
+			next = pomomo.ShortBreakInterval
+		} else {
+			next = pomomo.PomodoroInterval
+		}
+	} else if seq := s.Settings.Sequence; len(seq) > 0 {
+		if s.Record.CurrentInterval != 0 {
+			// loop back around once the sequence is done
+			s.Record.SequenceIndex = (s.Record.SequenceIndex + 1) % len(seq)
+		}
+		next = seq[s.Record.SequenceIndex].Interval
+		if next == pomomo.LongBreakInterval {
+			s.Record.Stats.LongBreaks++
+		}
+	} else if s.Record.CurrentInterval == pomomo.PomodoroInterval {
 		// After pomodoro, decide break type based on completed pomodoros
-		if s.Stats.CompletedPomodoros > 0 && s.Stats.CompletedPomodoros%s.Settings.Intervals == 0 {
+		if s.Record.Stats.CompletedPomodoros > 0 && s.Record.Stats.CompletedPomodoros%s.Settings.Intervals == 0 {
 			next = pomomo.LongBreakInterval
+			s.Record.Stats.LongBreaks++
 		} else {
 			next = pomomo.ShortBreakInterval
 		}
@@ -97,12 +190,103 @@ func (s *Session) GoNextInterval(shouldUpdateStats bool) {
 	s.Record.CurrentInterval = next
 
 	// update time/duration
-	if s.Record.Status == pomomo.SessionRunning && !s.Record.IntervalStartedAt.IsZero() {
+	if s.Record.Status == pomomo.SessionRunning && !s.Record.IntervalStartedAt.IsZero() && !openEnded {
 		// may need multiple calls to catch up
 		s.Record.IntervalStartedAt = s.Record.IntervalStartedAt.Add(s.Record.TimeRemainingAtStart)
 	} else {
-		// Session is paused or hasn't started; start now
+		// Session is paused, hasn't started, or is leaving open-ended focus; start now
 		s.Record.IntervalStartedAt = time.Now()
 	}
 	s.Record.TimeRemainingAtStart = s.CurrentDuration()
 }
+
+// TakeBreak ends open-ended focus with a break proportional to it
+func (s *Session) TakeBreak() {
+	if !s.OpenEnded() {
+		return
+	}
+	s.GoNextInterval(true)
+}
+
+// UpdateSettings applies new settings mid-interval. The current interval's time remaining is either
+// rescaled proportionally to its new duration or kept as is, capped at the new duration.
+func (s *Session) UpdateSettings(settings pomomo.SessionSettingsRecord, rescale bool) {
+	settings.SessionID = s.Settings.SessionID
+	settings.Mode = s.Settings.Mode // switching modes mid-session isn't supported
+	if settings.Mode == pomomo.FlowtimeMode || s.Record.Status == pomomo.SessionLobby {
+		// flowtime intervals aren't derived from settings and the lobby countdown doesn't depend on them
+		s.Settings = settings
+		return
+	}
+	remaining := max(s.TimeRemaining(), 0)
+	oldDuration := s.CurrentDuration()
+	warmingUp := s.Record.CurrentInterval == pomomo.WarmUpInterval
+	s.Settings = settings
+	if seq := settings.Sequence; len(seq) > 0 {
+		s.Record.SequenceIndex = min(s.Record.SequenceIndex, len(seq)-1)
+		s.Record.CurrentInterval = seq[s.Record.SequenceIndex].Interval
+	} else if s.Record.CurrentInterval == pomomo.WarmUpInterval {
+		// classic cycle has no warm-up
+		s.Record.SequenceIndex = 0
+		s.Record.CurrentInterval = pomomo.ShortBreakInterval
+	}
+	newDuration := s.CurrentDuration()
+	// a warm-up only rescales to another warm-up - one that became a break keeps its time remaining as is
+	if rescale && oldDuration > 0 && (!warmingUp || s.Record.CurrentInterval == pomomo.WarmUpInterval) {
+		remaining = time.Duration(float64(remaining) * float64(newDuration) / float64(oldDuration))
+	}
+	remaining = min(remaining, newDuration)
+
+	if s.Record.Status == pomomo.SessionPaused {
+		s.Record.TimeRemainingAtStart = remaining
+		return
+	}
+	// interval start is kept so that focus time already spent in the interval is still credited
+	s.Record.TimeRemainingAtStart = remaining + time.Since(s.Record.IntervalStartedAt)
+}
+
+// OpenLobby counts down d before the first interval starts
+func (s *Session) OpenLobby(d time.Duration) {
+	s.Record.Status = pomomo.SessionLobby
+	s.Record.CurrentInterval = 0
+	s.Record.IntervalStartedAt = time.Now()
+	s.Record.TimeRemainingAtStart = d
+}
+
+// StartFromLobby starts the first interval, backdated to when the countdown expired if it already has
+func (s *Session) StartFromLobby() {
+	if s.Record.Status != pomomo.SessionLobby {
+		return
+	}
+	start := time.Now()
+	if end := s.Record.IntervalStartedAt.Add(s.Record.TimeRemainingAtStart); end.Before(start) {
+		start = end
+	}
+	s.Record.Status = pomomo.SessionRunning
+	s.GoNextInterval(false)
+	s.Record.IntervalStartedAt = start
+}
+
+// Pause freezes the time remaining in the current interval
+func (s *Session) Pause() {
+	if s.Record.Status != pomomo.SessionRunning {
+		return
+	}
+	if s.OpenEnded() {
+		// keep the negative time remaining so that elapsed focus carries over
+		s.Record.TimeRemainingAtStart = s.TimeRemaining()
+	} else {
+		s.Record.TimeRemainingAtStart = max(s.TimeRemaining(), 0)
+	}
+	s.Record.IntervalStartedAt = time.Now()
+	s.Record.Status = pomomo.SessionPaused
+}
+
+// Resume picks up the current interval from the frozen time remaining
+func (s *Session) Resume() {
+	if s.Record.Status != pomomo.SessionPaused {
+		return
+	}
+	s.Record.IntervalStartedAt = time.Now()
+	s.Record.Status = pomomo.SessionRunning
+}
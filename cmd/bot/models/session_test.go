@@ -0,0 +1,370 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/benjamonnguyen/pomomo-go"
+)
+
+func testSession(settings pomomo.SessionSettingsRecord) Session {
+	return NewSession("s1", "g1", "t1", "v1", "m1", settings)
+}
+
+func TestGoNextInterval(t *testing.T) {
+	classic := func(intervals int) pomomo.SessionSettingsRecord {
+		return pomomo.SessionSettingsRecord{
+			Pomodoro:   25 * time.Minute,
+			ShortBreak: 5 * time.Minute,
+			LongBreak:  15 * time.Minute,
+			Intervals:  intervals,
+		}
+	}
+	var (
+		p  = pomomo.IntervalStep{Interval: pomomo.PomodoroInterval, Duration: 25 * time.Minute}
+		sb = pomomo.IntervalStep{Interval: pomomo.ShortBreakInterval, Duration: 5 * time.Minute}
+		lb = pomomo.IntervalStep{Interval: pomomo.LongBreakInterval, Duration: 15 * time.Minute}
+	)
+	sequence := pomomo.IntervalSequence{
+		{Interval: pomomo.WarmUpInterval, Duration: 10 * time.Minute},
+		{Interval: pomomo.PomodoroInterval, Duration: 50 * time.Minute},
+		{Interval: pomomo.ShortBreakInterval, Duration: 10 * time.Minute},
+		{Interval: pomomo.PomodoroInterval, Duration: 50 * time.Minute},
+		{Interval: pomomo.LongBreakInterval, Duration: 30 * time.Minute},
+	}
+	tests := []struct {
+		name        string
+		settings    pomomo.SessionSettingsRecord
+		updateStats bool
+		want        []pomomo.IntervalStep
+		wantStats   pomomo.SessionStats
+	}{
+		{"classic", classic(4), true, []pomomo.IntervalStep{p, sb, p, sb, p, sb, p, lb, p, sb}, pomomo.SessionStats{CompletedPomodoros: 5, LongBreaks: 1}},
+		{"two intervals", classic(2), true, []pomomo.IntervalStep{p, sb, p, lb, p, sb, p, lb}, pomomo.SessionStats{CompletedPomodoros: 4, LongBreaks: 2}},
+		{"one interval", classic(1), true, []pomomo.IntervalStep{p, lb, p, lb}, pomomo.SessionStats{CompletedPomodoros: 2, LongBreaks: 2}},
+		// skipped pomodoros don't count towards the long break
+		{"skipped", classic(2), false, []pomomo.IntervalStep{p, sb, p, sb, p, sb}, pomomo.SessionStats{}},
+		{"sequence", pomomo.SessionSettingsRecord{Sequence: sequence}, true,
+			append(append(pomomo.IntervalSequence{}, sequence...), sequence[:2]...), pomomo.SessionStats{CompletedPomodoros: 2, LongBreaks: 1}},
+		{"sequence skipped", pomomo.SessionSettingsRecord{Sequence: sequence}, false,
+			append(append(pomomo.IntervalSequence{}, sequence...), sequence...), pomomo.SessionStats{LongBreaks: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := testSession(tt.settings)
+			var start time.Time
+			var elapsed time.Duration
+			for i, want := range tt.want {
+				s.GoNextInterval(tt.updateStats)
+				if i == 0 {
+					start = s.Record.IntervalStartedAt
+				}
+				if s.Record.CurrentInterval != want.Interval {
+					t.Fatalf("interval %d = %v, want %v", i, s.Record.CurrentInterval, want.Interval)
+				}
+				if s.Record.TimeRemainingAtStart != want.Duration {
+					t.Errorf("interval %d duration = %v, want %v", i, s.Record.TimeRemainingAtStart, want.Duration)
+				}
+				// running intervals start when the one before was due to end so that sessions catch up
+				if !s.Record.IntervalStartedAt.Equal(start.Add(elapsed)) {
+					t.Errorf("interval %d started %v after the first, want %v", i, s.Record.IntervalStartedAt.Sub(start), elapsed)
+				}
+				elapsed += want.Duration
+			}
+			if s.Record.Stats != tt.wantStats {
+				t.Errorf("stats = %+v, want %+v", s.Record.Stats, tt.wantStats)
+			}
+		})
+	}
+}
+
+func TestGoNextIntervalFlowtime(t *testing.T) {
+	tests := []struct {
+		name  string
+		focus time.Duration
+		ratio float64
+		want  time.Duration
+	}{
+		{"default ratio", 25 * time.Minute, pomomo.DefaultBreakRatio, 5 * time.Minute},
+		{"half", 50 * time.Minute, 0.5, 25 * time.Minute},
+		{"rounded down", 7*time.Minute + time.Second, 0.2, time.Minute + 24*time.Second},
+		{"rounded up", 7*time.Minute + 3*time.Second, 0.2, time.Minute + 25*time.Second},
+		{"sub-second focus", 7*time.Minute + 2400*time.Millisecond, 0.2, time.Minute + 24*time.Second},
+		{"no focus", 0, 0.2, 0},
+		{"no ratio", 30 * time.Minute, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := testSession(pomomo.SessionSettingsRecord{Mode: pomomo.FlowtimeMode, BreakRatio: tt.ratio})
+			s.GoNextInterval(false)
+			if !s.OpenEnded() || s.Record.TimeRemainingAtStart != 0 {
+				t.Fatalf("first interval isn't open-ended focus: %v, %v", s.Record.CurrentInterval, s.Record.TimeRemainingAtStart)
+			}
+
+			// paused so that the focus time is exact
+			s.Record.Status = pomomo.SessionPaused
+			s.Record.TimeRemainingAtStart = -tt.focus
+			s.GoNextInterval(true)
+			if s.Record.CurrentInterval != pomomo.ShortBreakInterval {
+				t.Errorf("interval after focus = %v, want a short break", s.Record.CurrentInterval)
+			}
+			if s.Record.BreakDuration != tt.want || s.Record.TimeRemainingAtStart != tt.want {
+				t.Errorf("break = %v with %v remaining, want %v", s.Record.BreakDuration, s.Record.TimeRemainingAtStart, tt.want)
+			}
+
+			s.GoNextInterval(true)
+			if !s.OpenEnded() || s.Record.TimeRemainingAtStart != 0 {
+				t.Errorf("interval after the break isn't open-ended focus: %v, %v", s.Record.CurrentInterval, s.Record.TimeRemainingAtStart)
+			}
+			if want := (pomomo.SessionStats{CompletedPomodoros: 1}); s.Record.Stats != want {
+				t.Errorf("stats = %+v, want %+v", s.Record.Stats, want)
+			}
+		})
+	}
+
+	t.Run("running", func(t *testing.T) {
+		s := testSession(pomomo.SessionSettingsRecord{Mode: pomomo.FlowtimeMode, BreakRatio: 0.2})
+		s.GoNextInterval(false)
+		s.Record.IntervalStartedAt = time.Now().Add(-10 * time.Minute)
+		s.GoNextInterval(true)
+		if s.Record.BreakDuration != 2*time.Minute {
+			t.Errorf("break = %v, want 2m", s.Record.BreakDuration)
+		}
+		// the break starts when focus ends rather than when it began
+		if since := time.Since(s.Record.IntervalStartedAt); since < 0 || since > time.Second {
+			t.Errorf("break started %v ago, want now", since)
+		}
+	})
+}
+
+func TestUpdateSettings(t *testing.T) {
+	settings := pomomo.SessionSettingsRecord{
+		Pomodoro:   25 * time.Minute,
+		ShortBreak: 5 * time.Minute,
+		LongBreak:  15 * time.Minute,
+		Intervals:  4,
+	}
+	pomodoro := func(d time.Duration) pomomo.SessionSettingsRecord {
+		s := settings
+		s.Pomodoro = d
+		return s
+	}
+	tests := []struct {
+		name     string
+		elapsed  time.Duration
+		paused   bool
+		settings pomomo.SessionSettingsRecord
+		rescale  bool
+		want     time.Duration
+	}{
+		{"lengthened", 10 * time.Minute, false, pomodoro(30 * time.Minute), false, 15 * time.Minute},
+		{"lengthened rescaled", 10 * time.Minute, false, pomodoro(50 * time.Minute), true, 30 * time.Minute},
+		{"shortened", 5 * time.Minute, false, pomodoro(10 * time.Minute), false, 10 * time.Minute},
+		{"shortened rescaled", 5 * time.Minute, false, pomodoro(10 * time.Minute), true, 8 * time.Minute},
+		// the time already spent isn't taken off the new duration
+		{"shortened below elapsed", 20 * time.Minute, false, pomodoro(15 * time.Minute), false, 5 * time.Minute},
+		{"shortened below elapsed rescaled", 20 * time.Minute, false, pomodoro(15 * time.Minute), true, 3 * time.Minute},
+		{"shortened below remaining", 20 * time.Minute, false, pomodoro(2 * time.Minute), false, 2 * time.Minute},
+		{"overdue", 26 * time.Minute, false, pomodoro(50 * time.Minute), true, 0},
+		{"paused", 10 * time.Minute, true, pomodoro(30 * time.Minute), false, 15 * time.Minute},
+		{"paused rescaled", 10 * time.Minute, true, pomodoro(50 * time.Minute), true, 30 * time.Minute},
+		{"paused shortened below elapsed", 20 * time.Minute, true, pomodoro(15 * time.Minute), false, 5 * time.Minute},
+		{"paused shortened below elapsed rescaled", 20 * time.Minute, true, pomodoro(15 * time.Minute), true, 3 * time.Minute},
+		{"paused shortened below remaining", 5 * time.Minute, true, pomodoro(10 * time.Minute), false, 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := testSession(settings)
+			s.GoNextInterval(false)
+			if tt.paused {
+				s.Record.Status = pomomo.SessionPaused
+				s.Record.TimeRemainingAtStart = settings.Pomodoro - tt.elapsed
+			} else {
+				s.Record.IntervalStartedAt = time.Now().Add(-tt.elapsed)
+			}
+			startedAt := s.Record.IntervalStartedAt
+
+			s.UpdateSettings(tt.settings, tt.rescale)
+			if s.Settings.Pomodoro != tt.settings.Pomodoro {
+				t.Errorf("Pomodoro = %v, want %v", s.Settings.Pomodoro, tt.settings.Pomodoro)
+			}
+			if !s.Record.IntervalStartedAt.Equal(startedAt) {
+				t.Error("interval start changed")
+			}
+			if tt.paused {
+				if got := s.TimeRemaining(); got != tt.want {
+					t.Errorf("TimeRemaining() = %v, want %v", got, tt.want)
+				}
+				if s.Record.Status != pomomo.SessionPaused {
+					t.Errorf("status = %v, want paused", s.Record.Status)
+				}
+				// the edited time remaining carries over once resumed
+				s.Resume()
+			}
+			if got := s.TimeRemaining(); got > tt.want || got < tt.want-100*time.Millisecond {
+				t.Errorf("TimeRemaining() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("break", func(t *testing.T) {
+		s := testSession(settings)
+		s.GoNextInterval(true)
+		s.GoNextInterval(true)
+		s.Record.Status = pomomo.SessionPaused
+		s.Record.TimeRemainingAtStart = 4 * time.Minute
+		edited := settings
+		edited.ShortBreak = 10 * time.Minute
+		s.UpdateSettings(edited, true)
+		if got := s.TimeRemaining(); got != 8*time.Minute {
+			t.Errorf("TimeRemaining() = %v, want 8m", got)
+		}
+	})
+
+	t.Run("shortened sequence", func(t *testing.T) {
+		s := testSession(pomomo.SessionSettingsRecord{Sequence: pomomo.IntervalSequence{
+			{Interval: pomomo.PomodoroInterval, Duration: 25 * time.Minute},
+			{Interval: pomomo.ShortBreakInterval, Duration: 5 * time.Minute},
+			{Interval: pomomo.PomodoroInterval, Duration: 25 * time.Minute},
+			{Interval: pomomo.LongBreakInterval, Duration: 20 * time.Minute},
+		}})
+		for range 4 {
+			s.GoNextInterval(true)
+		}
+		s.Record.Status = pomomo.SessionPaused
+		s.Record.TimeRemainingAtStart = 10 * time.Minute
+		s.UpdateSettings(pomomo.SessionSettingsRecord{Sequence: pomomo.IntervalSequence{
+			{Interval: pomomo.PomodoroInterval, Duration: 50 * time.Minute},
+			{Interval: pomomo.ShortBreakInterval, Duration: 10 * time.Minute},
+		}}, true)
+		// the long break is gone so the session carries on from the last step
+		if s.Record.SequenceIndex != 1 || s.Record.CurrentInterval != pomomo.ShortBreakInterval {
+			t.Errorf("step %d %v, want step 1 short break", s.Record.SequenceIndex, s.Record.CurrentInterval)
+		}
+		if got := s.TimeRemaining(); got != 5*time.Minute {
+			t.Errorf("TimeRemaining() = %v, want 5m", got)
+		}
+	})
+
+	t.Run("warm-up", func(t *testing.T) {
+		warmUp := func(d time.Duration) pomomo.SessionSettingsRecord {
+			edited := settings
+			edited.Sequence = pomomo.IntervalSequence{
+				{Interval: pomomo.WarmUpInterval, Duration: d},
+				{Interval: pomomo.PomodoroInterval, Duration: 50 * time.Minute},
+				{Interval: pomomo.ShortBreakInterval, Duration: 10 * time.Minute},
+			}
+			return edited
+		}
+		tests := []struct {
+			name         string
+			settings     pomomo.SessionSettingsRecord
+			rescale      bool
+			wantInterval pomomo.SessionInterval
+			want         time.Duration
+		}{
+			{"lengthened", warmUp(20 * time.Minute), false, pomomo.WarmUpInterval, 4 * time.Minute},
+			// rescaled by the warm-up step rather than the short break
+			{"lengthened rescaled", warmUp(20 * time.Minute), true, pomomo.WarmUpInterval, 8 * time.Minute},
+			{"shortened rescaled", warmUp(5 * time.Minute), true, pomomo.WarmUpInterval, 2 * time.Minute},
+			// the classic cycle has no warm-up so it becomes a short break without rescaling
+			{"sequence removed", settings, false, pomomo.ShortBreakInterval, 4 * time.Minute},
+			{"sequence removed rescaled", settings, true, pomomo.ShortBreakInterval, 4 * time.Minute},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				s := testSession(warmUp(10 * time.Minute))
+				s.GoNextInterval(false)
+				s.Record.Status = pomomo.SessionPaused
+				s.Record.TimeRemainingAtStart = 4 * time.Minute
+				s.UpdateSettings(tt.settings, tt.rescale)
+				if s.Record.CurrentInterval != tt.wantInterval {
+					t.Errorf("interval = %v, want %v", s.Record.CurrentInterval, tt.wantInterval)
+				}
+				if got := s.TimeRemaining(); got != tt.want {
+					t.Errorf("TimeRemaining() = %v, want %v", got, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("flowtime", func(t *testing.T) {
+		s := testSession(pomomo.SessionSettingsRecord{Mode: pomomo.FlowtimeMode, BreakRatio: 0.2})
+		s.GoNextInterval(false)
+		s.Record.Status = pomomo.SessionPaused
+		s.Record.TimeRemainingAtStart = -10 * time.Minute
+		s.UpdateSettings(pomomo.SessionSettingsRecord{Mode: pomomo.ClassicMode, BreakRatio: 0.5}, true)
+		if s.Settings.Mode != pomomo.FlowtimeMode || s.Settings.BreakRatio != 0.5 {
+			t.Errorf("settings = %v %v, want flowtime with the new ratio", s.Settings.Mode, s.Settings.BreakRatio)
+		}
+		if got := s.TimeRemaining(); got != -10*time.Minute {
+			t.Errorf("TimeRemaining() = %v, want focus kept at -10m", got)
+		}
+	})
+}
+
+func TestPauseResume(t *testing.T) {
+	classic := pomomo.SessionSettingsRecord{
+		Pomodoro:   25 * time.Minute,
+		ShortBreak: 5 * time.Minute,
+		LongBreak:  15 * time.Minute,
+		Intervals:  4,
+	}
+	flowtime := pomomo.SessionSettingsRecord{Mode: pomomo.FlowtimeMode, BreakRatio: 0.2}
+	var (
+		pause  = (*Session).Pause
+		resume = (*Session).Resume
+		// wait passes 5 minutes in the interval
+		wait = func(s *Session) { s.Record.IntervalStartedAt = s.Record.IntervalStartedAt.Add(-5 * time.Minute) }
+	)
+	tests := []struct {
+		name     string
+		settings pomomo.SessionSettingsRecord
+		paused   bool
+		// elapsed is the time spent in the interval before ops
+		elapsed       time.Duration
+		ops           []func(*Session)
+		wantStatus    pomomo.SessionStatus
+		wantRemaining time.Duration
+		// wantStartedAgo is how long before now IntervalStartedAt should be
+		wantStartedAgo time.Duration
+	}{
+		{"pause", classic, false, 10 * time.Minute, []func(*Session){pause}, pomomo.SessionPaused, 15 * time.Minute, 0},
+		{"pause overdue", classic, false, 30 * time.Minute, []func(*Session){pause}, pomomo.SessionPaused, 0, 0},
+		// elapsed focus carries over as negative time remaining
+		{"pause flowtime", flowtime, false, 10 * time.Minute, []func(*Session){pause}, pomomo.SessionPaused, -10 * time.Minute, 0},
+		{"pause twice", classic, false, 10 * time.Minute, []func(*Session){pause, wait, pause}, pomomo.SessionPaused, 15 * time.Minute, 5 * time.Minute},
+		{"pause then wait", classic, false, 10 * time.Minute, []func(*Session){pause, wait}, pomomo.SessionPaused, 15 * time.Minute, 5 * time.Minute},
+		{"resume", classic, true, 10 * time.Minute, []func(*Session){resume}, pomomo.SessionRunning, 15 * time.Minute, 0},
+		{"resume flowtime", flowtime, true, 10 * time.Minute, []func(*Session){resume}, pomomo.SessionRunning, -10 * time.Minute, 0},
+		{"resume running", classic, false, 10 * time.Minute, []func(*Session){resume}, pomomo.SessionRunning, 15 * time.Minute, 10 * time.Minute},
+		{"resume twice", classic, true, 10 * time.Minute, []func(*Session){resume, wait, resume}, pomomo.SessionRunning, 10 * time.Minute, 5 * time.Minute},
+		{"pause then resume", classic, false, 10 * time.Minute, []func(*Session){pause, wait, resume}, pomomo.SessionRunning, 15 * time.Minute, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := testSession(tt.settings)
+			s.GoNextInterval(false)
+			if tt.paused {
+				s.Record.Status = pomomo.SessionPaused
+				s.Record.TimeRemainingAtStart -= tt.elapsed
+				s.Record.IntervalStartedAt = time.Now()
+			} else {
+				s.Record.IntervalStartedAt = time.Now().Add(-tt.elapsed)
+			}
+
+			for _, op := range tt.ops {
+				op(&s)
+			}
+			if s.Record.Status != tt.wantStatus {
+				t.Errorf("status = %v, want %v", s.Record.Status, tt.wantStatus)
+			}
+			if got := s.TimeRemaining(); got > tt.wantRemaining || got < tt.wantRemaining-100*time.Millisecond {
+				t.Errorf("TimeRemaining() = %v, want %v", got, tt.wantRemaining)
+			}
+			if ago := time.Since(s.Record.IntervalStartedAt); ago < tt.wantStartedAgo || ago > tt.wantStartedAgo+time.Second {
+				t.Errorf("interval started %v ago, want %v", ago, tt.wantStartedAgo)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"slices"
+
+	"github.com/benjamonnguyen/pomomo-go/cmd/bot/models"
+	"github.com/bwmarrin/discordgo"
+	"github.com/charmbracelet/log"
+)
+
+// SessionAuthorizer decides who can control a session
+type SessionAuthorizer interface {
+	// CanManage reports whether member can end, skip, pause, or edit the session. That is the host,
+	// guild moderators, and - once the host has left or if there never was one, e.g. for scheduled
+	// sessions - any current participant.
+	CanManage(ctx context.Context, s models.Session, member *discordgo.Member) bool
+}
+
+var _ SessionAuthorizer = (*sessionAuthorizer)(nil)
+
+type sessionAuthorizer struct {
+	repo GuildSettingsRepo
+	pm   ParticipantsManager
+}
+
+func NewSessionAuthorizer(repo GuildSettingsRepo, pm ParticipantsManager) SessionAuthorizer {
+	return &sessionAuthorizer{
+		repo: repo,
+		pm:   pm,
+	}
+}
+
+func (a *sessionAuthorizer) CanManage(ctx context.Context, s models.Session, member *discordgo.Member) bool {
+	if member == nil || member.User == nil {
+		return false
+	}
+	if s.Record.HostUserID != "" && member.User.ID == s.Record.HostUserID {
+		return true
+	}
+	if member.Permissions&(discordgo.PermissionAdministrator|discordgo.PermissionManageGuild) != 0 {
+		return true
+	}
+
+	settings, err := getGuildSettings(ctx, a.repo, s.Record.GuildID)
+	if err != nil {
+		log.Error("failed to get guild settings", "gid", s.Record.GuildID, "err", err)
+	}
+	if settings.ModeratorRoleID != "" && slices.Contains(member.Roles, settings.ModeratorRoleID) {
+		return true
+	}
+
+	unlock := a.pm.AcquireVoiceChannelLock(s.Record.VoiceCID)
+	defer unlock()
+	hostLeft := s.Record.HostUserID == "" || a.pm.Get(s.Record.HostUserID, s.Record.VoiceCID) == (models.Participant{})
+	return hostLeft && a.pm.Get(member.User.ID, s.Record.VoiceCID) != (models.Participant{})
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/benjamonnguyen/pomomo-go"
+	"github.com/benjamonnguyen/pomomo-go/cmd/bot/models"
+	"github.com/benjamonnguyen/pomomo-go/sqlite"
+	"github.com/bwmarrin/discordgo"
+	"github.com/charmbracelet/log"
+)
+
+// fakeParticipantsRepo keeps participants in memory
+type fakeParticipantsRepo struct {
+	mu           sync.Mutex
+	participants map[pomomo.ParticipantID]pomomo.ExistingParticipantRecord
+}
+
+func newFakeParticipantsRepo() *fakeParticipantsRepo {
+	return &fakeParticipantsRepo{participants: make(map[pomomo.ParticipantID]pomomo.ExistingParticipantRecord)}
+}
+
+func (r *fakeParticipantsRepo) InsertParticipant(_ context.Context, p pomomo.ParticipantRecord) (pomomo.ExistingParticipantRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id := pomomo.ParticipantID(fmt.Sprintf("p%d", len(r.participants)+1))
+	existing := pomomo.ExistingParticipantRecord{
+		ExistingRecord:    pomomo.ExistingRecord[pomomo.ParticipantID]{ID: id, CreatedAt: time.Now()},
+		ParticipantRecord: p,
+	}
+	r.participants[id] = existing
+	return existing, nil
+}
+
+func (r *fakeParticipantsRepo) UpdateParticipant(_ context.Context, id pomomo.ParticipantID, p pomomo.ParticipantRecord) (pomomo.ExistingParticipantRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	existing, ok := r.participants[id]
+	if !ok {
+		return pomomo.ExistingParticipantRecord{}, sqlite.ErrNotFound
+	}
+	existing.ParticipantRecord = p
+	r.participants[id] = existing
+	return existing, nil
+}
+
+func (r *fakeParticipantsRepo) DeleteParticipant(_ context.Context, id pomomo.ParticipantID) (pomomo.ExistingParticipantRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	existing, ok := r.participants[id]
+	if !ok {
+		return pomomo.ExistingParticipantRecord{}, sqlite.ErrNotFound
+	}
+	delete(r.participants, id)
+	return existing, nil
+}
+
+func (r *fakeParticipantsRepo) GetAllParticipants(context.Context) ([]pomomo.ExistingParticipantRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var all []pomomo.ExistingParticipantRecord
+	for _, p := range r.participants {
+		all = append(all, p)
+	}
+	return all, nil
+}
+
+func (r *fakeParticipantsRepo) GetParticipantByUserID(_ context.Context, userID string) (pomomo.ExistingParticipantRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, p := range r.participants {
+		if p.UserID == userID {
+			return p, nil
+		}
+	}
+	return pomomo.ExistingParticipantRecord{}, sqlite.ErrNotFound
+}
+
+func TestSessionAuthorizerCanManage(t *testing.T) {
+	ctx := context.Background()
+	settings := pomomo.NewGuildSettingsRecord("g1")
+	settings.ModeratorRoleID = "mods"
+	guildRepo := &fakeGuildSettingsRepo{settings: map[string]pomomo.GuildSettingsRecord{"g1": settings}}
+	pm := NewParticipantManager(newFakeParticipantsRepo(), *log.Default())
+	for _, uid := range []string{"host", "participant"} {
+		if _, err := pm.Insert(ctx, pomomo.ParticipantRecord{GuildID: "g1", UserID: uid, VoiceCID: "v1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := pm.Insert(ctx, pomomo.ParticipantRecord{GuildID: "g1", UserID: "participant", VoiceCID: "v2"}); err != nil {
+		t.Fatal(err)
+	}
+	auth := NewSessionAuthorizer(guildRepo, pm)
+
+	session := func(voiceCID, hostUserID string) models.Session {
+		s := models.NewSession("s1", "g1", "t1", voiceCID, "m1", pomomo.SessionSettingsRecord{})
+		s.Record.HostUserID = hostUserID
+		return s
+	}
+	member := func(uid string, roles ...string) *discordgo.Member {
+		return &discordgo.Member{User: &discordgo.User{ID: uid}, Roles: roles}
+	}
+	admin := member("admin")
+	admin.Permissions = discordgo.PermissionAdministrator
+	manager := member("manager")
+	manager.Permissions = discordgo.PermissionManageGuild
+
+	tests := []struct {
+		name    string
+		session models.Session
+		member  *discordgo.Member
+		want    bool
+	}{
+		{"host", session("v1", "host"), member("host"), true},
+		{"participant", session("v1", "host"), member("participant"), false},
+		{"stranger", session("v1", "host"), member("stranger"), false},
+		{"admin", session("v1", "host"), admin, true},
+		{"manage guild", session("v1", "host"), manager, true},
+		{"moderator", session("v1", "host"), member("mod", "other", "mods"), true},
+		{"other role", session("v1", "host"), member("mod", "other"), false},
+		{"participant after host left", session("v2", "host"), member("participant"), true},
+		{"stranger after host left", session("v2", "host"), member("stranger"), false},
+		// scheduled sessions never have a host
+		{"hostless participant", session("v1", ""), member("participant"), true},
+		{"hostless stranger", session("v1", ""), member("stranger"), false},
+		{"hostless moderator", session("v1", ""), member("mod", "mods"), true},
+		{"hostless empty channel", session("v3", ""), member("stranger"), false},
+		{"no member", session("v1", ""), nil, false},
+		{"no user", session("v1", ""), &discordgo.Member{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := auth.CanManage(ctx, tt.session, tt.member); got != tt.want {
+				t.Errorf("CanManage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/benjamonnguyen/pomomo-go"
+)
+
+type PresetRepo interface {
+	UpsertPreset(context.Context, pomomo.SessionPresetRecord) error
+	DeletePreset(ctx context.Context, guildID, userID, name string) error
+	GetPresets(ctx context.Context, guildID, userID string) ([]pomomo.SessionPresetRecord, error)
+}
+
+// availablePresets returns the user's presets, then the guild's, then the built-ins.
+// Built-ins are still returned alongside any error.
+func availablePresets(ctx context.Context, repo PresetRepo, guildID, userID string) ([]pomomo.SessionPresetRecord, error) {
+	presets, err := repo.GetPresets(ctx, guildID, userID)
+	return append(presets, pomomo.BuiltinPresets...), err
+}
+
+// findPreset matches name case-insensitively, preferring earlier presets
+func findPreset(presets []pomomo.SessionPresetRecord, name string) (pomomo.SessionPresetRecord, bool) {
+	for _, p := range presets {
+		if strings.EqualFold(p.Name, strings.TrimSpace(name)) {
+			return p, true
+		}
+	}
+	return pomomo.SessionPresetRecord{}, false
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/benjamonnguyen/pomomo-go"
+)
+
+// fakePresetRepo returns its presets for any guild and user - only reads are implemented
+type fakePresetRepo struct {
+	PresetRepo
+	presets []pomomo.SessionPresetRecord
+	err     error
+}
+
+func (r *fakePresetRepo) GetPresets(context.Context, string, string) ([]pomomo.SessionPresetRecord, error) {
+	return r.presets, r.err
+}
+
+func TestFindPreset(t *testing.T) {
+	user := pomomo.SessionPresetRecord{GuildID: "g1", UserID: "u1", Name: "Study", Pomodoro: 45 * time.Minute}
+	guild := pomomo.SessionPresetRecord{GuildID: "g1", Name: "study", Pomodoro: 30 * time.Minute}
+	// shadows the built-in of the same name
+	classic := pomomo.SessionPresetRecord{GuildID: "g1", Name: "Classic 25/5", Pomodoro: 20 * time.Minute}
+
+	tests := []struct {
+		name    string
+		repo    *fakePresetRepo
+		find    string
+		want    pomomo.SessionPresetRecord
+		wantOK  bool
+		wantErr bool
+	}{
+		{"user before guild", &fakePresetRepo{presets: []pomomo.SessionPresetRecord{user, guild}}, "study", user, true, false},
+		{"case and spaces", &fakePresetRepo{presets: []pomomo.SessionPresetRecord{guild}}, "  STUDY ", guild, true, false},
+		{"saved before builtin", &fakePresetRepo{presets: []pomomo.SessionPresetRecord{classic}}, "classic 25/5", classic, true, false},
+		{"builtin", &fakePresetRepo{}, "deep work 90/20", pomomo.BuiltinPresets[2], true, false},
+		// built-ins still work when saved presets can't be read
+		{"builtin on error", &fakePresetRepo{presets: nil, err: errors.New("database is locked")}, "52/17", pomomo.BuiltinPresets[1], true, true},
+		{"not found", &fakePresetRepo{presets: []pomomo.SessionPresetRecord{user}}, "nap", pomomo.SessionPresetRecord{}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			presets, err := availablePresets(context.Background(), tt.repo, "g1", "u1")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("availablePresets() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			got, ok := findPreset(presets, tt.find)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("findPreset(%q) = %+v, %v, want %+v, %v", tt.find, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/benjamonnguyen/pomomo-go"
+	"github.com/benjamonnguyen/pomomo-go/cmd/bot/models"
+	"github.com/benjamonnguyen/pomomo-go/sqlite"
+	"github.com/bwmarrin/discordgo"
+	"github.com/charmbracelet/log"
+)
+
+type ScheduleRepo interface {
+	InsertSchedule(context.Context, pomomo.SessionScheduleRecord) (pomomo.ExistingSessionScheduleRecord, error)
+	UpdateNextFireAt(ctx context.Context, id pomomo.ScheduleID, next time.Time) error
+	DeleteSchedule(ctx context.Context, guildID string, id pomomo.ScheduleID) (pomomo.ExistingSessionScheduleRecord, error)
+	GetSchedules(ctx context.Context, guildID string) ([]pomomo.ExistingSessionScheduleRecord, error)
+	GetDueSchedules(ctx context.Context, t time.Time) ([]pomomo.ExistingSessionScheduleRecord, error)
+}
+
+var (
+	scheduleTickRate = 30 * time.Second
+	// missedFireGrace is how late a schedule can still fire, e.g. after the bot was down
+	missedFireGrace = 15 * time.Minute
+	// scheduledJoinGrace is how long a scheduled session waits for participants before it's ended as empty
+	scheduledJoinGrace = 10 * time.Minute
+)
+
+// sessionScheduler starts sessions when their schedules are due
+type sessionScheduler struct {
+	repo           ScheduleRepo
+	guildRepo      GuildSettingsRepo
+	presetRepo     PresetRepo
+	sessionManager SessionManager
+	dm             DiscordMessenger
+}
+
+func newSessionScheduler(repo ScheduleRepo, guildRepo GuildSettingsRepo, presetRepo PresetRepo, sessionManager SessionManager, dm DiscordMessenger) *sessionScheduler {
+	return &sessionScheduler{
+		repo:           repo,
+		guildRepo:      guildRepo,
+		presetRepo:     presetRepo,
+		sessionManager: sessionManager,
+		dm:             dm,
+	}
+}
+
+// Run fires due schedules immediately and then every scheduleTickRate until ctx is done.
+// Since next fire times are persisted, schedules that came due while the bot was down are
+// fired on startup if they're within missedFireGrace and skipped otherwise.
+func (sch *sessionScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(scheduleTickRate)
+	defer ticker.Stop()
+	for {
+		if err := sch.FireDue(ctx); err != nil {
+			log.Error("failed to fire due schedules", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (sch *sessionScheduler) FireDue(ctx context.Context) error {
+	now := time.Now()
+	due, err := sch.repo.GetDueSchedules(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	for _, schedule := range due {
+		guildSettings, err := getGuildSettings(ctx, sch.guildRepo, schedule.GuildID)
+		if err != nil {
+			log.Error("failed to get guild settings - using defaults", "gid", schedule.GuildID, "err", err)
+		}
+
+		// advance before starting so that a failed start isn't retried every tick
+		next := schedule.Recurrence.Next(now, guildSettings.Location())
+		if err := sch.repo.UpdateNextFireAt(ctx, schedule.ID, next); err != nil {
+			log.Error("failed to advance schedule", "scheduleID", schedule.ID, "err", err)
+			continue
+		}
+		if late := now.Sub(schedule.NextFireAt); late > missedFireGrace {
+			log.Info("skipped missed schedule", "scheduleID", schedule.ID, "missedAt", schedule.NextFireAt, "next", next)
+			continue
+		}
+		if err := sch.start(ctx, schedule, guildSettings); err != nil {
+			log.Error("failed to start scheduled session", "scheduleID", schedule.ID, "err", err)
+		}
+	}
+	return nil
+}
+
+func (sch *sessionScheduler) start(ctx context.Context, schedule pomomo.ExistingSessionScheduleRecord, guildSettings pomomo.GuildSettingsRecord) error {
+	if sch.sessionManager.HasSession(string(schedule.TextCID)) || sch.sessionManager.HasVoiceSession(string(schedule.VoiceCID)) {
+		log.Info("skipped schedule - channel already has a session", "scheduleID", schedule.ID)
+		return nil
+	}
+
+	settings := guildSettings.SessionDefaults()
+	if schedule.PresetName != "" {
+		presets, err := availablePresets(ctx, sch.presetRepo, schedule.GuildID, schedule.CreatorUserID)
+		if err != nil {
+			log.Error("failed to get presets", "gid", schedule.GuildID, "err", err)
+		}
+		if preset, ok := findPreset(presets, schedule.PresetName); ok {
+			settings = preset.Apply(settings)
+		} else {
+			log.Warn("scheduled preset not found - using defaults", "scheduleID", schedule.ID, "preset", schedule.PresetName)
+		}
+	}
+
+	preview := models.NewSession("", schedule.GuildID, string(schedule.TextCID), string(schedule.VoiceCID), "", settings)
+	preview.GoNextInterval(false) // initialize fields for display - "real" session is created by sessionManager
+	var components []discordgo.MessageComponent
+	if schedule.PingRoleID != "" {
+		components = append(components, TextDisplay(fmt.Sprintf("<@&%s> a scheduled session is starting in <#%s>!", schedule.PingRoleID, schedule.VoiceCID)))
+	}
+	components = append(components, SessionMessageComponents(preview)...)
+	msg, err := sch.dm.SendChannelMessage(schedule.TextCID, components...)
+	if err != nil {
+		return fmt.Errorf("failed to send session message: %w", err)
+	}
+
+	session, err := sch.sessionManager.StartSession(ctx, startSessionRequest{
+		guildID:    schedule.GuildID,
+		textCID:    string(schedule.TextCID),
+		voiceCID:   string(schedule.VoiceCID),
+		messageID:  msg.ID,
+		settings:   settings,
+		scheduleID: schedule.ID,
+	})
+	if err != nil {
+		if _, err := sch.dm.EditChannelMessage(schedule.TextCID, msg.ID, TextDisplay("Failed to start scheduled session.")); err != nil {
+			log.Error(err)
+		}
+		return err
+	}
+	log.Info("started scheduled session", "id", session.ID, "scheduleID", schedule.ID)
+
+	if err := sch.dm.PinChannelMessage(schedule.TextCID, msg.ID); err != nil {
+		log.Error("failed to pin message", "err", err)
+	}
+	return nil
+}
+
+// reschedule recomputes the guild's next fire times, e.g. after its timezone changes
+func reschedule(ctx context.Context, repo ScheduleRepo, guildID string, loc *time.Location) error {
+	schedules, err := repo.GetSchedules(ctx, guildID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, s := range schedules {
+		err := repo.UpdateNextFireAt(ctx, s.ID, s.Recurrence.Next(now, loc))
+		if err != nil && err != sqlite.ErrNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+// awaitingParticipants reports whether a scheduled session is still within its join grace period
+func awaitingParticipants(s models.Session) bool {
+	return s.Record.ScheduleID != "" && time.Since(s.CreatedAt) < scheduledJoinGrace
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/benjamonnguyen/pomomo-go"
+	"github.com/benjamonnguyen/pomomo-go/sqlite"
+	"github.com/bwmarrin/discordgo"
+)
+
+// fakeScheduleRepo keeps schedules in memory - only what the scheduler fires with is implemented
+type fakeScheduleRepo struct {
+	ScheduleRepo
+
+	mu        sync.Mutex
+	schedules map[pomomo.ScheduleID]pomomo.ExistingSessionScheduleRecord
+}
+
+func (r *fakeScheduleRepo) UpdateNextFireAt(_ context.Context, id pomomo.ScheduleID, next time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.schedules[id]
+	if !ok {
+		return sqlite.ErrNotFound
+	}
+	s.NextFireAt = next
+	r.schedules[id] = s
+	return nil
+}
+
+func (r *fakeScheduleRepo) GetDueSchedules(_ context.Context, t time.Time) ([]pomomo.ExistingSessionScheduleRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var due []pomomo.ExistingSessionScheduleRecord
+	for _, s := range r.schedules {
+		if !s.NextFireAt.After(t) {
+			due = append(due, s)
+		}
+	}
+	return due, nil
+}
+
+func (r *fakeScheduleRepo) nextFireAt(id pomomo.ScheduleID) time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.schedules[id].NextFireAt
+}
+
+type fakeGuildSettingsRepo struct {
+	GuildSettingsRepo
+	settings map[string]pomomo.GuildSettingsRecord
+	err      error
+}
+
+func (r *fakeGuildSettingsRepo) GetGuildSettings(_ context.Context, guildID string) (pomomo.ExistingGuildSettingsRecord, error) {
+	if r.err != nil {
+		return pomomo.ExistingGuildSettingsRecord{}, r.err
+	}
+	s, ok := r.settings[guildID]
+	if !ok {
+		return pomomo.ExistingGuildSettingsRecord{}, sqlite.ErrNotFound
+	}
+	return pomomo.ExistingGuildSettingsRecord{GuildSettingsRecord: s}, nil
+}
+
+// fakeMessenger records the channels messages were sent to
+type fakeMessenger struct {
+	DiscordMessenger
+
+	mu   sync.Mutex
+	sent []pomomo.TextChannelID
+}
+
+func (m *fakeMessenger) SendChannelMessage(cID pomomo.TextChannelID, _ ...discordgo.MessageComponent) (*discordgo.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, cID)
+	return &discordgo.Message{ID: "m" + string(cID), ChannelID: string(cID)}, nil
+}
+
+func (m *fakeMessenger) PinChannelMessage(pomomo.TextChannelID, string) error {
+	return nil
+}
+
+func TestSessionSchedulerFireDue(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	every := pomomo.Recurrence{Days: pomomo.Everyday, Hour: 9}
+	schedule := func(id, guildID string, nextFireAt time.Time) pomomo.ExistingSessionScheduleRecord {
+		return pomomo.ExistingSessionScheduleRecord{
+			ExistingRecord: pomomo.ExistingRecord[pomomo.ScheduleID]{ID: pomomo.ScheduleID(id)},
+			SessionScheduleRecord: pomomo.SessionScheduleRecord{
+				GuildID:    guildID,
+				TextCID:    pomomo.TextChannelID("t-" + id),
+				VoiceCID:   pomomo.VoiceChannelID("v-" + id),
+				Recurrence: every,
+				NextFireAt: nextFireAt,
+			},
+		}
+	}
+	tests := []struct {
+		name      string
+		schedule  pomomo.ExistingSessionScheduleRecord
+		wantStart bool
+		wantNext  time.Time
+	}{
+		{"on time", schedule("s1", "g1", now), true, every.Next(now, time.UTC)},
+		{"within grace", schedule("s2", "g1", now.Add(-missedFireGrace+time.Minute)), true, every.Next(now, time.UTC)},
+		{"past grace", schedule("s3", "g1", now.Add(-missedFireGrace-time.Minute)), false, every.Next(now, time.UTC)},
+		{"days late", schedule("s4", "g1", now.Add(-72*time.Hour)), false, every.Next(now, time.UTC)},
+		{"guild timezone", schedule("s5", "g2", now.Add(-time.Minute)), true, every.Next(now, ny)},
+		{"not due", schedule("s6", "g1", now.Add(time.Minute)), false, now.Add(time.Minute)},
+	}
+
+	repo := &fakeScheduleRepo{schedules: make(map[pomomo.ScheduleID]pomomo.ExistingSessionScheduleRecord)}
+	for _, tt := range tests {
+		repo.schedules[tt.schedule.ID] = tt.schedule
+	}
+	g2 := pomomo.NewGuildSettingsRecord("g2")
+	g2.Timezone = "America/New_York"
+	guildRepo := &fakeGuildSettingsRepo{settings: map[string]pomomo.GuildSettingsRecord{"g2": g2}}
+	dm := &fakeMessenger{}
+	m := newTestSessionManager(t)
+	sch := newSessionScheduler(repo, guildRepo, nil, m, dm)
+	if err := sch.FireDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := m.GetSession(tt.schedule.TextCID)
+			if started := err == nil; started != tt.wantStart {
+				t.Fatalf("started = %v, want %v", started, tt.wantStart)
+			}
+			if tt.wantStart && s.Record.ScheduleID != tt.schedule.ID {
+				t.Errorf("session ScheduleID = %v, want %v", s.Record.ScheduleID, tt.schedule.ID)
+			}
+			// missed schedules are still advanced so that they aren't due again
+			if next := repo.nextFireAt(tt.schedule.ID); !next.Equal(tt.wantNext) {
+				t.Errorf("NextFireAt = %v, want %v", next, tt.wantNext)
+			}
+		})
+	}
+	if len(dm.sent) != 3 {
+		t.Errorf("sent %d session messages, want 3", len(dm.sent))
+	}
+
+	// advanced schedules aren't fired again
+	if err := sch.FireDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(dm.sent) != 3 {
+		t.Errorf("sent %d session messages after firing again, want 3", len(dm.sent))
+	}
+}
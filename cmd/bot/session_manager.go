@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"slices"
 	"sync"
 	"time"
 
@@ -12,13 +13,20 @@ import (
 	"github.com/charmbracelet/log"
 )
 
-var updateTickRate = 20 * time.Second
+// timerBarTickRate is how often session messages are refreshed - transitions don't wait for it
+var timerBarTickRate = 20 * time.Second
+
+// pausedTickRate is how often paused sessions are checked for having expired or reached their goal
+// in case their transition timer was missed
+var pausedTickRate = time.Minute
 
 type startSessionRequest struct {
 	guildID, textCID, voiceCID, messageID string
 	settings                              pomomo.SessionSettingsRecord
+	scheduleID                            pomomo.ScheduleID
+	lobby                                 time.Duration // countdown before the first interval, if any
 
-	// user that is starting the session to be joined as participant
+	// user that is starting the session to be joined as participant - empty for scheduled sessions
 	user struct {
 		id         string
 		mute, deaf bool
@@ -30,20 +38,32 @@ type SessionRepo interface {
 	UpdateSession(context.Context, pomomo.SessionID, pomomo.SessionRecord) (pomomo.ExistingSessionRecord, error)
 	DeleteSession(context.Context, pomomo.SessionID) (pomomo.ExistingSessionRecord, error)
 	GetSession(context.Context, pomomo.SessionID) (pomomo.ExistingSessionRecord, error)
-	GetSessionsByStatus(context.Context, ...pomomo.SessionStatus) ([]pomomo.ExistingSessionRecord, error)
+	GetSessions(context.Context, pomomo.SessionFilter) ([]pomomo.ExistingSessionRecord, error)
+	DeleteEndedSessions(ctx context.Context, endedBefore time.Time) (int64, error)
 
 	// settings
 	InsertSettings(context.Context, pomomo.SessionSettingsRecord) (pomomo.ExistingSessionSettingsRecord, error)
 	GetSettings(context.Context, pomomo.SessionID) (pomomo.ExistingSessionSettingsRecord, error)
+	UpdateSettings(context.Context, pomomo.SessionID, pomomo.SessionSettingsRecord) (pomomo.ExistingSessionSettingsRecord, error)
 	DeleteSettings(context.Context, pomomo.SessionID) (pomomo.ExistingSessionSettingsRecord, error)
 }
 
 type SessionManager interface {
 	HasSession(textCID string) bool
 	GetSession(cid pomomo.TextChannelID) (models.Session, error)
+	GetVoiceSession(voiceCID pomomo.VoiceChannelID) (models.Session, error)
 	StartSession(context.Context, startSessionRequest) (models.Session, error)
 	EndSession(ctx context.Context, cid pomomo.TextChannelID) (models.Session, error)
 	SkipInterval(ctx context.Context, cid pomomo.TextChannelID) (models.Session, error)
+	// StartNow ends a session's lobby countdown early
+	StartNow(ctx context.Context, cid pomomo.TextChannelID) (models.Session, error)
+	// TakeBreak ends a flowtime session's open-ended focus
+	TakeBreak(ctx context.Context, cid pomomo.TextChannelID) (models.Session, error)
+	PauseSession(ctx context.Context, cid pomomo.TextChannelID) (models.Session, error)
+	ResumeSession(ctx context.Context, cid pomomo.TextChannelID) (models.Session, error)
+	TransferHost(ctx context.Context, cid pomomo.TextChannelID, userID string) (models.Session, error)
+	// UpdateSettings applies settings to a running or paused session, rescaling or keeping the current interval's time remaining
+	UpdateSettings(ctx context.Context, cid pomomo.TextChannelID, settings pomomo.SessionSettingsRecord, rescale bool) (models.Session, error)
 	RestoreSessions(context.Context) error
 
 	//
@@ -52,6 +72,9 @@ type SessionManager interface {
 
 	// lifecycle hooks
 	AfterUpdate(func(ctx context.Context, before, curr models.Session))
+	// OnIntervalWarning is called once per interval at the session's warning offset before it ends.
+	// ctx is cancelled once the warning is stale, i.e. the interval ended, was skipped or paused, or the session ended.
+	OnIntervalWarning(func(ctx context.Context, s models.Session))
 
 	//
 	Shutdown() error
@@ -65,7 +88,11 @@ type sessionManager struct {
 	parentCtx context.Context
 	pm        ParticipantsManager
 
-	afterUpdate func(ctx context.Context, before, curr models.Session)
+	loopsMu sync.Mutex
+	loops   map[pomomo.TextChannelID]*updateLoop
+
+	afterUpdate       func(ctx context.Context, before, curr models.Session)
+	onIntervalWarning func(ctx context.Context, s models.Session)
 }
 
 func NewSessionManager(ctx context.Context, repo SessionRepo, pm ParticipantsManager, tx transactor.Transactor) SessionManager {
@@ -74,7 +101,7 @@ func NewSessionManager(ctx context.Context, repo SessionRepo, pm ParticipantsMan
 		locks:            make(map[pomomo.TextChannelID]*sync.Mutex),
 		cancelFuncs:      make(map[pomomo.TextChannelID]func()),
 		guildSessionCnts: make(map[string]int),
-		voiceChannels:    make(map[pomomo.VoiceChannelID]struct{}),
+		voiceChannels:    make(map[pomomo.VoiceChannelID]pomomo.TextChannelID),
 	}
 
 	return &sessionManager{
@@ -83,6 +110,7 @@ func NewSessionManager(ctx context.Context, repo SessionRepo, pm ParticipantsMan
 		pm:        pm,
 		tx:        tx,
 		parentCtx: ctx,
+		loops:     make(map[pomomo.TextChannelID]*updateLoop),
 	}
 }
 
@@ -103,7 +131,9 @@ func (m *sessionManager) RestoreSessions(ctx context.Context) error {
 	var toRestore []*models.Session
 	var toEnd []models.Session
 	err := m.tx.WithinTransaction(ctx, func(ctx context.Context) error {
-		pendingSessionRecords, err := m.repo.GetSessionsByStatus(ctx, pomomo.SessionRunning, pomomo.SessionPaused)
+		pendingSessionRecords, err := m.repo.GetSessions(ctx, pomomo.SessionFilter{
+			Statuses: []pomomo.SessionStatus{pomomo.SessionRunning, pomomo.SessionPaused, pomomo.SessionLobby},
+		})
 		if err != nil {
 			return err
 		}
@@ -114,12 +144,26 @@ func (m *sessionManager) RestoreSessions(ctx context.Context) error {
 				return err
 			}
 			session := models.SessionFromExistingRecords(r, existingSettings)
-			if session.TimeRemaining() < (-1 * time.Hour) {
-				// bot has been down for over an hour
+			if session.Record.Status == pomomo.SessionPaused {
+				// time remaining is frozen so there's nothing to catch up on
+				toRestore = append(toRestore, &session)
+				continue
+			}
+			if !session.OpenEnded() && session.TimeRemaining() < (-1*time.Hour) {
+				// bot has been down for over an hour - the session stopped once its interval ran out
+				session.Record.EndedAt = session.Record.IntervalStartedAt.Add(session.Record.TimeRemainingAtStart)
 				toEnd = append(toEnd, session)
 				continue
 			}
-			for session.TimeRemaining() <= 0 {
+			if session.Record.Status == pomomo.SessionLobby {
+				if session.TimeRemaining() > 0 {
+					toRestore = append(toRestore, &session)
+					continue
+				}
+				// countdown expired while the bot was down
+				session.StartFromLobby()
+			}
+			for !session.OpenEnded() && session.TimeRemaining() <= 0 {
 				session.GoNextInterval(true)
 			}
 			toRestore = append(toRestore, &session)
@@ -171,55 +215,265 @@ func (m *sessionManager) GetSession(cid pomomo.TextChannelID) (models.Session, e
 	return *s, nil
 }
 
+func (m *sessionManager) GetVoiceSession(voiceCID pomomo.VoiceChannelID) (models.Session, error) {
+	m.cache.cacheMu.RLock()
+	cid, exists := m.cache.voiceChannels[voiceCID]
+	m.cache.cacheMu.RUnlock()
+	if !exists {
+		return models.Session{}, fmt.Errorf("session not found for voiceCID: %v", voiceCID)
+	}
+	return m.GetSession(cid)
+}
+
 func (m *sessionManager) AfterUpdate(handler func(ctx context.Context, before, curr models.Session)) {
 	m.afterUpdate = handler
 }
 
-func (m *sessionManager) updateSession(ctx context.Context, s *models.Session) error {
-	if s.TimeRemaining() > 0 {
-		return nil
+func (m *sessionManager) OnIntervalWarning(handler func(ctx context.Context, s models.Session)) {
+	m.onIntervalWarning = handler
+}
+
+// nextTimers returns when the session's next transition and interval warning are due, or zero times if none are,
+// and whether it's paused
+func (m *sessionManager) nextTimers(cid pomomo.TextChannelID) (transitionAt, warnAt time.Time, paused bool) {
+	s, unlock := m.cache.Get(cid)
+	if s == nil {
+		return time.Time{}, time.Time{}, false
 	}
-	s.GoNextInterval(true)
-	return m.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+	defer unlock()
+	return s.NextTransitionAt(), s.WarningAt(), s.Record.Status == pomomo.SessionPaused
+}
+
+// warnInterval calls the interval warning hook and reports true if warnAt is still the session's warning
+// time, i.e. the interval hasn't been skipped, paused or edited since the warning was scheduled
+func (m *sessionManager) warnInterval(ctx context.Context, cid pomomo.TextChannelID, warnAt time.Time) bool {
+	var current, ended bool
+	var curr models.Session
+	func() {
+		s, unlock := m.cache.Get(cid)
+		if s == nil {
+			return
+		}
+		defer unlock()
+		if !s.WarningAt().Equal(warnAt) {
+			return
+		}
+		current, ended = true, s.TimeRemaining() <= 0
+		curr = *s
+	}()
+	if current && !ended && m.onIntervalWarning != nil {
+		go m.onIntervalWarning(ctx, curr)
+	}
+	return current
+}
+
+// updateSession moves the session on if its lobby or current interval is over and reports whether it did
+func (m *sessionManager) updateSession(ctx context.Context, s *models.Session) (bool, error) {
+	switch {
+	case s.Record.Status == pomomo.SessionLobby && s.TimeRemaining() <= 0:
+		s.StartFromLobby()
+	case s.Record.Status == pomomo.SessionRunning && !s.OpenEnded() && s.TimeRemaining() <= 0:
+		s.GoNextInterval(true)
+	default:
+		return false, nil
+	}
+	err := m.tx.WithinTransaction(ctx, func(ctx context.Context) error {
 		_, err := m.repo.UpdateSession(ctx, s.ID, s.Record)
 		return err
 	})
+	return err == nil, err
 }
 
-func (m *sessionManager) startUpdateLoop(ctx context.Context, cid pomomo.TextChannelID) {
+// lobbyDeserted reports whether the update from before to curr ended the session's lobby countdown without
+// anyone joining the host. Lobbies the host started early aren't deserted.
+func lobbyDeserted(before, curr models.Session, participants []models.Participant) bool {
+	if before.Record.Status != pomomo.SessionLobby || curr.Record.Status == pomomo.SessionLobby {
+		return false
+	}
+	// sessions started from an expired lobby are backdated to when the countdown ran out
+	countdownEnd := before.Record.IntervalStartedAt.Add(before.Record.TimeRemainingAtStart)
+	if curr.Record.IntervalStartedAt.Before(countdownEnd) {
+		return false
+	}
+	return !slices.ContainsFunc(participants, func(p models.Participant) bool {
+		return p.Record.UserID != curr.Record.HostUserID
+	})
+}
+
+// updateLoop tracks a session's update loop so that it can be stopped and woken
+type updateLoop struct {
+	cancel func()
+	wake   chan struct{}
+}
+
+// wakeUpdateLoop makes the update loop rearm its timers after the session was changed outside of it
+func (m *sessionManager) wakeUpdateLoop(cid pomomo.TextChannelID) {
+	m.loopsMu.Lock()
+	defer m.loopsMu.Unlock()
+	if l := m.loops[cid]; l != nil && l.wake != nil {
+		select {
+		case l.wake <- struct{}{}:
+		default:
+			// already pending
+		}
+	}
+}
+
+func (m *sessionManager) removeUpdateLoop(cid pomomo.TextChannelID) {
+	m.loopsMu.Lock()
+	defer m.loopsMu.Unlock()
+	if l := m.loops[cid]; l != nil {
+		l.cancel()
+	}
+	delete(m.loops, cid)
+}
+
+// startUpdateLoop runs the session until it ends. Transitions fire on a timer armed for their exact time
+// while the slower timerBarTickRate only refreshes the session message. Paused sessions are only
+// checked every pausedTickRate so that they still end once they expire or reach their end time.
+func (m *sessionManager) startUpdateLoop(sessionCtx context.Context, cid pomomo.TextChannelID) {
+	m.loopsMu.Lock()
+	if l := m.loops[cid]; l != nil {
+		l.cancel()
+	}
+	ctx, cancel := context.WithCancel(sessionCtx)
+	wake := make(chan struct{}, 1)
+	m.loops[cid] = &updateLoop{cancel: cancel, wake: wake}
+	m.loopsMu.Unlock()
+
 	m.wg.Go(func() {
 		var updateMu sync.Mutex
-		ticker := time.NewTicker(updateTickRate)
+		tickRate := func(paused bool) time.Duration {
+			if paused {
+				return pausedTickRate
+			}
+			return timerBarTickRate
+		}
+		ticker := time.NewTicker(timerBarTickRate)
+		defer ticker.Stop()
+		transitionTimer := time.NewTimer(0)
+		transitionTimer.Stop()
+		defer transitionTimer.Stop()
+		warnTimer := time.NewTimer(0)
+		warnTimer.Stop()
+		defer warnTimer.Stop()
+
+		// a time that has already fired isn't rearmed, e.g. after a failed update, so that it's
+		// retried on the next tick rather than in a hot loop
+		var transitionAt, firedTransitionAt, warnAt, warnedAt time.Time
+		var paused bool
+		// cancels the context of the last warning once it's stale
+		cancelWarning := func() {}
+		defer func() { cancelWarning() }()
+		arm := func(timer *time.Timer, at, fired time.Time) {
+			if at.IsZero() || at.Equal(fired) {
+				timer.Stop()
+				return
+			}
+			timer.Reset(time.Until(at))
+		}
+		armTimers := func() {
+			wasPaused := paused
+			transitionAt, warnAt, paused = m.nextTimers(cid)
+			arm(transitionTimer, transitionAt, firedTransitionAt)
+			arm(warnTimer, warnAt, warnedAt)
+			if !warnAt.Equal(warnedAt) {
+				cancelWarning()
+			}
+			if paused != wasPaused {
+				ticker.Reset(tickRate(paused))
+			}
+		}
 		for {
+			var expired, goalReached, missing bool
+			var before, curr models.Session
 			func() {
 				s, unlock := m.cache.Get(cid)
-				defer unlock()
 				if s == nil {
 					log.Error("UNEXPECTED - ending update loop - session not found", "textCID", cid)
+					missing = true
+					return
+				}
+				defer unlock()
+				if expired = s.Expired(); expired {
+					return
+				}
+				if goalReached = s.GoalReached(); goalReached {
 					return
 				}
 
-				before := *s
-				if err := m.updateSession(ctx, s); err != nil {
+				before = *s
+				changed, err := m.updateSession(ctx, s)
+				if err != nil {
 					log.Error("failed to update session interval in timer", "sessionID", s.ID, "err", err)
 					return
 				}
+				curr = *s
+				if goalReached = s.GoalReached(); goalReached {
+					// the update that reached the goal is handled synchronously below so that it lands before the end
+					return
+				}
+				if !changed && s.Record.Status == pomomo.SessionPaused {
+					// there's no timer bar to refresh
+					return
+				}
 
 				if m.afterUpdate != nil {
-					// can't rely on onSessionUpdate to handle ctx timeout - if still locked, skip call
 					go func() {
-						if updateMu.TryLock() {
-							defer updateMu.Unlock()
-							m.afterUpdate(ctx, before, *s)
+						if changed {
+							// transitions are never dropped
+							updateMu.Lock()
+						} else if !updateMu.TryLock() {
+							// timer bar refreshes are coalesced with the update that's still in flight
+							return
 						}
+						defer updateMu.Unlock()
+						m.afterUpdate(ctx, before, curr)
 					}()
 				}
 			}()
-			select {
-			case <-ctx.Done():
+			if missing {
 				return
-			case <-ticker.C:
-				continue
+			}
+			if goalReached && curr.ID != "" && m.afterUpdate != nil {
+				updateMu.Lock()
+				m.afterUpdate(ctx, before, curr)
+				updateMu.Unlock()
+			}
+			if expired || goalReached {
+				// session lock must be released before ending
+				_, err := m.EndSession(m.parentCtx, cid)
+				if err == nil {
+					log.Info("ended session", "textCID", cid, "expired", expired, "goalReached", goalReached)
+					return
+				}
+				log.Error("failed to end session - retrying next tick", "textCID", cid, "err", err)
+			}
+			armTimers()
+		wait:
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					break wait
+				case <-transitionTimer.C:
+					firedTransitionAt = transitionAt
+					break wait
+				case <-wake:
+					// the session was skipped, edited, etc.
+					armTimers()
+				case <-warnTimer.C:
+					warnCtx, cancel := context.WithCancel(ctx)
+					if m.warnInterval(warnCtx, cid, warnAt) {
+						cancelWarning()
+						warnedAt, cancelWarning = warnAt, cancel
+					} else {
+						cancel()
+					}
+					// rearm in case the interval changed since the warning was scheduled
+					armTimers()
+				}
 			}
 		}
 	})
@@ -227,6 +481,11 @@ func (m *sessionManager) startUpdateLoop(ctx context.Context, cid pomomo.TextCha
 
 func (m *sessionManager) StartSession(ctx context.Context, req startSessionRequest) (models.Session, error) {
 	session := models.NewSession("", req.guildID, req.textCID, req.voiceCID, req.messageID, req.settings)
+	session.Record.HostUserID = req.user.id
+	session.Record.ScheduleID = req.scheduleID
+	if req.lobby > 0 {
+		session.OpenLobby(req.lobby)
+	}
 
 	if m.cache.Has(session.Record.TextCID) {
 		return models.Session{}, fmt.Errorf("session already exists for guild %s channel %s", req.guildID, req.textCID)
@@ -249,6 +508,7 @@ func (m *sessionManager) StartSession(ctx context.Context, req startSessionReque
 		}
 
 		session.ID = inserted.ID
+		session.CreatedAt = inserted.CreatedAt
 		return nil
 	})
 	release()
@@ -258,22 +518,26 @@ func (m *sessionManager) StartSession(ctx context.Context, req startSessionReque
 	sessionCtxs := m.cache.Add(m.parentCtx, &session)
 
 	// user that starts session is automatically joined as a participant
-	unlock := m.pm.AcquireVoiceChannelLock(session.Record.VoiceCID)
-	defer unlock()
-	_, err = m.pm.Insert(ctx, pomomo.ParticipantRecord{
-		SessionID:  session.ID,
-		GuildID:    session.Record.GuildID,
-		VoiceCID:   session.Record.VoiceCID,
-		UserID:     req.user.id,
-		IsMuted:    req.user.mute,
-		IsDeafened: req.user.deaf,
-	})
-	if err != nil {
-		log.Error("failed to insert original participant", "err", err, "uid", req.user.id, "sid", session.ID)
+	if req.user.id != "" {
+		unlock := m.pm.AcquireVoiceChannelLock(session.Record.VoiceCID)
+		defer unlock()
+		_, err = m.pm.Insert(ctx, pomomo.ParticipantRecord{
+			SessionID:  session.ID,
+			GuildID:    session.Record.GuildID,
+			VoiceCID:   session.Record.VoiceCID,
+			UserID:     req.user.id,
+			IsMuted:    req.user.mute,
+			IsDeafened: req.user.deaf,
+		})
+		if err != nil {
+			log.Error("failed to insert original participant", "err", err, "uid", req.user.id, "sid", session.ID)
+		}
 	}
 
+	// copied before the update loop starts changing the cached session
+	started := session
 	m.startUpdateLoop(sessionCtxs[0], session.Record.TextCID)
-	return session, nil
+	return started, nil
 }
 
 func (m *sessionManager) SkipInterval(ctx context.Context, cid pomomo.TextChannelID) (models.Session, error) {
@@ -282,12 +546,15 @@ func (m *sessionManager) SkipInterval(ctx context.Context, cid pomomo.TextChanne
 		return models.Session{}, fmt.Errorf("session not found for textCID: %v", cid)
 	}
 	defer unlock()
+	if s.Record.Status == pomomo.SessionLobby {
+		return *s, fmt.Errorf("session is in lobby for textCID: %v", cid)
+	}
 
 	before := *s
 	s.GoNextInterval(false)
 	// TODO GoNextInterval codesmell
 	s.Record.IntervalStartedAt = time.Now()
-	s.Stats.Skips += 1
+	s.Record.Stats.Skips += 1
 
 	// Update database with new interval state
 	err := m.tx.WithinTransaction(ctx, func(ctx context.Context) error {
@@ -297,6 +564,7 @@ func (m *sessionManager) SkipInterval(ctx context.Context, cid pomomo.TextChanne
 	if err != nil {
 		return models.Session{}, fmt.Errorf("failed to skip interval: %w", err)
 	}
+	m.wakeUpdateLoop(cid)
 
 	if m.afterUpdate != nil {
 		m.afterUpdate(ctx, before, *s)
@@ -304,17 +572,74 @@ func (m *sessionManager) SkipInterval(ctx context.Context, cid pomomo.TextChanne
 	return *s, nil
 }
 
+func (m *sessionManager) StartNow(ctx context.Context, cid pomomo.TextChannelID) (models.Session, error) {
+	s, unlock := m.cache.Get(cid)
+	if s == nil {
+		return models.Session{}, fmt.Errorf("session not found for textCID: %v", cid)
+	}
+	defer unlock()
+	if s.Record.Status != pomomo.SessionLobby {
+		return *s, fmt.Errorf("session is not in lobby for textCID: %v", cid)
+	}
+
+	before := *s
+	s.StartFromLobby()
+	err := m.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		_, err := m.repo.UpdateSession(ctx, s.ID, s.Record)
+		return err
+	})
+	if err != nil {
+		*s = before
+		return models.Session{}, fmt.Errorf("failed to start session: %w", err)
+	}
+	m.wakeUpdateLoop(cid)
+
+	if m.afterUpdate != nil {
+		m.afterUpdate(ctx, before, *s)
+	}
+	return *s, nil
+}
+
+func (m *sessionManager) TakeBreak(ctx context.Context, cid pomomo.TextChannelID) (models.Session, error) {
+	s, unlock := m.cache.Get(cid)
+	if s == nil {
+		return models.Session{}, fmt.Errorf("session not found for textCID: %v", cid)
+	}
+	defer unlock()
+	if !s.OpenEnded() {
+		return *s, fmt.Errorf("session is not in open-ended focus for textCID: %v", cid)
+	}
+
+	before := *s
+	s.TakeBreak()
+	err := m.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		_, err := m.repo.UpdateSession(ctx, s.ID, s.Record)
+		return err
+	})
+	if err != nil {
+		*s = before
+		return models.Session{}, fmt.Errorf("failed to take break: %w", err)
+	}
+	m.wakeUpdateLoop(cid)
+
+	if m.afterUpdate != nil {
+		m.afterUpdate(ctx, before, *s)
+	}
+	return *s, nil
+}
+
+// endSession marks the session ended, now unless it already knows when it stopped, and persists it
 func (m *sessionManager) endSession(ctx context.Context, s models.Session) (models.Session, error) {
 	s.Record.Status = pomomo.SessionEnded
+	if s.Record.EndedAt.IsZero() {
+		s.Record.EndedAt = time.Now()
+	}
+	// ended sessions are kept for history until pruned
 	err := m.tx.WithinTransaction(ctx, func(ctx context.Context) error {
 		_, err := m.repo.UpdateSession(ctx, s.ID, s.Record)
 		if err != nil {
 			return fmt.Errorf("failed to update session status: %w", err)
 		}
-
-		if _, err := m.repo.DeleteSettings(ctx, s.ID); err != nil {
-			return fmt.Errorf("failed to delete session settings: %w", err)
-		}
 		return nil
 	})
 	return s, err
@@ -333,12 +658,121 @@ func (m *sessionManager) EndSession(ctx context.Context, cid pomomo.TextChannelI
 	}
 
 	m.cache.Remove(cid)
+	m.removeUpdateLoop(cid)
 	if m.afterUpdate != nil {
 		m.afterUpdate(ctx, *s, ended)
 	}
 	return ended, nil
 }
 
+func (m *sessionManager) PauseSession(ctx context.Context, cid pomomo.TextChannelID) (models.Session, error) {
+	s, unlock := m.cache.Get(cid)
+	if s == nil {
+		return models.Session{}, fmt.Errorf("session not found for textCID: %v", cid)
+	}
+	defer unlock()
+	if s.Record.Status != pomomo.SessionRunning {
+		return *s, fmt.Errorf("session is not running for textCID: %v", cid)
+	}
+
+	before := *s
+	s.Pause()
+	err := m.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		_, err := m.repo.UpdateSession(ctx, s.ID, s.Record)
+		return err
+	})
+	if err != nil {
+		*s = before
+		return models.Session{}, fmt.Errorf("failed to pause session: %w", err)
+	}
+	m.wakeUpdateLoop(cid)
+
+	if m.afterUpdate != nil {
+		m.afterUpdate(ctx, before, *s)
+	}
+	return *s, nil
+}
+
+func (m *sessionManager) ResumeSession(ctx context.Context, cid pomomo.TextChannelID) (models.Session, error) {
+	s, unlock := m.cache.Get(cid)
+	if s == nil {
+		return models.Session{}, fmt.Errorf("session not found for textCID: %v", cid)
+	}
+	defer unlock()
+	if s.Record.Status != pomomo.SessionPaused {
+		return *s, fmt.Errorf("session is not paused for textCID: %v", cid)
+	}
+
+	before := *s
+	s.Resume()
+	err := m.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		_, err := m.repo.UpdateSession(ctx, s.ID, s.Record)
+		return err
+	})
+	if err != nil {
+		*s = before
+		return models.Session{}, fmt.Errorf("failed to resume session: %w", err)
+	}
+	m.wakeUpdateLoop(cid)
+
+	if m.afterUpdate != nil {
+		m.afterUpdate(ctx, before, *s)
+	}
+	return *s, nil
+}
+
+func (m *sessionManager) TransferHost(ctx context.Context, cid pomomo.TextChannelID, userID string) (models.Session, error) {
+	s, unlock := m.cache.Get(cid)
+	if s == nil {
+		return models.Session{}, fmt.Errorf("session not found for textCID: %v", cid)
+	}
+	defer unlock()
+
+	before := *s
+	s.Record.HostUserID = userID
+	err := m.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		_, err := m.repo.UpdateSession(ctx, s.ID, s.Record)
+		return err
+	})
+	if err != nil {
+		*s = before
+		return models.Session{}, fmt.Errorf("failed to transfer host: %w", err)
+	}
+
+	if m.afterUpdate != nil {
+		m.afterUpdate(ctx, before, *s)
+	}
+	return *s, nil
+}
+
+func (m *sessionManager) UpdateSettings(ctx context.Context, cid pomomo.TextChannelID, settings pomomo.SessionSettingsRecord, rescale bool) (models.Session, error) {
+	s, unlock := m.cache.Get(cid)
+	if s == nil {
+		return models.Session{}, fmt.Errorf("session not found for textCID: %v", cid)
+	}
+	defer unlock()
+
+	before := *s
+	s.UpdateSettings(settings, rescale)
+	err := m.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if _, err := m.repo.UpdateSettings(ctx, s.ID, s.Settings); err != nil {
+			return err
+		}
+		_, err := m.repo.UpdateSession(ctx, s.ID, s.Record)
+		return err
+	})
+	if err != nil {
+		*s = before
+		return models.Session{}, fmt.Errorf("failed to update settings: %w", err)
+	}
+	m.wakeUpdateLoop(cid)
+
+	if m.afterUpdate != nil {
+		m.afterUpdate(ctx, before, *s)
+	}
+	return *s, nil
+}
+
 func (m *sessionManager) Shutdown() error {
 	m.cache.cacheMu.Lock()
 	for _, c := range m.cache.cancelFuncs {
@@ -357,7 +791,7 @@ type sessionCache struct {
 	sessions         map[pomomo.TextChannelID]*models.Session
 	locks            map[pomomo.TextChannelID]*sync.Mutex
 	cancelFuncs      map[pomomo.TextChannelID]func()
-	voiceChannels    map[pomomo.VoiceChannelID]struct{}
+	voiceChannels    map[pomomo.VoiceChannelID]pomomo.TextChannelID
 	guildSessionCnts map[string]int
 }
 
@@ -379,7 +813,7 @@ func (c *sessionCache) Add(ctx context.Context, sessions ...*models.Session) []c
 		sessionCtx, cancel := context.WithCancel(ctx)
 		c.cancelFuncs[key] = cancel
 		sessionCtxs = append(sessionCtxs, sessionCtx)
-		c.voiceChannels[s.Record.VoiceCID] = struct{}{}
+		c.voiceChannels[s.Record.VoiceCID] = key
 		c.guildSessionCnts[s.Record.GuildID] += 1
 	}
 
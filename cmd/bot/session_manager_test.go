@@ -0,0 +1,564 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/benjamonnguyen/pomomo-go"
+	"github.com/benjamonnguyen/pomomo-go/cmd/bot/models"
+	"github.com/benjamonnguyen/pomomo-go/sqlite"
+)
+
+type fakeTransactor struct{}
+
+func (fakeTransactor) WithinTransaction(ctx context.Context, fn func(context.Context) error) error {
+	return fn(ctx)
+}
+
+// fakeSessionRepo keeps records in memory - only what the session manager uses is implemented
+type fakeSessionRepo struct {
+	SessionRepo
+
+	mu       sync.Mutex
+	sessions map[pomomo.SessionID]pomomo.SessionRecord
+	settings map[pomomo.SessionID]pomomo.SessionSettingsRecord
+}
+
+func newFakeSessionRepo() *fakeSessionRepo {
+	return &fakeSessionRepo{
+		sessions: make(map[pomomo.SessionID]pomomo.SessionRecord),
+		settings: make(map[pomomo.SessionID]pomomo.SessionSettingsRecord),
+	}
+}
+
+func (r *fakeSessionRepo) InsertSession(_ context.Context, s pomomo.SessionRecord) (pomomo.ExistingSessionRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id := pomomo.SessionID(fmt.Sprintf("s%d", len(r.sessions)+1))
+	r.sessions[id] = s
+	return pomomo.ExistingSessionRecord{ExistingRecord: pomomo.ExistingRecord[pomomo.SessionID]{ID: id, CreatedAt: time.Now()}, SessionRecord: s}, nil
+}
+
+func (r *fakeSessionRepo) UpdateSession(_ context.Context, id pomomo.SessionID, s pomomo.SessionRecord) (pomomo.ExistingSessionRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessions[id] = s
+	return pomomo.ExistingSessionRecord{ExistingRecord: pomomo.ExistingRecord[pomomo.SessionID]{ID: id}, SessionRecord: s}, nil
+}
+
+func (r *fakeSessionRepo) InsertSettings(_ context.Context, s pomomo.SessionSettingsRecord) (pomomo.ExistingSessionSettingsRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.settings[s.SessionID] = s
+	return pomomo.ExistingSessionSettingsRecord{SessionSettingsRecord: s}, nil
+}
+
+func (r *fakeSessionRepo) UpdateSettings(_ context.Context, id pomomo.SessionID, s pomomo.SessionSettingsRecord) (pomomo.ExistingSessionSettingsRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.settings[id] = s
+	return pomomo.ExistingSessionSettingsRecord{SessionSettingsRecord: s}, nil
+}
+
+func (r *fakeSessionRepo) GetSessions(_ context.Context, filter pomomo.SessionFilter) ([]pomomo.ExistingSessionRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var sessions []pomomo.ExistingSessionRecord
+	for id, s := range r.sessions {
+		if len(filter.Statuses) == 0 || slices.Contains(filter.Statuses, s.Status) {
+			sessions = append(sessions, pomomo.ExistingSessionRecord{ExistingRecord: pomomo.ExistingRecord[pomomo.SessionID]{ID: id}, SessionRecord: s})
+		}
+	}
+	return sessions, nil
+}
+
+func (r *fakeSessionRepo) GetSettings(_ context.Context, id pomomo.SessionID) (pomomo.ExistingSessionSettingsRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.settings[id]
+	if !ok {
+		return pomomo.ExistingSessionSettingsRecord{}, sqlite.ErrNotFound
+	}
+	return pomomo.ExistingSessionSettingsRecord{SessionSettingsRecord: s}, nil
+}
+
+func (r *fakeSessionRepo) session(id pomomo.SessionID) pomomo.SessionRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.sessions[id]
+}
+
+func newTestSessionManager(t *testing.T) *sessionManager {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	m := NewSessionManager(ctx, newFakeSessionRepo(), nil, fakeTransactor{}).(*sessionManager)
+	t.Cleanup(func() {
+		cancel()
+		m.wg.Wait()
+	})
+	return m
+}
+
+func testSettings() pomomo.SessionSettingsRecord {
+	return pomomo.SessionSettingsRecord{
+		Pomodoro:   25 * time.Minute,
+		ShortBreak: 5 * time.Minute,
+		LongBreak:  15 * time.Minute,
+		Intervals:  4,
+	}
+}
+
+func TestSessionManagerRemovedSession(t *testing.T) {
+	m := newTestSessionManager(t)
+	s, err := m.StartSession(context.Background(), startSessionRequest{
+		guildID: "g1", textCID: "t1", voiceCID: "v1", messageID: "m1", settings: testSettings(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.EndSession(context.Background(), s.Record.TextCID); err != nil {
+		t.Fatal(err)
+	}
+
+	// the update loop can race the session's removal
+	transitionAt, warnAt, paused := m.nextTimers(s.Record.TextCID)
+	if !transitionAt.IsZero() || !warnAt.IsZero() || paused {
+		t.Errorf("nextTimers() = %v, %v, %v, want zero times", transitionAt, warnAt, paused)
+	}
+}
+
+// warning is a session passed to the interval warning hook with the hook's context
+type warning struct {
+	models.Session
+	ctx context.Context
+}
+
+// startWarningSession starts a session that warns warning before each interval ends and returns its warnings
+func startWarningSession(t *testing.T, m *sessionManager, settings pomomo.SessionSettingsRecord) (models.Session, <-chan warning) {
+	t.Helper()
+	warnings := make(chan warning, 10)
+	m.OnIntervalWarning(func(ctx context.Context, s models.Session) {
+		warnings <- warning{s, ctx}
+	})
+	s, err := m.StartSession(context.Background(), startSessionRequest{
+		guildID: "g1", textCID: "t1", voiceCID: "v1", messageID: "m1", settings: settings,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the update loop moves the new session into its first pomodoro
+	deadline := time.Now().Add(time.Second)
+	for s.Record.CurrentInterval != pomomo.PomodoroInterval {
+		if time.Now().After(deadline) {
+			t.Fatal("session didn't start its first pomodoro")
+		}
+		time.Sleep(time.Millisecond)
+		if s, err = m.GetSession(s.Record.TextCID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s, warnings
+}
+
+func TestSessionManagerIntervalWarning(t *testing.T) {
+	m := newTestSessionManager(t)
+	settings := testSettings()
+	settings.Pomodoro = 600 * time.Millisecond
+	settings.Warning = 300 * time.Millisecond
+	s, warnings := startWarningSession(t, m, settings)
+
+	select {
+	case w := <-warnings:
+		if w.Record.CurrentInterval != pomomo.PomodoroInterval {
+			t.Errorf("warned during %v, want the pomodoro", w.Record.CurrentInterval)
+		}
+		want := s.Record.IntervalStartedAt.Add(settings.Pomodoro - settings.Warning)
+		if late := time.Since(want); late < 0 || late > 150*time.Millisecond {
+			t.Errorf("warned %v after the warning time, want within 150ms", late)
+		}
+	case <-time.After(settings.Pomodoro):
+		t.Fatal("no warning before the pomodoro ended")
+	}
+
+	// once per interval
+	select {
+	case w := <-warnings:
+		if w.Record.CurrentInterval == pomomo.PomodoroInterval {
+			t.Error("warned twice during the pomodoro")
+		}
+	case <-time.After(settings.Warning + 100*time.Millisecond):
+	}
+}
+
+func TestSessionManagerIntervalWarningCancelled(t *testing.T) {
+	settings := testSettings()
+	settings.Pomodoro = 600 * time.Millisecond
+	settings.Warning = 300 * time.Millisecond
+
+	t.Run("skip", func(t *testing.T) {
+		m := newTestSessionManager(t)
+		s, warnings := startWarningSession(t, m, settings)
+		if _, err := m.SkipInterval(context.Background(), s.Record.TextCID); err != nil {
+			t.Fatal(err)
+		}
+		// the short break's warning is minutes away
+		select {
+		case w := <-warnings:
+			t.Errorf("warned during %v after skipping the pomodoro", w.Record.CurrentInterval)
+		case <-time.After(settings.Pomodoro):
+		}
+	})
+
+	t.Run("pause", func(t *testing.T) {
+		m := newTestSessionManager(t)
+		s, warnings := startWarningSession(t, m, settings)
+		if _, err := m.PauseSession(context.Background(), s.Record.TextCID); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-warnings:
+			t.Error("warned while paused")
+		case <-time.After(settings.Pomodoro):
+		}
+
+		// rescheduled from the time remaining on resume
+		resumed, err := m.ResumeSession(context.Background(), s.Record.TextCID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case w := <-warnings:
+			want := resumed.WarningAt()
+			if late := time.Since(want); late < 0 || late > 150*time.Millisecond {
+				t.Errorf("warned %v after the warning time, want within 150ms", late)
+			}
+			if w.Record.CurrentInterval != pomomo.PomodoroInterval {
+				t.Errorf("warned during %v, want the pomodoro", w.Record.CurrentInterval)
+			}
+		case <-time.After(settings.Pomodoro):
+			t.Fatal("no warning after resuming")
+		}
+	})
+}
+
+func TestSessionManagerIntervalWarningStale(t *testing.T) {
+	settings := testSettings()
+	settings.Pomodoro = 600 * time.Millisecond
+	settings.Warning = 300 * time.Millisecond
+
+	tests := []struct {
+		name string
+		// change makes the warning stale, returning when it did
+		change func(m *sessionManager, s models.Session) (time.Time, error)
+	}{
+		{"interval ended", func(_ *sessionManager, s models.Session) (time.Time, error) {
+			return s.Record.IntervalStartedAt.Add(settings.Pomodoro), nil
+		}},
+		{"skipped", func(m *sessionManager, s models.Session) (time.Time, error) {
+			_, err := m.SkipInterval(context.Background(), s.Record.TextCID)
+			return time.Now(), err
+		}},
+		{"paused", func(m *sessionManager, s models.Session) (time.Time, error) {
+			_, err := m.PauseSession(context.Background(), s.Record.TextCID)
+			return time.Now(), err
+		}},
+		{"session ended", func(m *sessionManager, s models.Session) (time.Time, error) {
+			_, err := m.EndSession(context.Background(), s.Record.TextCID)
+			return time.Now(), err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestSessionManager(t)
+			s, warnings := startWarningSession(t, m, settings)
+			var w warning
+			select {
+			case w = <-warnings:
+			case <-time.After(settings.Pomodoro):
+				t.Fatal("no warning before the pomodoro ended")
+			}
+			if w.ctx.Err() != nil {
+				t.Fatal("warning context is done while the warning is current")
+			}
+
+			staleAt, err := tt.change(m, s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case <-w.ctx.Done():
+				if late := time.Since(staleAt); late < 0 || late > 150*time.Millisecond {
+					t.Errorf("warning context done %v after the warning was stale, want within 150ms", late)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("warning context isn't done once the warning is stale")
+			}
+		})
+	}
+}
+
+func TestSessionManagerWarnInterval(t *testing.T) {
+	m := newTestSessionManager(t)
+	settings := testSettings()
+	settings.Warning = time.Minute
+	s, warnings := startWarningSession(t, m, settings)
+
+	if m.warnInterval(context.Background(), s.Record.TextCID, s.WarningAt().Add(-time.Second)) {
+		t.Error("warnInterval() = true for a stale warning time")
+	}
+	if !m.warnInterval(context.Background(), s.Record.TextCID, s.WarningAt()) {
+		t.Error("warnInterval() = false for the current warning time")
+	}
+	select {
+	case <-warnings:
+	case <-time.After(time.Second):
+		t.Error("warning hook wasn't called")
+	}
+
+	if _, err := m.EndSession(context.Background(), s.Record.TextCID); err != nil {
+		t.Fatal(err)
+	}
+	if m.warnInterval(context.Background(), s.Record.TextCID, s.WarningAt()) {
+		t.Error("warnInterval() = true for a removed session")
+	}
+	select {
+	case <-warnings:
+		t.Error("warning hook was called for a removed session")
+	default:
+	}
+}
+
+// onEnded returns the sessions the manager ends
+func onEnded(m *sessionManager) <-chan models.Session {
+	ended := make(chan models.Session, 1)
+	m.AfterUpdate(func(_ context.Context, _, curr models.Session) {
+		if curr.Record.Status == pomomo.SessionEnded {
+			ended <- curr
+		}
+	})
+	return ended
+}
+
+func TestSessionManagerEndedSessionUncached(t *testing.T) {
+	m := newTestSessionManager(t)
+	// the voice connection is only dropped once no other session in the guild could be using it
+	guildSessionCnts := make(chan int, 2)
+	m.AfterUpdate(func(_ context.Context, _, curr models.Session) {
+		if curr.Record.Status == pomomo.SessionEnded {
+			guildSessionCnts <- m.GuildSessionCnt(curr.Record.GuildID)
+		}
+	})
+	for _, cid := range []string{"1", "2"} {
+		_, err := m.StartSession(context.Background(), startSessionRequest{
+			guildID: "g1", textCID: "t" + cid, voiceCID: "v" + cid, messageID: "m" + cid, settings: testSettings(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for cid, want := range []int{1, 0} {
+		textCID := pomomo.TextChannelID(fmt.Sprintf("t%d", cid+1))
+		if _, err := m.EndSession(context.Background(), textCID); err != nil {
+			t.Fatal(err)
+		}
+		if got := <-guildSessionCnts; got != want {
+			t.Errorf("GuildSessionCnt() after ending %v = %d, want %d", textCID, got, want)
+		}
+	}
+}
+
+func TestSessionManagerPausedSessionEnds(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings func(*pomomo.SessionSettingsRecord)
+	}{
+		{"expired", func(s *pomomo.SessionSettingsRecord) { s.MaxDuration = 300 * time.Millisecond }},
+		{"end time", func(s *pomomo.SessionSettingsRecord) { s.GoalUntil = time.Now().Add(300 * time.Millisecond) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestSessionManager(t)
+			ended := onEnded(m)
+			settings := testSettings()
+			tt.settings(&settings)
+			s, err := m.StartSession(context.Background(), startSessionRequest{
+				guildID: "g1", textCID: "t1", voiceCID: "v1", messageID: "m1", settings: settings,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := m.PauseSession(context.Background(), s.Record.TextCID); err != nil {
+				t.Fatal(err)
+			}
+
+			select {
+			case e := <-ended:
+				if late := time.Since(e.NextTransitionAt()); late > 150*time.Millisecond {
+					t.Errorf("ended %v late", late)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("paused session didn't end")
+			}
+			if m.HasSession(string(s.Record.TextCID)) {
+				t.Error("ended session is still cached")
+			}
+		})
+	}
+
+	t.Run("goal reached while paused", func(t *testing.T) {
+		defer func(rate time.Duration) { pausedTickRate = rate }(pausedTickRate)
+		pausedTickRate = 100 * time.Millisecond
+		m := newTestSessionManager(t)
+		ended := onEnded(m)
+		settings := testSettings()
+		settings.Pomodoro = 100 * time.Millisecond
+		s, err := m.StartSession(context.Background(), startSessionRequest{
+			guildID: "g1", textCID: "t1", voiceCID: "v1", messageID: "m1", settings: settings,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		deadline := time.Now().Add(time.Second)
+		for s.Record.Stats.CompletedPomodoros == 0 {
+			if time.Now().After(deadline) {
+				t.Fatal("session didn't complete its first pomodoro")
+			}
+			time.Sleep(time.Millisecond)
+			if s, err = m.GetSession(s.Record.TextCID); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := m.PauseSession(context.Background(), s.Record.TextCID); err != nil {
+			t.Fatal(err)
+		}
+
+		// lowering the goal to what's been done has no transition to wait for
+		settings.GoalRounds = 1
+		if _, err := m.UpdateSettings(context.Background(), s.Record.TextCID, settings, false); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-ended:
+		case <-time.After(time.Second):
+			t.Fatal("paused session didn't end")
+		}
+	})
+
+	t.Run("resumed", func(t *testing.T) {
+		m := newTestSessionManager(t)
+		ended := onEnded(m)
+		settings := testSettings()
+		settings.MaxDuration = time.Hour
+		s, err := m.StartSession(context.Background(), startSessionRequest{
+			guildID: "g1", textCID: "t1", voiceCID: "v1", messageID: "m1", settings: settings,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := m.PauseSession(context.Background(), s.Record.TextCID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := m.ResumeSession(context.Background(), s.Record.TextCID); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case e := <-ended:
+			t.Fatalf("ended the resumed session with %v left", e.TimeRemaining())
+		case <-time.After(200 * time.Millisecond):
+		}
+		if got, err := m.GetSession(s.Record.TextCID); err != nil || got.Record.Status != pomomo.SessionRunning {
+			t.Errorf("GetSession() = %v, %v, want a running session", got.Record.Status, err)
+		}
+	})
+}
+
+func TestLobbyDeserted(t *testing.T) {
+	lobby := models.NewSession("s1", "g1", "t1", "v1", "m1", testSettings())
+	lobby.Record.HostUserID = "host"
+	lobby.OpenLobby(time.Minute)
+	lobby.Record.IntervalStartedAt = time.Now().Add(-2 * time.Minute)
+	expired := lobby
+	expired.StartFromLobby()
+	// the host pressed start now with the countdown still running
+	earlyLobby := lobby
+	earlyLobby.Record.IntervalStartedAt = time.Now()
+	early := earlyLobby
+	early.StartFromLobby()
+	hostless := lobby
+	hostless.Record.HostUserID = ""
+	hostlessExpired := expired
+	hostlessExpired.Record.HostUserID = ""
+
+	participants := func(uids ...string) []models.Participant {
+		var ps []models.Participant
+		for _, uid := range uids {
+			ps = append(ps, models.Participant{Record: pomomo.ParticipantRecord{UserID: uid, VoiceCID: "v1"}})
+		}
+		return ps
+	}
+	tests := []struct {
+		name         string
+		before, curr models.Session
+		participants []models.Participant
+		want         bool
+	}{
+		// the host is joined when the session starts so they don't count
+		{"only the host", lobby, expired, participants("host"), true},
+		{"host left", lobby, expired, nil, true},
+		{"joined", lobby, expired, participants("host", "u1"), false},
+		{"joined after host left", lobby, expired, participants("u1"), false},
+		{"started early", earlyLobby, early, participants("host"), false},
+		{"hostless", hostless, hostlessExpired, nil, true},
+		{"hostless joined", hostless, hostlessExpired, participants("u1"), false},
+		{"still in lobby", lobby, lobby, participants("host"), false},
+		{"already running", expired, expired, participants("host"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lobbyDeserted(tt.before, tt.curr, tt.participants); got != tt.want {
+				t.Errorf("lobbyDeserted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionManagerRestoreStaleSession(t *testing.T) {
+	m := newTestSessionManager(t)
+	repo := m.repo.(*fakeSessionRepo)
+	intervalStart := time.Now().Add(-3 * time.Hour).Truncate(time.Second)
+	stale := pomomo.SessionRecord{
+		GuildID: "g1", TextCID: "t1", VoiceCID: "v1", MessageID: "m1",
+		Status:               pomomo.SessionRunning,
+		CurrentInterval:      pomomo.PomodoroInterval,
+		IntervalStartedAt:    intervalStart,
+		TimeRemainingAtStart: 25 * time.Minute,
+	}
+	repo.sessions["s1"] = stale
+	repo.settings["s1"] = testSettings()
+
+	var ended models.Session
+	m.AfterUpdate(func(_ context.Context, _, curr models.Session) {
+		if curr.Record.Status == pomomo.SessionEnded {
+			ended = curr
+		}
+	})
+	if err := m.RestoreSessions(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if m.HasSession("t1") {
+		t.Error("restored a stale session")
+	}
+	// the downtime after the interval ran out isn't part of the session
+	want := intervalStart.Add(25 * time.Minute)
+	if got := repo.session("s1"); got.Status != pomomo.SessionEnded || !got.EndedAt.Equal(want) {
+		t.Errorf("stale session is %v ended at %v, want ended at %v", got.Status, got.EndedAt, want)
+	}
+	if !ended.Record.EndedAt.Equal(want) {
+		t.Errorf("ended session passed to hooks ended at %v, want %v", ended.Record.EndedAt, want)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/Thiht/transactor"
+	"github.com/charmbracelet/log"
+)
+
+var pruneTickRate = time.Hour
+
+// sessionPruner deletes ended sessions once they fall outside of the retention window
+type sessionPruner struct {
+	repo      SessionRepo
+	tx        transactor.Transactor
+	retention time.Duration
+}
+
+func newSessionPruner(repo SessionRepo, tx transactor.Transactor, retention time.Duration) *sessionPruner {
+	return &sessionPruner{
+		repo:      repo,
+		tx:        tx,
+		retention: retention,
+	}
+}
+
+// Run prunes immediately and then every pruneTickRate until ctx is done
+func (p *sessionPruner) Run(ctx context.Context) {
+	ticker := time.NewTicker(pruneTickRate)
+	defer ticker.Stop()
+	for {
+		if err := p.Prune(ctx); err != nil {
+			log.Error("failed to prune ended sessions", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *sessionPruner) Prune(ctx context.Context) error {
+	var cnt int64
+	err := p.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		cnt, err = p.repo.DeleteEndedSessions(ctx, time.Now().Add(-p.retention))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if cnt > 0 {
+		log.Info("pruned ended sessions", "count", cnt, "retention", p.retention)
+	}
+	return nil
+}
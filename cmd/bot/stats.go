@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/benjamonnguyen/pomomo-go"
+	"github.com/benjamonnguyen/pomomo-go/cmd/bot/models"
+	"github.com/charmbracelet/log"
+)
+
+type ParticipantStatsRepo interface {
+	AddParticipantStats(context.Context, pomomo.ParticipantStatsRecord) error
+	GetUserStats(ctx context.Context, guildID, userID string, since time.Time) (pomomo.StatsSummary, error)
+	GetGuildStats(ctx context.Context, guildID string, since time.Time) (pomomo.StatsSummary, error)
+	GetLeaderboard(ctx context.Context, guildID string, metric pomomo.LeaderboardMetric, since time.Time, limit int) ([]pomomo.LeaderboardEntry, error)
+
+	// streaks
+	UpdateStreak(ctx context.Context, guildID, userID string, day time.Time) error
+	GetStreak(ctx context.Context, guildID, userID string) (pomomo.ParticipantStreakRecord, error)
+	GetStreakLeaderboard(ctx context.Context, guildID string, limit int) ([]pomomo.ParticipantStreakRecord, error)
+
+	// session participation
+	RecordSessionJoin(ctx context.Context, sessionID pomomo.SessionID, userID string, at time.Time) error
+	RecordSessionLeave(ctx context.Context, sessionID pomomo.SessionID, userID string, at time.Time) error
+	AddSessionFocus(ctx context.Context, sessionID pomomo.SessionID, userID string, focus time.Duration) error
+	GetSessionParticipantStats(ctx context.Context, sessionID pomomo.SessionID) ([]pomomo.SessionParticipantStatsRecord, error)
+}
+
+// StatsRecorder persists participant stats as sessions progress
+type StatsRecorder interface {
+	// RecordUpdate credits participants for the interval that ended between before and curr
+	RecordUpdate(ctx context.Context, participants []models.Participant, before, curr models.Session)
+	// RecordJoin tracks the participant in the session's history
+	RecordJoin(ctx context.Context, p models.Participant)
+	// RecordLeave credits focus time for a participant leaving mid-pomodoro
+	RecordLeave(ctx context.Context, p models.Participant, s models.Session)
+}
+
+var _ StatsRecorder = (*statsRecorder)(nil)
+
+type statsRecorder struct {
+	repo ParticipantStatsRepo
+	l    log.Logger
+}
+
+func NewStatsRecorder(repo ParticipantStatsRepo, l log.Logger) StatsRecorder {
+	return &statsRecorder{
+		repo: repo,
+		l:    l,
+	}
+}
+
+func (r *statsRecorder) RecordUpdate(ctx context.Context, participants []models.Participant, before, curr models.Session) {
+	if before.ID == "" {
+		// session was just started or restored
+		return
+	}
+	intervalChanged := before.Record.CurrentInterval != curr.Record.CurrentInterval
+	if !intervalChanged && before.Record.Status == curr.Record.Status {
+		return
+	}
+
+	// interval transitions are backdated to when the interval actually ended
+	at := time.Now()
+	if intervalChanged && !curr.Record.IntervalStartedAt.IsZero() && curr.Record.IntervalStartedAt.Before(at) {
+		at = curr.Record.IntervalStartedAt
+	}
+	skipped := curr.Record.Stats.Skips > before.Record.Stats.Skips
+	completedPomodoro := curr.Record.Stats.CompletedPomodoros > before.Record.Stats.CompletedPomodoros
+	tookBreak := intervalChanged && !skipped && before.Record.CurrentInterval.IsBreak()
+
+	for _, p := range participants {
+		stats := pomomo.ParticipantStatsRecord{
+			GuildID:   p.Record.GuildID,
+			UserID:    p.Record.UserID,
+			Day:       pomomo.StatsDay(at),
+			FocusTime: focusTime(p, before, at),
+		}
+		if completedPomodoro {
+			stats.CompletedPomodoros = 1
+			if err := r.repo.UpdateStreak(ctx, stats.GuildID, stats.UserID, stats.Day); err != nil {
+				r.l.Error("failed to update streak", "gid", stats.GuildID, "uid", stats.UserID, "err", err)
+			}
+		}
+		if tookBreak {
+			stats.BreaksTaken = 1
+		}
+		r.record(ctx, p.Record.SessionID, stats, at)
+	}
+}
+
+func (r *statsRecorder) RecordJoin(ctx context.Context, p models.Participant) {
+	if err := r.repo.RecordSessionJoin(ctx, p.Record.SessionID, p.Record.UserID, p.StartedIntervalAt); err != nil {
+		r.l.Error("failed to record session join", "sid", p.Record.SessionID, "uid", p.Record.UserID, "err", err)
+	}
+}
+
+func (r *statsRecorder) RecordLeave(ctx context.Context, p models.Participant, s models.Session) {
+	at := time.Now()
+	r.record(ctx, p.Record.SessionID, pomomo.ParticipantStatsRecord{
+		GuildID:   p.Record.GuildID,
+		UserID:    p.Record.UserID,
+		Day:       pomomo.StatsDay(at),
+		FocusTime: focusTime(p, s, at),
+	}, at)
+	if err := r.repo.RecordSessionLeave(ctx, p.Record.SessionID, p.Record.UserID, at); err != nil {
+		r.l.Error("failed to record session leave", "sid", p.Record.SessionID, "uid", p.Record.UserID, "err", err)
+	}
+}
+
+// record adds stats that were earned up until at
+func (r *statsRecorder) record(ctx context.Context, sid pomomo.SessionID, stats pomomo.ParticipantStatsRecord, at time.Time) {
+	if stats.FocusTime <= 0 && stats.CompletedPomodoros == 0 && stats.BreaksTaken == 0 {
+		return
+	}
+	for _, dayStats := range splitByDay(stats, at) {
+		if err := r.repo.AddParticipantStats(ctx, dayStats); err != nil {
+			r.l.Error("failed to record participant stats", "gid", dayStats.GuildID, "uid", dayStats.UserID, "day", dayStats.Day, "err", err)
+		}
+	}
+	if stats.FocusTime > 0 {
+		if err := r.repo.AddSessionFocus(ctx, sid, stats.UserID, stats.FocusTime); err != nil {
+			r.l.Error("failed to record session focus", "sid", sid, "uid", stats.UserID, "err", err)
+		}
+	}
+}
+
+// splitByDay credits focus time that ended at at to each UTC day it was spent in, latest day first.
+// Pomodoros and breaks count towards the day of at.
+func splitByDay(stats pomomo.ParticipantStatsRecord, at time.Time) []pomomo.ParticipantStatsRecord {
+	focus := stats.FocusTime
+	day := pomomo.StatsDay(at)
+	stats.Day = day
+	stats.FocusTime = min(focus, at.Sub(day))
+	var split []pomomo.ParticipantStatsRecord
+	if stats.FocusTime > 0 || stats.CompletedPomodoros > 0 || stats.BreaksTaken > 0 {
+		// empty rows would count as active days
+		split = append(split, stats)
+	}
+	for focus -= stats.FocusTime; focus > 0; focus -= 24 * time.Hour {
+		day = day.AddDate(0, 0, -1)
+		split = append(split, pomomo.ParticipantStatsRecord{
+			GuildID:   stats.GuildID,
+			UserID:    stats.UserID,
+			Day:       day,
+			FocusTime: min(focus, 24*time.Hour),
+		})
+	}
+	return split
+}
+
+// focusTime returns how long p has focused in the session's current interval up until at
+func focusTime(p models.Participant, s models.Session, at time.Time) time.Duration {
+	if s.Record.Status != pomomo.SessionRunning || s.Record.CurrentInterval != pomomo.PomodoroInterval {
+		return 0
+	}
+	start := s.Record.IntervalStartedAt
+	if p.StartedIntervalAt.After(start) {
+		// joined mid-interval
+		start = p.StartedIntervalAt
+	}
+	return max(at.Sub(start), 0)
+}
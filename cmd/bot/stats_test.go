@@ -0,0 +1,56 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/benjamonnguyen/pomomo-go"
+)
+
+func TestSplitByDay(t *testing.T) {
+	day := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	stats := func(d time.Time, focus time.Duration, pomodoros, breaks int) pomomo.ParticipantStatsRecord {
+		return pomomo.ParticipantStatsRecord{GuildID: "g1", UserID: "u1", Day: d, FocusTime: focus, CompletedPomodoros: pomodoros, BreaksTaken: breaks}
+	}
+	tests := []struct {
+		name  string
+		stats pomomo.ParticipantStatsRecord
+		at    time.Time
+		want  []pomomo.ParticipantStatsRecord
+	}{
+		{"within day", stats(day, 25*time.Minute, 1, 0), day.Add(12 * time.Hour),
+			[]pomomo.ParticipantStatsRecord{stats(day, 25*time.Minute, 1, 0)}},
+		{"up to midnight", stats(day, 25*time.Minute, 1, 0), day.Add(25 * time.Minute),
+			[]pomomo.ParticipantStatsRecord{stats(day, 25*time.Minute, 1, 0)}},
+		// the pomodoro counts towards the day it was completed in
+		{"across midnight", stats(day, 25*time.Minute, 1, 1), day.Add(10 * time.Minute),
+			[]pomomo.ParticipantStatsRecord{stats(day, 10*time.Minute, 1, 1), stats(day.AddDate(0, 0, -1), 15*time.Minute, 0, 0)}},
+		{"ended at midnight", stats(day, 25*time.Minute, 1, 0), day,
+			[]pomomo.ParticipantStatsRecord{stats(day, 0, 1, 0), stats(day.AddDate(0, 0, -1), 25*time.Minute, 0, 0)}},
+		{"ended at midnight without a pomodoro", stats(day, 25*time.Minute, 0, 0), day,
+			[]pomomo.ParticipantStatsRecord{stats(day.AddDate(0, 0, -1), 25*time.Minute, 0, 0)}},
+		{"across days", stats(day, 50*time.Hour, 0, 0), day.Add(time.Hour),
+			[]pomomo.ParticipantStatsRecord{
+				stats(day, time.Hour, 0, 0),
+				stats(day.AddDate(0, 0, -1), 24*time.Hour, 0, 0),
+				stats(day.AddDate(0, 0, -2), 24*time.Hour, 0, 0),
+				stats(day.AddDate(0, 0, -3), time.Hour, 0, 0),
+			}},
+		{"no focus", stats(day, 0, 0, 1), day.Add(time.Hour),
+			[]pomomo.ParticipantStatsRecord{stats(day, 0, 0, 1)}},
+		{"local time", stats(day, 25*time.Minute, 1, 0), day.Add(10 * time.Minute).In(time.FixedZone("UTC-5", -5*60*60)),
+			[]pomomo.ParticipantStatsRecord{stats(day, 10*time.Minute, 1, 0), stats(day.AddDate(0, 0, -1), 15*time.Minute, 0, 0)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitByDay(tt.stats, tt.at)
+			if !slices.EqualFunc(got, tt.want, func(a, b pomomo.ParticipantStatsRecord) bool {
+				return a.Day.Equal(b.Day) && a.FocusTime == b.FocusTime && a.CompletedPomodoros == b.CompletedPomodoros &&
+					a.BreaksTaken == b.BreaksTaken && a.GuildID == b.GuildID && a.UserID == b.UserID
+			}) {
+				t.Errorf("splitByDay() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
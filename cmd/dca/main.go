@@ -0,0 +1,57 @@
+// Command dca converts WAV and Ogg Opus files into the DCA frames bundled in cmd/bot/sounds, e.g.
+//
+//	go run ./cmd/dca -o cmd/bot/sounds/pomodoro.dca pomodoro.wav
+//
+// WAV files are encoded as is. Ogg Opus must already be encoded with 20ms frames, which is the default
+// for opusenc and ffmpeg's libopus.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/benjamonnguyen/pomomo-go/dca"
+	"github.com/charmbracelet/log"
+)
+
+func main() {
+	out := flag.String("o", "", "output file (Default: the input file with a .dca extension)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [-o out.dca] <in.wav|in.ogg|in.opus|in.dca>\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	in := flag.Arg(0)
+	if *out == "" {
+		*out = strings.TrimSuffix(in, filepath.Ext(in)) + ".dca"
+	}
+
+	f, err := os.Open(in)
+	if err != nil {
+		log.Fatal(err)
+	}
+	frames, err := dca.ReadFile(in, f)
+	_ = f.Close()
+	if err != nil {
+		log.Fatal("failed to read frames", "in", in, "err", err)
+	}
+	duration, err := dca.Validate(frames)
+	if err != nil {
+		log.Fatal("invalid audio", "in", in, "err", err)
+	}
+	data, err := dca.Marshal(frames)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, data, 0644); err != nil {
+		log.Fatal(err)
+	}
+	log.Info("wrote frames", "out", *out, "frames", len(frames), "duration", duration)
+}
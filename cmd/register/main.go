@@ -50,6 +50,22 @@ func main() {
 
 	cmds := []*discordgo.ApplicationCommand{
 		&pomomo.StartCommand,
+		&pomomo.PauseCommand,
+		&pomomo.ResumeCommand,
+		&pomomo.SkipCommand,
+		&pomomo.BreakCommand,
+		&pomomo.EndCommand,
+		&pomomo.JoinCommand,
+		&pomomo.LeaveCommand,
+		&pomomo.StatusCommand,
+		&pomomo.SettingsCommand,
+		&pomomo.TransferCommand,
+		&pomomo.ConfigCommand,
+		&pomomo.PresetCommand,
+		&pomomo.ScheduleCommand,
+		&pomomo.SoundCommand,
+		&pomomo.StatsCommand,
+		&pomomo.LeaderboardCommand,
 	}
 
 	created, err := bot.ApplicationCommandBulkOverwrite(app.ID, "", cmds)
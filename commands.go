@@ -11,6 +11,70 @@ const (
 	IntervalsOption  = "intervals"
 	NoDeafenOption   = "no_deafen"
 	NoMuteOption     = "no_mute"
+	RangeOption      = "range"
+	UserOption       = "user"
+	MetricOption     = "metric"
+	RoleOption       = "role"
+	MinutesOption    = "minutes"
+	ChannelOption    = "channel"
+	PresetOption     = "preset"
+	NameOption       = "name"
+	ScopeOption      = "scope"
+	SequenceOption   = "sequence"
+	FlowtimeOption   = "flowtime"
+	BreakRatioOption = "break_ratio"
+	DaysOption       = "days"
+	TimeOption       = "time"
+	VoiceOption      = "voice_channel"
+	PingOption       = "ping"
+	ScheduleOption   = "schedule"
+	TimezoneOption   = "timezone"
+	LobbyOption      = "lobby"
+	RoundsOption     = "rounds"
+	UntilOption      = "until"
+	WarningOption    = "warning"
+	AmbientOption    = "ambient"
+	VolumeOption     = "volume"
+	SoundOption      = "sound"
+	FileOption       = "file"
+)
+
+const (
+	ConfigModeratorRoleSubcommand   = "moderator_role"
+	ConfigDefaultsSubcommand        = "defaults"
+	ConfigMaxLengthSubcommand       = "max_length"
+	ConfigAllowChannelSubcommand    = "allow_channel"
+	ConfigDisallowChannelSubcommand = "disallow_channel"
+	ConfigTimezoneSubcommand        = "timezone"
+	ConfigShowSubcommand            = "show"
+)
+
+const (
+	ScheduleCreateSubcommand = "create"
+	ScheduleDeleteSubcommand = "delete"
+	ScheduleListSubcommand   = "list"
+)
+
+const (
+	SoundSetSubcommand   = "set"
+	SoundResetSubcommand = "reset"
+)
+
+const (
+	PresetSaveSubcommand   = "save"
+	PresetDeleteSubcommand = "delete"
+	PresetListSubcommand   = "list"
+)
+
+const (
+	PresetScopeUser  = "me"
+	PresetScopeGuild = "server"
+)
+
+const (
+	StatsMeSubcommand     = "me"
+	StatsUserSubcommand   = "user"
+	StatsServerSubcommand = "server"
 )
 
 func float64Ptr(f float64) *float64 {
@@ -21,43 +85,568 @@ var StartCommand = discordgo.ApplicationCommand{
 	Name:        "start",
 	Description: "start pomodoro session",
 	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:         discordgo.ApplicationCommandOptionString,
+			Name:         PresetOption,
+			Description:  "saved or built-in preset - other options override it",
+			Autocomplete: true,
+		},
 		{
 			Type:        discordgo.ApplicationCommandOptionInteger,
 			Name:        PomodoroOption,
-			Description: "pomodoro duration in minutes (Default: 20)",
+			Description: "pomodoro duration in minutes (Default: server default)",
 			MinValue:    float64Ptr(0),
 			MaxValue:    240,
 		},
 		{
 			Type:        discordgo.ApplicationCommandOptionInteger,
 			Name:        ShortBreakOption,
-			Description: "short break duration in minutes (Default: 5)",
+			Description: "short break duration in minutes (Default: server default)",
 			MinValue:    float64Ptr(0),
 			MaxValue:    240,
 		},
 		{
 			Type:        discordgo.ApplicationCommandOptionInteger,
 			Name:        LongBreakOption,
-			Description: "long break duration in minutes (Default: 15)",
+			Description: "long break duration in minutes (Default: server default)",
 			MinValue:    float64Ptr(0),
 			MaxValue:    240,
 		},
 		{
 			Type:        discordgo.ApplicationCommandOptionInteger,
 			Name:        IntervalsOption,
-			Description: "number of intervals between long breaks (Default: 4)",
+			Description: "number of intervals between long breaks (Default: server default)",
 			MinValue:    float64Ptr(1),
 			MaxValue:    20,
 		},
 		{
 			Type:        discordgo.ApplicationCommandOptionBoolean,
 			Name:        NoDeafenOption,
-			Description: "participants will not be deafened during pomodoro intervals (Default: false)",
+			Description: "participants will not be deafened during pomodoro intervals (Default: server default)",
 		},
 		{
 			Type:        discordgo.ApplicationCommandOptionBoolean,
 			Name:        NoMuteOption,
-			Description: "participants will not be muted during pomodoro intervals (Default: false)",
+			Description: "participants will not be muted during pomodoro intervals (Default: server default)",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        SequenceOption,
+			Description: "custom interval order, e.g. \"warmup 10, focus 50, break 10, focus 50, long 30\"",
+			MaxLength:   400,
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionBoolean,
+			Name:        FlowtimeOption,
+			Description: "pomodoros count up until you take a break instead of running on a timer",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        BreakRatioOption,
+			Description: "flowtime break length as a percentage of the focus before it (Default: 20)",
+			MinValue:    float64Ptr(1),
+			MaxValue:    100,
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        LobbyOption,
+			Description: "minutes to wait for others to join before the first pomodoro",
+			MinValue:    float64Ptr(1),
+			MaxValue:    60,
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        RoundsOption,
+			Description: "end the session after this many pomodoros",
+			MinValue:    float64Ptr(1),
+			MaxValue:    50,
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        UntilOption,
+			Description: "end the session at this 24-hour time in the server's timezone, e.g. 17:30",
+			MaxLength:   5,
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        WarningOption,
+			Description: "minutes before each interval ends to play a warning",
+			MinValue:    float64Ptr(1),
+			MaxValue:    15,
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionBoolean,
+			Name:        AmbientOption,
+			Description: "loop the server's ambient track (see /sound) during pomodoro intervals",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        VolumeOption,
+			Description: "alert volume as a percent (Default: server default)",
+			MinValue:    float64Ptr(MinVolume),
+			MaxValue:    MaxVolume,
+		},
+	},
+}
+
+var PauseCommand = discordgo.ApplicationCommand{
+	Name:        "pause",
+	Description: "pause the session in this channel",
+}
+
+var ResumeCommand = discordgo.ApplicationCommand{
+	Name:        "resume",
+	Description: "resume the paused session in this channel",
+}
+
+var SkipCommand = discordgo.ApplicationCommand{
+	Name:        "skip",
+	Description: "skip the current interval of the session in this channel",
+}
+
+var BreakCommand = discordgo.ApplicationCommand{
+	Name:        "break",
+	Description: "end the flowtime pomodoro of the session in this channel and take a break",
+}
+
+var EndCommand = discordgo.ApplicationCommand{
+	Name:        "end",
+	Description: "end the session in this channel",
+}
+
+var JoinCommand = discordgo.ApplicationCommand{
+	Name:        "join",
+	Description: "join the session in this channel",
+}
+
+var LeaveCommand = discordgo.ApplicationCommand{
+	Name:        "leave",
+	Description: "leave the session in this channel without leaving voice",
+}
+
+var StatusCommand = discordgo.ApplicationCommand{
+	Name:        "status",
+	Description: "show the current interval and time remaining of the session in this channel",
+}
+
+var SettingsCommand = discordgo.ApplicationCommand{
+	Name:        "settings",
+	Description: "show the settings of the session in this channel",
+}
+
+var TransferCommand = discordgo.ApplicationCommand{
+	Name:        "transfer",
+	Description: "make another participant the host of the session in this channel",
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionUser,
+			Name:        UserOption,
+			Description: "participant to make host",
+			Required:    true,
+		},
+	},
+}
+
+var manageGuildPermission int64 = discordgo.PermissionManageGuild
+
+var ConfigCommand = discordgo.ApplicationCommand{
+	Name:                     "config",
+	Description:              "configure pomomo for this server",
+	DefaultMemberPermissions: &manageGuildPermission,
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        ConfigModeratorRoleSubcommand,
+			Description: "set the role that can manage any session (omit role to clear)",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionRole,
+					Name:        RoleOption,
+					Description: "moderator role",
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        ConfigDefaultsSubcommand,
+			Description: "set the settings that /start uses when options are omitted",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        PomodoroOption,
+					Description: "default pomodoro duration in minutes",
+					MinValue:    float64Ptr(1),
+					MaxValue:    240,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        ShortBreakOption,
+					Description: "default short break duration in minutes",
+					MinValue:    float64Ptr(1),
+					MaxValue:    240,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        LongBreakOption,
+					Description: "default long break duration in minutes",
+					MinValue:    float64Ptr(1),
+					MaxValue:    240,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        IntervalsOption,
+					Description: "default number of intervals between long breaks",
+					MinValue:    float64Ptr(1),
+					MaxValue:    20,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        NoDeafenOption,
+					Description: "participants will not be deafened during pomodoro intervals by default",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        NoMuteOption,
+					Description: "participants will not be muted during pomodoro intervals by default",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        VolumeOption,
+					Description: "default alert volume as a percent",
+					MinValue:    float64Ptr(MinVolume),
+					MaxValue:    MaxVolume,
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        ConfigMaxLengthSubcommand,
+			Description: "set how long sessions can run before they're ended",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        MinutesOption,
+					Description: "max session length in minutes (0 for no limit)",
+					MinValue:    float64Ptr(0),
+					Required:    true,
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        ConfigAllowChannelSubcommand,
+			Description: "allow sessions in a channel - once any are allowed, sessions are limited to allowed channels",
+			Options:     []*discordgo.ApplicationCommandOption{configChannelOption},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        ConfigDisallowChannelSubcommand,
+			Description: "remove a channel from the allowed channels",
+			Options:     []*discordgo.ApplicationCommandOption{configChannelOption},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        ConfigTimezoneSubcommand,
+			Description: "set the timezone that schedules run in",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        TimezoneOption,
+					Description: "IANA timezone, e.g. America/New_York (Default: UTC)",
+					Required:    true,
+					MaxLength:   64,
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        ConfigShowSubcommand,
+			Description: "show this server's configuration",
+		},
+	},
+}
+
+var configChannelOption = &discordgo.ApplicationCommandOption{
+	Type:         discordgo.ApplicationCommandOptionChannel,
+	Name:         ChannelOption,
+	Description:  "text or voice channel",
+	ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText, discordgo.ChannelTypeGuildVoice},
+	Required:     true,
+}
+
+var presetScopeOption = &discordgo.ApplicationCommandOption{
+	Type:        discordgo.ApplicationCommandOptionString,
+	Name:        ScopeOption,
+	Description: "whose preset (Default: me)",
+	Choices: []*discordgo.ApplicationCommandOptionChoice{
+		{Name: "me", Value: PresetScopeUser},
+		{Name: "server", Value: PresetScopeGuild},
+	},
+}
+
+var PresetCommand = discordgo.ApplicationCommand{
+	Name:        "preset",
+	Description: "manage session presets",
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        PresetSaveSubcommand,
+			Description: "save a preset - omitted settings use the server defaults",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        NameOption,
+					Description: "preset name",
+					Required:    true,
+					MaxLength:   32,
+				},
+				presetScopeOption,
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        PomodoroOption,
+					Description: "pomodoro duration in minutes",
+					MinValue:    float64Ptr(1),
+					MaxValue:    240,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        ShortBreakOption,
+					Description: "short break duration in minutes",
+					MinValue:    float64Ptr(1),
+					MaxValue:    240,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        LongBreakOption,
+					Description: "long break duration in minutes",
+					MinValue:    float64Ptr(1),
+					MaxValue:    240,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        IntervalsOption,
+					Description: "number of intervals between long breaks",
+					MinValue:    float64Ptr(1),
+					MaxValue:    20,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        NoDeafenOption,
+					Description: "participants will not be deafened during pomodoro intervals",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        NoMuteOption,
+					Description: "participants will not be muted during pomodoro intervals",
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        PresetDeleteSubcommand,
+			Description: "delete a saved preset",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         NameOption,
+					Description:  "preset name",
+					Required:     true,
+					Autocomplete: true,
+				},
+				presetScopeOption,
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        PresetListSubcommand,
+			Description: "list the presets available to you",
+		},
+	},
+}
+
+var ScheduleCommand = discordgo.ApplicationCommand{
+	Name:                     "schedule",
+	Description:              "start sessions on a recurring schedule",
+	DefaultMemberPermissions: &manageGuildPermission,
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        ScheduleCreateSubcommand,
+			Description: "schedule a recurring session in the server's timezone (see /config timezone)",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        DaysOption,
+					Description: "\"daily\", \"weekdays\", \"weekends\" or days such as \"mon, wed, fri\"",
+					Required:    true,
+					MaxLength:   64,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        TimeOption,
+					Description: "24-hour start time, e.g. 09:00",
+					Required:    true,
+					MaxLength:   5,
+				},
+				{
+					Type:         discordgo.ApplicationCommandOptionChannel,
+					Name:         VoiceOption,
+					Description:  "voice channel for the session",
+					ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildVoice},
+					Required:     true,
+				},
+				{
+					Type:         discordgo.ApplicationCommandOptionChannel,
+					Name:         ChannelOption,
+					Description:  "text channel for the session message (Default: this channel)",
+					ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionRole,
+					Name:        PingOption,
+					Description: "role to ping when the session starts",
+				},
+				{
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         PresetOption,
+					Description:  "saved or built-in preset (Default: server defaults)",
+					Autocomplete: true,
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        ScheduleDeleteSubcommand,
+			Description: "delete a schedule",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         ScheduleOption,
+					Description:  "schedule to delete",
+					Required:     true,
+					Autocomplete: true,
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        ScheduleListSubcommand,
+			Description: "list this server's schedules",
+		},
+	},
+}
+
+var SoundCommand = discordgo.ApplicationCommand{
+	Name:                     "sound",
+	Description:              "customize this server's alert sounds and ambient track",
+	DefaultMemberPermissions: &manageGuildPermission,
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        SoundSetSubcommand,
+			Description: "replace an alert (max 10s) or set the ambient track (max 5m) with a WAV, Ogg Opus or .dca file",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        SoundOption,
+					Description: "alert to replace, or the ambient track",
+					Required:    true,
+					Choices:     alertSoundChoices(),
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionAttachment,
+					Name:        FileOption,
+					Description: "sound file",
+					Required:    true,
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        SoundResetSubcommand,
+			Description: "go back to the default alert sounds or remove the ambient track",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        SoundOption,
+					Description: "sound to reset (Default: all)",
+					Choices:     alertSoundChoices(),
+				},
+			},
+		},
+	},
+}
+
+func alertSoundChoices() []*discordgo.ApplicationCommandOptionChoice {
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, s := range AlertSounds {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  s.String(),
+			Value: string(s),
+		})
+	}
+	return choices
+}
+
+var statsRangeOption = &discordgo.ApplicationCommandOption{
+	Type:        discordgo.ApplicationCommandOptionString,
+	Name:        RangeOption,
+	Description: "time range (Default: week)",
+	Choices: []*discordgo.ApplicationCommandOptionChoice{
+		{Name: "day", Value: string(StatsRangeDay)},
+		{Name: "week", Value: string(StatsRangeWeek)},
+		{Name: "month", Value: string(StatsRangeMonth)},
+		{Name: "all", Value: string(StatsRangeAll)},
+	},
+}
+
+var StatsCommand = discordgo.ApplicationCommand{
+	Name:        "stats",
+	Description: "show focus stats",
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        StatsMeSubcommand,
+			Description: "show your focus stats",
+			Options:     []*discordgo.ApplicationCommandOption{statsRangeOption},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        StatsUserSubcommand,
+			Description: "show another member's focus stats",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionUser,
+					Name:        UserOption,
+					Description: "member to show stats for",
+					Required:    true,
+				},
+				statsRangeOption,
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        StatsServerSubcommand,
+			Description: "show focus stats for the whole server",
+			Options:     []*discordgo.ApplicationCommandOption{statsRangeOption},
+		},
+	},
+}
+
+var LeaderboardCommand = discordgo.ApplicationCommand{
+	Name:        "leaderboard",
+	Description: "rank server members by focus",
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        MetricOption,
+			Description: "what to rank by (Default: focus)",
+			Choices: []*discordgo.ApplicationCommandOptionChoice{
+				{Name: "focus time", Value: string(LeaderboardFocus)},
+				{Name: "pomodoros", Value: string(LeaderboardPomodoros)},
+				{Name: "streak", Value: string(LeaderboardStreak)},
+			},
 		},
+		statsRangeOption,
 	},
 }
@@ -16,6 +16,8 @@ const (
 	ShardCountKey  cfg.Key = "POMOMO_SHARD_COUNT"
 	LogLevelKey    cfg.Key = "POMOMO_LOG_LEVEL"
 	LogFileKey     cfg.Key = "POMOMO_LOG_FILE"
+
+	SessionRetentionDaysKey cfg.Key = "POMOMO_SESSION_RETENTION_DAYS"
 )
 
 func LoadConfig() (cfg.Config, error) {
@@ -49,6 +51,11 @@ func LoadConfig() (cfg.Config, error) {
 			Key:      LogFileKey,
 			Required: false,
 		},
+		{
+			Key:      SessionRetentionDaysKey,
+			Default:  "30",
+			Required: false,
+		},
 	}
 
 	cfgPath := os.Getenv("POMOMO_CONFIG_PATH")
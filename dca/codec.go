@@ -0,0 +1,145 @@
+package dca
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/thesyncim/gopus"
+)
+
+const (
+	// SampleRate is the rate frames are decoded at and the one other rates are resampled to for encoding
+	SampleRate = 48000
+	bitrate    = 96000
+	// maxPacketSize is the largest opus packet, see RFC 6716 section 3.4
+	maxPacketSize = 1275
+)
+
+// Encode encodes interleaved samples into opus frames of FrameDuration, padding the last one with
+// silence. Sample rates that opus doesn't support natively are resampled to SampleRate.
+func Encode(pcm []float32, sampleRate, channels int) ([][]byte, error) {
+	if channels < 1 || channels > 2 {
+		return nil, fmt.Errorf("only mono and stereo audio is supported")
+	}
+	if len(pcm) < channels {
+		return nil, fmt.Errorf("no audio")
+	}
+	switch sampleRate {
+	case 8000, 12000, 16000, 24000, SampleRate:
+	default:
+		pcm = resample(pcm, channels, sampleRate, SampleRate)
+		sampleRate = SampleRate
+	}
+
+	enc, err := gopus.NewEncoder(gopus.EncoderConfig{
+		SampleRate:  sampleRate,
+		Channels:    channels,
+		Application: gopus.ApplicationAudio,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := enc.SetBitrate(bitrate); err != nil {
+		return nil, err
+	}
+	frameSize := sampleRate * int(FrameDuration.Milliseconds()) / 1000 * channels
+	frame := make([]float32, frameSize)
+	packet := make([]byte, maxPacketSize)
+	frames := make([][]byte, 0, (len(pcm)+frameSize-1)/frameSize)
+	for start := 0; start < len(pcm); start += frameSize {
+		n := copy(frame, pcm[start:])
+		clear(frame[n:])
+		size, err := enc.Encode(frame, packet)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode frame %d: %w", len(frames), err)
+		}
+		frames = append(frames, append([]byte(nil), packet[:size]...))
+	}
+	return frames, nil
+}
+
+// Decode decodes frames into interleaved samples at SampleRate. Stereo frames decode to two channels and
+// mono frames to one, going by the first frame.
+func Decode(frames [][]byte) (pcm []float32, channels int, err error) {
+	if len(frames) == 0 || len(frames[0]) == 0 {
+		return nil, 0, fmt.Errorf("no frames")
+	}
+	channels = 1
+	if gopus.ParseTOC(frames[0][0]).Stereo {
+		channels = 2
+	}
+	dec, err := gopus.NewDecoder(gopus.DefaultDecoderConfig(SampleRate, channels))
+	if err != nil {
+		return nil, 0, err
+	}
+	// 120ms is the longest packet
+	out := make([]float32, SampleRate*120/1000*channels)
+	for i, f := range frames {
+		n, err := dec.Decode(f, out)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to decode frame %d: %w", i, err)
+		}
+		pcm = append(pcm, out[:n*channels]...)
+	}
+	return pcm, channels, nil
+}
+
+// Scale re-encodes frames at gain times their volume, clipping anything pushed past full scale
+func Scale(frames [][]byte, gain float64) ([][]byte, error) {
+	pcm, channels, err := Decode(frames)
+	if err != nil {
+		return nil, err
+	}
+	for i, s := range pcm {
+		pcm[i] = float32(max(-1, min(1, float64(s)*gain)))
+	}
+	return Encode(pcm, SampleRate, channels)
+}
+
+// resample converts interleaved samples between rates with a windowed sinc filter, which also low-passes
+// below the new Nyquist frequency when downsampling
+func resample(pcm []float32, channels, from, to int) []float32 {
+	const zeroCrossings = 16 // per side of the filter
+	in := len(pcm) / channels
+	out := int(int64(in) * int64(to) / int64(from))
+	ratio := float64(from) / float64(to)
+	cutoff := min(1, 1/ratio)
+	// the filter widens as the cutoff drops to keep the same number of zero crossings
+	half := int(math.Ceil(zeroCrossings / cutoff))
+	resampled := make([]float32, out*channels)
+	for i := range out {
+		center := float64(i) * ratio
+		first := int(math.Floor(center)) - half + 1
+		for ch := range channels {
+			var sum, weights float64
+			for j := first; j < first+2*half; j++ {
+				if j < 0 || j >= in {
+					continue
+				}
+				x := (float64(j) - center) * cutoff
+				w := sinc(x) * hann(x/zeroCrossings)
+				sum += w * float64(pcm[j*channels+ch])
+				weights += w
+			}
+			if weights != 0 {
+				resampled[i*channels+ch] = float32(sum / weights)
+			}
+		}
+	}
+	return resampled
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// hann is the Hann window over [-1, 1]
+func hann(x float64) float64 {
+	if x <= -1 || x >= 1 {
+		return 0
+	}
+	return 0.5 + 0.5*math.Cos(math.Pi*x)
+}
@@ -0,0 +1,139 @@
+// Package dca reads and writes opus frames in the DCA format of the bot's sounds, where each frame is
+// prefixed with its int16 length, and muxes the same frames to and from Ogg Opus files.
+package dca
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FrameDuration is the only frame length that can be sent to discord as is - one frame every 20ms
+const FrameDuration = 20 * time.Millisecond
+
+// Read reads frames until EOF
+func Read(r io.Reader) ([][]byte, error) {
+	var frames [][]byte
+	var frameLen int16
+	for {
+		if err := binary.Read(r, binary.LittleEndian, &frameLen); err != nil {
+			if err == io.EOF {
+				return frames, nil
+			}
+			return nil, err
+		}
+		if frameLen < 0 {
+			return nil, fmt.Errorf("invalid frame length %d", frameLen)
+		}
+
+		// Read encoded pcm from dca file.
+		frame := make([]byte, frameLen)
+		if _, err := io.ReadFull(r, frame); err != nil {
+			// Should not be any end of file errors
+			return nil, err
+		}
+
+		// Append encoded pcm data to the buffer.
+		frames = append(frames, frame)
+	}
+}
+
+// Write is the inverse of Read
+func Write(w io.Writer, frames [][]byte) error {
+	for _, f := range frames {
+		if len(f) > math.MaxInt16 {
+			return fmt.Errorf("frame of %d bytes is too long", len(f))
+		}
+		if err := binary.Write(w, binary.LittleEndian, int16(len(f))); err != nil {
+			return err
+		}
+		if _, err := w.Write(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Marshal writes frames to a byte slice
+func Marshal(frames [][]byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := Write(&buf, frames); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ReadFile reads the frames of a DCA (.dca) or Ogg Opus (.ogg, .opus) file by its extension, encoding
+// WAV (.wav) files into frames
+func ReadFile(name string, r io.Reader) ([][]byte, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".dca":
+		return Read(r)
+	case ".ogg", ".opus":
+		return ReadOggOpus(r)
+	case ".wav":
+		pcm, sampleRate, channels, err := ReadWAV(r)
+		if err != nil {
+			return nil, err
+		}
+		return Encode(pcm, sampleRate, channels)
+	default:
+		return nil, fmt.Errorf("unsupported file type \"%s\" - use WAV (.wav), Ogg Opus (.ogg, .opus) or .dca", filepath.Ext(name))
+	}
+}
+
+// Validate checks that the frames can be sent as is and returns their total length
+func Validate(frames [][]byte) (time.Duration, error) {
+	if len(frames) == 0 {
+		return 0, fmt.Errorf("no audio")
+	}
+	var total time.Duration
+	for _, f := range frames {
+		d, err := PacketDuration(f)
+		if err != nil {
+			return 0, err
+		}
+		if d != FrameDuration {
+			return 0, fmt.Errorf("audio must be encoded with %dms frames", FrameDuration.Milliseconds())
+		}
+		total += d
+	}
+	return total, nil
+}
+
+// PacketDuration returns the length of audio in an opus packet from its TOC byte, see RFC 6716 section 3.1
+func PacketDuration(p []byte) (time.Duration, error) {
+	if len(p) == 0 {
+		return 0, fmt.Errorf("empty opus packet")
+	}
+	config := p[0] >> 3
+	var frame time.Duration
+	switch {
+	case config < 12:
+		// SILK-only
+		frame = []time.Duration{10, 20, 40, 60}[config%4] * time.Millisecond
+	case config < 16:
+		// hybrid
+		frame = []time.Duration{10, 20}[config%2] * time.Millisecond
+	default:
+		// CELT-only
+		frame = []time.Duration{2500, 5000, 10000, 20000}[config%4] * time.Microsecond
+	}
+
+	frames := 1
+	switch p[0] & 0x3 {
+	case 1, 2:
+		frames = 2
+	case 3:
+		if len(p) < 2 {
+			return 0, fmt.Errorf("opus packet is missing its frame count")
+		}
+		frames = int(p[1] & 0x3f)
+	}
+	return frame * time.Duration(frames), nil
+}
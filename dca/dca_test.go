@@ -0,0 +1,336 @@
+package dca
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWriteRead(t *testing.T) {
+	tests := []struct {
+		name   string
+		frames [][]byte
+	}{
+		{"one frame", [][]byte{{0xfc, 1, 2, 3}}},
+		{"several frames", [][]byte{{0xfc, 1}, {0xfc}, bytes.Repeat([]byte{0xfc}, 1275)}},
+		{"empty frame", [][]byte{{0xfc, 1}, {}, {0xfc, 2}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := Marshal(tt.frames)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Read(bytes.NewReader(b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.EqualFunc(got, tt.frames, bytes.Equal) {
+				t.Errorf("Read() = %v, want %v", got, tt.frames)
+			}
+		})
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+	b, err := Marshal([][]byte{{0xfc, 1, 2, 3}, {0xfc, 4, 5, 6}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"truncated frame", b[:len(b)-1]},
+		{"truncated length", b[:len(b)-5]},
+		{"negative length", []byte{0xff, 0xff, 0xfc}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if frames, err := Read(bytes.NewReader(tt.data)); err == nil {
+				t.Errorf("Read() = %v, want error", frames)
+			}
+		})
+	}
+}
+
+func TestWriteTooLong(t *testing.T) {
+	if err := Write(io.Discard, [][]byte{make([]byte, math.MaxInt16+1)}); err == nil {
+		t.Error("Write() succeeded, want error")
+	}
+}
+
+func TestPacketDuration(t *testing.T) {
+	tests := []struct {
+		name   string
+		packet []byte
+		want   time.Duration
+	}{
+		{"silk 10ms", []byte{0 << 3}, 10 * time.Millisecond},
+		{"silk 60ms", []byte{3 << 3}, 60 * time.Millisecond},
+		{"hybrid 20ms", []byte{13 << 3}, 20 * time.Millisecond},
+		{"celt 2.5ms", []byte{16 << 3}, 2500 * time.Microsecond},
+		{"celt 20ms", []byte{31 << 3}, 20 * time.Millisecond},
+		{"celt 20ms stereo", []byte{31<<3 | 0x04}, 20 * time.Millisecond},
+		{"two frames", []byte{31<<3 | 1}, 40 * time.Millisecond},
+		{"two frames of different sizes", []byte{31<<3 | 2}, 40 * time.Millisecond},
+		{"frame count", []byte{16<<3 | 3, 6}, 15 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PacketDuration(tt.packet)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("PacketDuration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	for _, p := range [][]byte{{}, {31<<3 | 3}} {
+		if _, err := PacketDuration(p); err == nil {
+			t.Errorf("PacketDuration(%v) succeeded, want error", p)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		frames  [][]byte
+		want    time.Duration
+		wantErr bool
+	}{
+		{"20ms frames", [][]byte{{31 << 3}, {31<<3 | 0x04}, {13 << 3}}, 60 * time.Millisecond, false},
+		{"no frames", nil, 0, true},
+		{"10ms frame", [][]byte{{31 << 3}, {30 << 3}}, 0, true},
+		{"empty frame", [][]byte{{31 << 3}, {}}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Validate(tt.frames)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadFileWAV(t *testing.T) {
+	tests := []struct {
+		name       string
+		sampleRate int
+		channels   int
+	}{
+		{"48kHz stereo", 48000, 2},
+		{"44.1kHz stereo", 44100, 2},
+		{"16kHz mono", 16000, 1},
+		{"22.05kHz mono", 22050, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pcm := sine(440, 0.5, tt.sampleRate, tt.channels, 500*time.Millisecond)
+			wav := wavFile(t, wavFormatPCM, 16, tt.sampleRate, tt.channels, pcm)
+			frames, err := ReadFile("alert.WAV", bytes.NewReader(wav))
+			if err != nil {
+				t.Fatal(err)
+			}
+			d, err := Validate(frames)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d != 500*time.Millisecond {
+				t.Errorf("duration = %v, want 500ms", d)
+			}
+
+			decoded, channels, err := Decode(frames)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if channels != tt.channels {
+				t.Errorf("channels = %d, want %d", channels, tt.channels)
+			}
+			if len(decoded) != SampleRate/2*channels {
+				t.Errorf("decoded %d samples, want %d", len(decoded), SampleRate/2*channels)
+			}
+			// a sine's RMS is its amplitude over √2; skip the encoder's lookahead
+			want := 0.5 / math.Sqrt2
+			if got := rms(decoded[len(decoded)/4:]); math.Abs(got-want) > want*0.1 {
+				t.Errorf("decoded RMS = %.3f, want %.3f", got, want)
+			}
+		})
+	}
+}
+
+func TestReadWAV(t *testing.T) {
+	pcm := []float32{0, 0.5, -0.5, 0.25, -1, 0.75}
+	tests := []struct {
+		name      string
+		format    int
+		bits      int
+		tolerance float64
+	}{
+		{"8-bit", wavFormatPCM, 8, 1.0 / (1 << 6)},
+		{"16-bit", wavFormatPCM, 16, 1.0 / (1 << 14)},
+		{"24-bit", wavFormatPCM, 24, 1.0 / (1 << 22)},
+		{"32-bit", wavFormatPCM, 32, 1.0 / (1 << 22)},
+		{"float", wavFormatFloat, 32, 0},
+		{"extensible", wavFormatExtensible, 16, 1.0 / (1 << 14)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, sampleRate, channels, err := ReadWAV(bytes.NewReader(wavFile(t, tt.format, tt.bits, 8000, 2, pcm)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sampleRate != 8000 || channels != 2 {
+				t.Errorf("ReadWAV() = %dHz, %d channels, want 8000Hz, 2 channels", sampleRate, channels)
+			}
+			if len(got) != len(pcm) {
+				t.Fatalf("ReadWAV() read %d samples, want %d", len(got), len(pcm))
+			}
+			for i := range pcm {
+				if math.Abs(float64(got[i]-pcm[i])) > tt.tolerance {
+					t.Errorf("sample %d = %v, want %v", i, got[i], pcm[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReadWAVInvalid(t *testing.T) {
+	pcm := []float32{0, 0.5, -0.5, 0.25}
+	valid := wavFile(t, wavFormatPCM, 16, 8000, 1, pcm)
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"not riff", append([]byte("RIFX"), valid[4:]...)},
+		{"no data", valid[:36]},
+		{"truncated header", valid[:8]},
+		{"surround", wavFile(t, wavFormatPCM, 16, 8000, 6, nil)},
+		{"adpcm", wavFile(t, 2, 4, 8000, 1, pcm)},
+		{"12-bit", wavFile(t, wavFormatPCM, 12, 8000, 1, pcm)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, _, err := ReadWAV(bytes.NewReader(tt.data)); err == nil {
+				t.Error("ReadWAV() succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestScale(t *testing.T) {
+	frames, err := Encode(sine(440, 0.4, SampleRate, 1, 500*time.Millisecond), SampleRate, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		gain float64
+		want float64
+	}{
+		{"quieter", 0.5, 0.2 / math.Sqrt2},
+		{"louder", 2, 0.8 / math.Sqrt2},
+		// clipped to a square-ish wave, which is louder than the sine would be
+		{"clipped", 10, 0.9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scaled, err := Scale(frames, tt.gain)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(scaled) != len(frames) {
+				t.Errorf("Scale() = %d frames, want %d", len(scaled), len(frames))
+			}
+			pcm, _, err := Decode(scaled)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := rms(pcm[len(pcm)/4:]); math.Abs(got-tt.want) > tt.want*0.15 {
+				t.Errorf("scaled RMS = %.3f, want %.3f", got, tt.want)
+			}
+		})
+	}
+}
+
+// sine returns interleaved samples of a tone, the same in every channel
+func sine(freq, amplitude float64, sampleRate, channels int, d time.Duration) []float32 {
+	n := int(d.Seconds() * float64(sampleRate))
+	pcm := make([]float32, 0, n*channels)
+	for i := range n {
+		s := float32(amplitude * math.Sin(2*math.Pi*freq*float64(i)/float64(sampleRate)))
+		for range channels {
+			pcm = append(pcm, s)
+		}
+	}
+	return pcm
+}
+
+func rms(pcm []float32) float64 {
+	var sum float64
+	for _, s := range pcm {
+		sum += float64(s) * float64(s)
+	}
+	return math.Sqrt(sum / float64(len(pcm)))
+}
+
+// wavFile encodes samples as a WAV file, with an odd-sized chunk before the audio to skip
+func wavFile(t *testing.T, format, bits, sampleRate, channels int, pcm []float32) []byte {
+	t.Helper()
+	var data []byte
+	for _, s := range pcm {
+		switch {
+		case format == wavFormatFloat:
+			data = binary.LittleEndian.AppendUint32(data, math.Float32bits(s))
+		case bits == 8:
+			data = append(data, byte(int(math.Round(float64(s)*127))+128))
+		case bits%8 == 0:
+			v := uint32(int32(math.Round(float64(s) * float64(int64(1)<<(bits-1)-1))))
+			for b := range bits / 8 {
+				data = append(data, byte(v>>(8*b)))
+			}
+		default:
+			data = binary.LittleEndian.AppendUint16(data, 0)
+		}
+	}
+
+	fmtChunk := binary.LittleEndian.AppendUint16(nil, uint16(format))
+	fmtChunk = binary.LittleEndian.AppendUint16(fmtChunk, uint16(channels))
+	fmtChunk = binary.LittleEndian.AppendUint32(fmtChunk, uint32(sampleRate))
+	fmtChunk = binary.LittleEndian.AppendUint32(fmtChunk, uint32(sampleRate*channels*bits/8))
+	fmtChunk = binary.LittleEndian.AppendUint16(fmtChunk, uint16(channels*bits/8))
+	fmtChunk = binary.LittleEndian.AppendUint16(fmtChunk, uint16(bits))
+	if format == wavFormatExtensible {
+		fmtChunk = binary.LittleEndian.AppendUint16(fmtChunk, 22)
+		fmtChunk = binary.LittleEndian.AppendUint16(fmtChunk, uint16(bits))
+		fmtChunk = binary.LittleEndian.AppendUint32(fmtChunk, 0)
+		// KSDATAFORMAT_SUBTYPE_PCM
+		fmtChunk = append(fmtChunk, 1, 0, 0, 0, 0, 0, 0x10, 0, 0x80, 0, 0, 0xaa, 0, 0x38, 0x9b, 0x71)
+	}
+
+	var body []byte
+	chunk := func(id string, b []byte) {
+		body = append(body, id...)
+		body = binary.LittleEndian.AppendUint32(body, uint32(len(b)))
+		body = append(body, b...)
+		if len(b)%2 == 1 {
+			body = append(body, 0)
+		}
+	}
+	body = append(body, "WAVE"...)
+	chunk("fmt ", fmtChunk)
+	chunk("LIST", []byte("INFO!"))
+	chunk("data", data)
+	return append(binary.LittleEndian.AppendUint32([]byte("RIFF"), uint32(len(body))), body...)
+}
@@ -0,0 +1,179 @@
+package dca
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ReadOggOpus demuxes the opus packets of a single-stream Ogg Opus file (RFC 7845), skipping its headers
+func ReadOggOpus(r io.Reader) ([][]byte, error) {
+	br := bufio.NewReader(r)
+	var packets [][]byte
+	var partial []byte
+	var serial uint32
+	for page := 0; ; page++ {
+		var header [27]byte
+		if _, err := io.ReadFull(br, header[:]); err != nil {
+			if err == io.EOF && page > 0 {
+				break
+			}
+			return nil, fmt.Errorf("failed to read ogg page: %w", err)
+		}
+		if !bytes.Equal(header[:4], []byte("OggS")) || header[4] != 0 {
+			return nil, fmt.Errorf("not an ogg file")
+		}
+		if s := binary.LittleEndian.Uint32(header[14:18]); page == 0 {
+			serial = s
+		} else if s != serial {
+			return nil, fmt.Errorf("ogg files with multiple streams aren't supported")
+		}
+
+		// packets are split into segments of 255 bytes with a shorter segment ending each packet,
+		// and may continue on the next page
+		segments := make([]byte, header[26])
+		if _, err := io.ReadFull(br, segments); err != nil {
+			return nil, fmt.Errorf("failed to read ogg page: %w", err)
+		}
+		var size int
+		for _, s := range segments {
+			size += int(s)
+		}
+		body := make([]byte, size)
+		if _, err := io.ReadFull(br, body); err != nil {
+			return nil, fmt.Errorf("failed to read ogg page: %w", err)
+		}
+		crc := binary.LittleEndian.Uint32(header[22:26])
+		clear(header[22:26])
+		if oggCRC(header[:], segments, body) != crc {
+			return nil, fmt.Errorf("ogg page %d is corrupt", page)
+		}
+
+		for _, s := range segments {
+			partial = append(partial, body[:s]...)
+			body = body[s:]
+			if s < 255 {
+				packets = append(packets, partial)
+				partial = nil
+			}
+		}
+	}
+
+	if partial != nil {
+		return nil, fmt.Errorf("ogg file ends partway through a packet")
+	}
+	if len(packets) < 2 || !bytes.HasPrefix(packets[0], []byte("OpusHead")) || !bytes.HasPrefix(packets[1], []byte("OpusTags")) {
+		return nil, fmt.Errorf("not an ogg opus file")
+	}
+	if head := packets[0]; len(head) < 19 || head[9] > 2 || head[18] != 0 {
+		return nil, fmt.Errorf("only mono and stereo ogg opus files are supported")
+	}
+
+	var audio [][]byte
+	for _, p := range packets[2:] {
+		if len(p) > 0 {
+			audio = append(audio, p)
+		}
+	}
+	return audio, nil
+}
+
+// oggSerial is the stream serial number of written files, which only needs to be unique within the file
+const oggSerial = 0x706f6d6f
+
+// WriteOggOpus muxes frames into a single-stream Ogg Opus file, the inverse of ReadOggOpus. The channel
+// count is taken from the first frame.
+func WriteOggOpus(w io.Writer, frames [][]byte) error {
+	if len(frames) == 0 || len(frames[0]) == 0 {
+		return fmt.Errorf("no frames")
+	}
+	channels := byte(1)
+	if frames[0][0]&0x04 != 0 {
+		channels = 2
+	}
+	head := make([]byte, 19)
+	copy(head, "OpusHead")
+	head[8] = 1 // version
+	head[9] = channels
+	binary.LittleEndian.PutUint32(head[12:16], 48000)
+	vendor := "pomomo"
+	tags := binary.LittleEndian.AppendUint32([]byte("OpusTags"), uint32(len(vendor)))
+	tags = append(tags, vendor...)
+	tags = binary.LittleEndian.AppendUint32(tags, 0) // no comments
+
+	bw := bufio.NewWriter(w)
+	if err := writeOggPage(bw, head, 0x02, 0, 0); err != nil {
+		return err
+	}
+	if err := writeOggPage(bw, tags, 0, 0, 1); err != nil {
+		return err
+	}
+	var granule int64
+	for i, f := range frames {
+		d, err := PacketDuration(f)
+		if err != nil {
+			return fmt.Errorf("frame %d: %w", i, err)
+		}
+		granule += int64(d * 48 / time.Millisecond)
+		var flags byte
+		if i == len(frames)-1 {
+			flags = 0x04
+		}
+		if err := writeOggPage(bw, f, flags, granule, uint32(i+2)); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// writeOggPage writes packet as a page of its own
+func writeOggPage(w io.Writer, packet []byte, flags byte, granule int64, seq uint32) error {
+	if len(packet) >= 255*255 {
+		return fmt.Errorf("packet of %d bytes doesn't fit in an ogg page", len(packet))
+	}
+	lacing := bytes.Repeat([]byte{255}, len(packet)/255)
+	lacing = append(lacing, byte(len(packet)%255))
+
+	page := make([]byte, 27, 27+len(lacing)+len(packet))
+	copy(page, "OggS")
+	page[5] = flags
+	binary.LittleEndian.PutUint64(page[6:14], uint64(granule))
+	binary.LittleEndian.PutUint32(page[14:18], oggSerial)
+	binary.LittleEndian.PutUint32(page[18:22], seq)
+	page[26] = byte(len(lacing))
+	page = append(page, lacing...)
+	page = append(page, packet...)
+	binary.LittleEndian.PutUint32(page[22:26], oggCRC(page))
+	_, err := w.Write(page)
+	return err
+}
+
+var oggCRCTable = func() (t [256]uint32) {
+	for i := range t {
+		r := uint32(i) << 24
+		for range 8 {
+			if r&0x80000000 != 0 {
+				r = r<<1 ^ 0x04c11db7
+			} else {
+				r <<= 1
+			}
+		}
+		t[i] = r
+	}
+	return t
+}()
+
+// oggCRC is the page checksum - CRC-32 with polynomial 0x04c11db7, unreflected and without xor - of the
+// page's parts with the checksum field zeroed
+func oggCRC(parts ...[]byte) uint32 {
+	var crc uint32
+	for _, p := range parts {
+		for _, b := range p {
+			crc = crc<<8 ^ oggCRCTable[byte(crc>>24)^b]
+		}
+	}
+	return crc
+}
@@ -0,0 +1,183 @@
+package dca
+
+import (
+	"bytes"
+	"encoding/binary"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestOggCRC(t *testing.T) {
+	// the CRC-32/MPEG-2 check value without its initial value and final xor
+	if got := oggCRC([]byte("123456789")); got != 0x89a1897f {
+		t.Errorf("oggCRC() = %#x, want 0x89a1897f", got)
+	}
+	if got := oggCRC([]byte("1234"), nil, []byte("56789")); got != 0x89a1897f {
+		t.Errorf("oggCRC() in parts = %#x, want 0x89a1897f", got)
+	}
+}
+
+func TestWriteReadOggOpus(t *testing.T) {
+	encoded, err := Encode(sine(440, 0.5, SampleRate, 2, 200*time.Millisecond), SampleRate, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		frames [][]byte
+	}{
+		{"encoded", encoded},
+		// packets of 255 bytes or more are laced over several segments
+		{"laced", [][]byte{
+			append([]byte{31 << 3}, make([]byte, 254)...),
+			append([]byte{31 << 3}, make([]byte, 255)...),
+			append([]byte{31 << 3}, make([]byte, 1274)...),
+		}},
+		{"single frame", [][]byte{{31<<3 | 0x04, 1, 2}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteOggOpus(&buf, tt.frames); err != nil {
+				t.Fatal(err)
+			}
+			ogg := buf.Bytes()
+			got, err := ReadOggOpus(bytes.NewReader(ogg))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.EqualFunc(got, tt.frames, bytes.Equal) {
+				t.Errorf("ReadOggOpus() = %d frames, want %v", len(got), len(tt.frames))
+			}
+
+			pages := oggPages(t, ogg)
+			if len(pages) != len(tt.frames)+2 {
+				t.Fatalf("wrote %d pages, want %d", len(pages), len(tt.frames)+2)
+			}
+			if pages[0][5] != 0x02 || pages[len(pages)-1][5] != 0x04 {
+				t.Error("first page isn't flagged as the start of the stream or last page as the end")
+			}
+			if channels := pages[0][27+1+9]; channels != 1+tt.frames[0][0]>>2&1 {
+				t.Errorf("OpusHead has %d channels", channels)
+			}
+			// 960 samples of 20ms frames at 48kHz
+			if granule := binary.LittleEndian.Uint64(pages[len(pages)-1][6:14]); granule != uint64(960*len(tt.frames)) {
+				t.Errorf("last granule position = %d, want %d", granule, 960*len(tt.frames))
+			}
+			for i, p := range pages {
+				page := slices.Clone(p)
+				clear(page[22:26])
+				if crc := oggCRC(page); crc != binary.LittleEndian.Uint32(p[22:26]) {
+					t.Errorf("page %d checksum = %#x, want %#x", i, binary.LittleEndian.Uint32(p[22:26]), crc)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteOggOpusInvalid(t *testing.T) {
+	for name, frames := range map[string][][]byte{
+		"no frames":     nil,
+		"empty frame":   {{}},
+		"huge frame":    {make([]byte, 255*255)},
+		"missing count": {{31 << 3}, {31<<3 | 3}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := WriteOggOpus(&bytes.Buffer{}, frames); err == nil {
+				t.Error("WriteOggOpus() succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestReadOggOpusInvalid(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteOggOpus(&buf, [][]byte{{31 << 3, 1}, {31 << 3, 2}, append([]byte{31 << 3}, make([]byte, 300)...)}); err != nil {
+		t.Fatal(err)
+	}
+	ogg := buf.Bytes()
+	pages := oggPages(t, ogg)
+	last := len(ogg) - len(pages[len(pages)-1])
+
+	// edit returns a copy of the file with fn applied to page i, recomputing its checksum
+	edit := func(i int, fn func(page []byte) []byte) []byte {
+		var out []byte
+		for j, p := range pages {
+			p = slices.Clone(p)
+			if j == i {
+				p = fn(p)
+				clear(p[22:26])
+				binary.LittleEndian.PutUint32(p[22:26], oggCRC(p))
+			}
+			out = append(out, p...)
+		}
+		return out
+	}
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"truncated header", ogg[:last+10]},
+		{"truncated lacing", ogg[:last+28]},
+		{"truncated packet", ogg[:len(ogg)-1]},
+		{"corrupt", func() []byte {
+			b := slices.Clone(ogg)
+			b[last+40]++
+			return b
+		}()},
+		{"bad capture pattern", edit(2, func(p []byte) []byte {
+			copy(p, "OggT")
+			return p
+		})},
+		{"bad version", edit(2, func(p []byte) []byte {
+			p[4] = 1
+			return p
+		})},
+		{"second stream", edit(3, func(p []byte) []byte {
+			p[14]++
+			return p
+		})},
+		{"unterminated packet", edit(len(pages)-1, func(p []byte) []byte {
+			// keep only the first, full segment of the packet so it never ends
+			page := append(p[:27:27], 255)
+			page[26] = 1
+			return append(page, p[27+int(p[26]):][:255]...)
+		})},
+		{"not opus", edit(0, func(p []byte) []byte {
+			copy(p[28:], "VorbHead")
+			return p
+		})},
+		{"surround", edit(0, func(p []byte) []byte {
+			p[28+9] = 6
+			return p
+		})},
+		{"missing tags", ogg[:len(pages[0])]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if frames, err := ReadOggOpus(bytes.NewReader(tt.data)); err == nil {
+				t.Errorf("ReadOggOpus() = %d frames, want error", len(frames))
+			}
+		})
+	}
+}
+
+// oggPages splits a file into its pages
+func oggPages(t *testing.T, b []byte) [][]byte {
+	t.Helper()
+	var pages [][]byte
+	for len(b) > 0 {
+		if len(b) < 27 || string(b[:4]) != "OggS" {
+			t.Fatalf("invalid page %d", len(pages))
+		}
+		size := 27 + int(b[26])
+		for _, s := range b[27:size] {
+			size += int(s)
+		}
+		pages = append(pages, b[:size])
+		b = b[size:]
+	}
+	return pages
+}
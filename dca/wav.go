@@ -0,0 +1,120 @@
+package dca
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	wavFormatPCM        = 1
+	wavFormatFloat      = 3
+	wavFormatExtensible = 0xfffe
+)
+
+// ReadWAV reads the interleaved samples of a mono or stereo WAV file, scaled to [-1, 1]. Integer PCM of
+// 8 to 32 bits and 32-bit float samples are supported.
+func ReadWAV(r io.Reader) (pcm []float32, sampleRate, channels int, err error) {
+	br := bufio.NewReader(r)
+	var header [12]byte
+	if _, err := io.ReadFull(br, header[:]); err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to read wav header: %w", err)
+	}
+	if !bytes.Equal(header[:4], []byte("RIFF")) || !bytes.Equal(header[8:], []byte("WAVE")) {
+		return nil, 0, 0, fmt.Errorf("not a wav file")
+	}
+
+	var format, bits int
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(br, chunk[:]); err != nil {
+			return nil, 0, 0, fmt.Errorf("wav file has no audio: %w", err)
+		}
+		id, size := string(chunk[:4]), int64(binary.LittleEndian.Uint32(chunk[4:]))
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return nil, 0, 0, fmt.Errorf("invalid wav format chunk")
+			}
+			fmtChunk := make([]byte, size)
+			if _, err := io.ReadFull(br, fmtChunk); err != nil {
+				return nil, 0, 0, fmt.Errorf("failed to read wav format: %w", err)
+			}
+			format = int(binary.LittleEndian.Uint16(fmtChunk[0:2]))
+			channels = int(binary.LittleEndian.Uint16(fmtChunk[2:4]))
+			sampleRate = int(binary.LittleEndian.Uint32(fmtChunk[4:8]))
+			bits = int(binary.LittleEndian.Uint16(fmtChunk[14:16]))
+			if format == wavFormatExtensible && size >= 26 {
+				// the real format leads the subformat GUID
+				format = int(binary.LittleEndian.Uint16(fmtChunk[24:26]))
+			}
+		case "data":
+			if format == 0 {
+				return nil, 0, 0, fmt.Errorf("wav audio comes before its format")
+			}
+			if channels < 1 || channels > 2 {
+				return nil, 0, 0, fmt.Errorf("only mono and stereo wav files are supported")
+			}
+			if sampleRate <= 0 {
+				return nil, 0, 0, fmt.Errorf("invalid wav sample rate %d", sampleRate)
+			}
+			pcm, err = readWAVSamples(io.LimitReader(br, size), format, bits)
+			if err != nil {
+				return nil, 0, 0, err
+			}
+			// drop a partial last frame
+			return pcm[:len(pcm)/channels*channels], sampleRate, channels, nil
+		}
+		if id != "fmt " {
+			if _, err := br.Discard(int(size)); err != nil {
+				return nil, 0, 0, fmt.Errorf("failed to read wav file: %w", err)
+			}
+		}
+		if size%2 == 1 {
+			// chunks are padded to an even size
+			if _, err := br.Discard(1); err != nil && err != io.EOF {
+				return nil, 0, 0, fmt.Errorf("failed to read wav file: %w", err)
+			}
+		}
+	}
+}
+
+func readWAVSamples(r io.Reader, format, bits int) ([]float32, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wav audio: %w", err)
+	}
+	switch {
+	case format == wavFormatFloat && bits == 32:
+		pcm := make([]float32, len(data)/4)
+		for i := range pcm {
+			pcm[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+		}
+		return pcm, nil
+	case format == wavFormatPCM && bits == 8:
+		// 8-bit samples are unsigned
+		pcm := make([]float32, len(data))
+		for i, b := range data {
+			pcm[i] = float32(int(b)-128) / 128
+		}
+		return pcm, nil
+	case format == wavFormatPCM && (bits == 16 || bits == 24 || bits == 32):
+		width := bits / 8
+		scale := float32(int64(1) << (bits - 1))
+		pcm := make([]float32, len(data)/width)
+		for i := range pcm {
+			var v int32
+			for b := range width {
+				v |= int32(data[i*width+b]) << (8 * (4 - width + b))
+			}
+			// sign-extended by the shift into the top bytes
+			pcm[i] = float32(v>>(8*(4-width))) / scale
+		}
+		return pcm, nil
+	default:
+		return nil, fmt.Errorf("unsupported wav encoding (format %d, %d bits) - use PCM or 32-bit float", format, bits)
+	}
+}
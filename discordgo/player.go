@@ -0,0 +1,143 @@
+package discordgo
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/benjamonnguyen/pomomo-go"
+	"github.com/charmbracelet/log"
+)
+
+var (
+	// ambientChunkFrames is how many ambient frames are sent between checks for alerts, i.e. 100ms
+	ambientChunkFrames = 5
+	// ambientSendTimeout bounds each ambient chunk, including any reconnect, before streaming stops
+	ambientSendTimeout = 15 * time.Second
+)
+
+// guildPlayer streams audio to a guild's voice connection. Alerts are played in order and preempt the
+// ambient track, which loops and picks up where it left off once they're done. Bots get one voice
+// connection per guild, so there's one player per guild and it hops channels as needed.
+type guildPlayer struct {
+	w   *discordgoAdapter
+	gID string
+
+	mu      sync.Mutex
+	alerts  []*alertRequest
+	ambient *ambientTrack
+	running bool
+}
+
+type alertRequest struct {
+	ctx     context.Context
+	cID     pomomo.VoiceChannelID
+	packets [][]byte
+	done    chan error
+}
+
+type ambientTrack struct {
+	cID     pomomo.VoiceChannelID
+	packets [][]byte
+	pos     int
+	paused  bool
+}
+
+func (w *discordgoAdapter) player(gID string) *guildPlayer {
+	w.playersMu.Lock()
+	defer w.playersMu.Unlock()
+	p, exists := w.players[gID]
+	if !exists {
+		p = &guildPlayer{w: w, gID: gID}
+		w.players[gID] = p
+	}
+	return p
+}
+
+// startLocked runs the player if it isn't already running - p.mu must be held
+func (p *guildPlayer) startLocked() {
+	if !p.running {
+		p.running = true
+		go p.run()
+	}
+}
+
+func (p *guildPlayer) idleLocked() bool {
+	return len(p.alerts) == 0 && (p.ambient == nil || p.ambient.paused)
+}
+
+func (p *guildPlayer) run() {
+	for {
+		p.mu.Lock()
+		if len(p.alerts) > 0 {
+			req := p.alerts[0]
+			p.alerts = p.alerts[1:]
+			p.mu.Unlock()
+			req.done <- p.w.voice.Send(req.ctx, p.gID, req.cID, req.packets)
+			continue
+		}
+		if p.idleLocked() {
+			p.running = false
+			p.mu.Unlock()
+			return
+		}
+		a := p.ambient
+		end := min(a.pos+ambientChunkFrames, len(a.packets))
+		chunk := a.packets[a.pos:end]
+		a.pos = end % len(a.packets)
+		p.mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), ambientSendTimeout)
+		err := p.w.voice.Send(ctx, p.gID, a.cID, chunk)
+		cancel()
+		if err != nil {
+			log.Error("failed to stream ambient audio - stopping", "guildID", p.gID, "channelID", a.cID, "err", err)
+			p.mu.Lock()
+			if p.ambient == a {
+				p.ambient = nil
+			}
+			p.mu.Unlock()
+		}
+	}
+}
+
+// PlayAmbient loops packets in cID whenever no alerts are playing. Playing the track that's already
+// set for cID resumes it from where it was paused.
+func (w *discordgoAdapter) PlayAmbient(gID string, cID pomomo.VoiceChannelID, packets [][]byte) {
+	if len(packets) == 0 {
+		return
+	}
+	p := w.player(gID)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if a := p.ambient; a == nil || a.cID != cID || !sameTrack(a.packets, packets) {
+		// one voice connection per guild means one ambient track per guild
+		p.ambient = &ambientTrack{cID: cID, packets: packets}
+	}
+	p.ambient.paused = false
+	p.startLocked()
+}
+
+// PauseAmbient pauses cID's ambient track, keeping its position
+func (w *discordgoAdapter) PauseAmbient(gID string, cID pomomo.VoiceChannelID) {
+	p := w.player(gID)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if a := p.ambient; a != nil && a.cID == cID {
+		a.paused = true
+	}
+}
+
+// StopAmbient drops cID's ambient track
+func (w *discordgoAdapter) StopAmbient(gID string, cID pomomo.VoiceChannelID) {
+	p := w.player(gID)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if a := p.ambient; a != nil && a.cID == cID {
+		p.ambient = nil
+	}
+}
+
+func sameTrack(a, b [][]byte) bool {
+	return len(a) == len(b) && len(a) > 0 && &a[0] == &b[0]
+}
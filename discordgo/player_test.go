@@ -0,0 +1,133 @@
+package discordgo
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/benjamonnguyen/pomomo-go"
+)
+
+// gatedVoice hands each send to the test and holds it until it's released
+type gatedVoice struct {
+	sends chan gatedSend
+	gate  chan struct{}
+}
+
+type gatedSend struct {
+	cID     pomomo.VoiceChannelID
+	packets [][]byte
+}
+
+func newGatedVoice() *gatedVoice {
+	return &gatedVoice{sends: make(chan gatedSend), gate: make(chan struct{})}
+}
+
+func (v *gatedVoice) Send(ctx context.Context, _ string, cID pomomo.VoiceChannelID, packets [][]byte) error {
+	v.sends <- gatedSend{cID, packets}
+	select {
+	case <-v.gate:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (v *gatedVoice) Disconnect(string, pomomo.VoiceChannelID) error {
+	return nil
+}
+
+// expect waits for the next send, checks it and lets it finish
+func (v *gatedVoice) expect(t *testing.T, what string, cID pomomo.VoiceChannelID, packets [][]byte) {
+	t.Helper()
+	v.expectHeld(t, what, cID, packets)
+	v.gate <- struct{}{}
+}
+
+// expectHeld waits for the next send and checks it, holding it until the gate is released
+func (v *gatedVoice) expectHeld(t *testing.T, what string, cID pomomo.VoiceChannelID, packets [][]byte) {
+	t.Helper()
+	select {
+	case s := <-v.sends:
+		if s.cID != cID || !slices.EqualFunc(s.packets, packets, bytes.Equal) {
+			t.Fatalf("%s: sent %q to %v, want %q to %v", what, s.packets, s.cID, packets, cID)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("%s: nothing sent", what)
+	}
+}
+
+func (v *gatedVoice) expectIdle(t *testing.T, p *guildPlayer) {
+	t.Helper()
+	select {
+	case s := <-v.sends:
+		t.Fatalf("sent %q to %v, want nothing", s.packets, s.cID)
+	case <-time.After(50 * time.Millisecond):
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.running {
+		t.Error("player is still running")
+	}
+}
+
+func TestGuildPlayerAmbient(t *testing.T) {
+	v := newGatedVoice()
+	w := NewDiscordAdapter(nil, v)
+	p := w.player("g1")
+	ambient := testPackets("a", 12)
+	chunk := func(from, to int) [][]byte { return ambient[from:to] }
+
+	w.PlayAmbient("g1", "v1", ambient)
+	v.expect(t, "first chunk", "v1", chunk(0, 5))
+	v.expectHeld(t, "second chunk", "v1", chunk(5, 10))
+
+	// an alert queued mid-chunk plays once the chunk is sent, then the track picks up where it was
+	alert := testPackets("alert", 3)
+	alertDone := make(chan error, 1)
+	go func() { alertDone <- w.SendOpusAudio(context.Background(), alert, "g1", "v1") }()
+	eventually(t, "alert queued", func() bool {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return len(p.alerts) == 1
+	})
+	v.gate <- struct{}{}
+	v.expect(t, "alert", "v1", alert)
+	if err := <-alertDone; err != nil {
+		t.Fatal(err)
+	}
+	v.expect(t, "resumed chunk", "v1", chunk(10, 12))
+	// and loops
+	v.expectHeld(t, "looped chunk", "v1", chunk(0, 5))
+
+	// pausing keeps the position, and alerts still play while paused
+	w.PauseAmbient("g1", "v1")
+	v.gate <- struct{}{}
+	v.expectIdle(t, p)
+	go func() { alertDone <- w.SendOpusAudio(context.Background(), alert, "g1", "v1") }()
+	v.expect(t, "alert while paused", "v1", alert)
+	if err := <-alertDone; err != nil {
+		t.Fatal(err)
+	}
+	v.expectIdle(t, p)
+
+	w.PlayAmbient("g1", "v1", ambient)
+	v.expectHeld(t, "chunk after resume", "v1", chunk(5, 10))
+
+	// stopping drops the position
+	w.StopAmbient("g1", "v1")
+	v.gate <- struct{}{}
+	v.expectIdle(t, p)
+	w.PlayAmbient("g1", "v1", ambient)
+	v.expect(t, "chunk after restart", "v1", chunk(0, 5))
+
+	// other channels' tracks are left alone
+	w.PauseAmbient("g1", "v2")
+	w.StopAmbient("g1", "v2")
+	v.expectHeld(t, "chunk after other channel", "v1", chunk(5, 10))
+	w.StopAmbient("g1", "v1")
+	v.gate <- struct{}{}
+	v.expectIdle(t, p)
+}
@@ -3,6 +3,7 @@ package discordgo
 
 import (
 	"context"
+	"sync"
 
 	"github.com/benjamonnguyen/pomomo-go"
 	"github.com/bwmarrin/discordgo"
@@ -10,13 +11,19 @@ import (
 )
 
 type discordgoAdapter struct {
-	cl *discordgo.Session
-	l  log.Logger
+	cl    *discordgo.Session
+	l     log.Logger
+	voice VoiceConnectionManager
+
+	playersMu sync.Mutex
+	players   map[string]*guildPlayer
 }
 
-func NewDiscordAdapter(cl *discordgo.Session) *discordgoAdapter {
+func NewDiscordAdapter(cl *discordgo.Session, voice VoiceConnectionManager) *discordgoAdapter {
 	return &discordgoAdapter{
-		cl: cl,
+		cl:      cl,
+		voice:   voice,
+		players: make(map[string]*guildPlayer),
 	}
 }
 
@@ -28,25 +35,36 @@ func (w *discordgoAdapter) UpdateVoiceState(gid, uid string, mute, deaf bool) er
 	return err
 }
 
+// SendOpusAudio plays packets in cID once and returns when they've been sent. Sends queue behind
+// other sends in the guild and preempt its ambient track.
 func (w *discordgoAdapter) SendOpusAudio(ctx context.Context, packets [][]byte, gID string, cID pomomo.VoiceChannelID) error {
 	if packets == nil {
 		return nil
 	}
-	conn, err := w.cl.ChannelVoiceJoin(gID, string(cID), false, true)
-	if err != nil {
-		return err
+	req := &alertRequest{
+		ctx:     ctx,
+		cID:     cID,
+		packets: packets,
+		done:    make(chan error, 1),
 	}
-	if err := conn.Speaking(true); err != nil {
+	p := w.player(gID)
+	p.mu.Lock()
+	p.alerts = append(p.alerts, req)
+	p.startLocked()
+	p.mu.Unlock()
+
+	select {
+	case err := <-req.done:
 		return err
+	case <-ctx.Done():
+		return ctx.Err()
 	}
-	for _, p := range packets {
-		if err := ctx.Err(); err != nil {
-			_ = conn.Speaking(false)
-			return err
-		}
-		conn.OpusSend <- p
-	}
-	return conn.Speaking(false)
+}
+
+// DisconnectVoice stops cID's ambient track and disconnects from the guild's voice channel if it's cID
+func (w *discordgoAdapter) DisconnectVoice(gID string, cID pomomo.VoiceChannelID) error {
+	w.StopAmbient(gID, cID)
+	return w.voice.Disconnect(gID, cID)
 }
 
 func (w *discordgoAdapter) GetVoiceState(gid, uid string) (pomomo.VoiceState, error) {
@@ -0,0 +1,292 @@
+package discordgo
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/benjamonnguyen/pomomo-go"
+	"github.com/bwmarrin/discordgo"
+	"github.com/charmbracelet/log"
+)
+
+// DefaultVoiceIdleTimeout is how long a guild's voice connection is kept without audio - long enough to
+// stay connected through most intervals
+const DefaultVoiceIdleTimeout = 15 * time.Minute
+
+const (
+	// speakingIdleTimeout is how long after the last send the speaking indicator is turned off, which
+	// spans the gaps between back-to-back sends
+	speakingIdleTimeout = 250 * time.Millisecond
+	// frameSendTimeout is how long a connection gets to take a frame before it's considered broken
+	frameSendTimeout  = time.Second
+	voiceJoinAttempts = 2
+)
+
+// VoiceConnectionManager owns the bot's voice connection in each guild. Bots get one connection per
+// guild, so sends to any of the guild's channels are serialized and move the connection as needed.
+type VoiceConnectionManager interface {
+	// Send sends opus frames to cID, joining it or reconnecting as needed
+	Send(ctx context.Context, gID string, cID pomomo.VoiceChannelID, packets [][]byte) error
+	// Disconnect leaves cID if it's where the guild's connection is
+	Disconnect(gID string, cID pomomo.VoiceChannelID) error
+}
+
+// voiceClient is the part of the discord session that voice connections are made through
+type voiceClient interface {
+	join(gID, cID string) (voiceConn, error)
+	// current returns the guild's voice connection, which failed joins leave behind too
+	current(gID string) voiceConn
+}
+
+type voiceConn interface {
+	// ready reports whether the connection is ready to send audio to cID
+	ready(cID string) bool
+	channelID() string
+	speaking(bool) error
+	frames() chan<- []byte
+	disconnect() error
+}
+
+var _ VoiceConnectionManager = (*voiceConnections)(nil)
+
+type voiceConnections struct {
+	cl voiceClient
+
+	idleTimeout     time.Duration
+	speakingTimeout time.Duration
+	frameTimeout    time.Duration
+	joinAttempts    int
+
+	mu     sync.Mutex
+	guilds map[string]*guildVoice
+}
+
+// guildVoice is a guild's connection state, guarded by its lock for the length of each send
+type guildVoice struct {
+	sync.Mutex
+	conn       voiceConn
+	speaking   bool
+	lastSend   time.Time
+	quietTimer *time.Timer
+	idleTimer  *time.Timer
+}
+
+// NewVoiceConnectionManager disconnects from a guild's voice channel after idleTimeout without audio
+func NewVoiceConnectionManager(cl *discordgo.Session, idleTimeout time.Duration) *voiceConnections {
+	return newVoiceConnections(discordVoiceClient{cl}, idleTimeout)
+}
+
+func newVoiceConnections(cl voiceClient, idleTimeout time.Duration) *voiceConnections {
+	return &voiceConnections{
+		cl:              cl,
+		idleTimeout:     idleTimeout,
+		speakingTimeout: speakingIdleTimeout,
+		frameTimeout:    frameSendTimeout,
+		joinAttempts:    voiceJoinAttempts,
+		guilds:          make(map[string]*guildVoice),
+	}
+}
+
+func (m *voiceConnections) guild(gID string) *guildVoice {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	g, exists := m.guilds[gID]
+	if !exists {
+		g = &guildVoice{}
+		m.guilds[gID] = g
+	}
+	return g
+}
+
+func (m *voiceConnections) Send(ctx context.Context, gID string, cID pomomo.VoiceChannelID, packets [][]byte) error {
+	g := m.guild(gID)
+	g.Lock()
+	defer g.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	conn, err := m.connect(g, gID, cID)
+	if err != nil {
+		return err
+	}
+	defer m.touch(g, gID)
+
+	stalled := time.NewTimer(m.frameTimeout)
+	defer stalled.Stop()
+	reconnected := false
+	for i := 0; i < len(packets); {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case conn.frames() <- packets[i]:
+			i++
+			stalled.Reset(m.frameTimeout)
+		case <-stalled.C:
+			// the connection closed under us, e.g. after a voice server change it couldn't recover from
+			if reconnected {
+				return fmt.Errorf("voice connection to %s stopped taking audio", cID)
+			}
+			log.Warn("voice connection stopped taking audio - reconnecting", "guildID", gID, "channelID", cID)
+			if err := m.disconnect(g, gID); err != nil {
+				log.Debug("failed to disconnect stalled voice connection", "guildID", gID, "err", err)
+			}
+			if conn, err = m.connect(g, gID, cID); err != nil {
+				return err
+			}
+			reconnected = true
+			stalled.Reset(m.frameTimeout)
+		}
+	}
+	return nil
+}
+
+// connect returns a ready connection to cID that's speaking, reusing the guild's connection if it's
+// already there and otherwise joining, retrying from a fresh connection if that fails
+func (m *voiceConnections) connect(g *guildVoice, gID string, cID pomomo.VoiceChannelID) (voiceConn, error) {
+	if conn := m.cl.current(gID); conn != nil && conn == g.conn && conn.ready(string(cID)) {
+		return conn, m.speak(g)
+	}
+
+	var err error
+	for attempt := range m.joinAttempts {
+		if attempt > 0 {
+			if err := m.disconnect(g, gID); err != nil {
+				log.Debug("failed to disconnect before rejoining voice", "guildID", gID, "err", err)
+			}
+		}
+		var conn voiceConn
+		conn, err = m.cl.join(gID, string(cID))
+		if err == nil {
+			// joining reopens the voice websocket, which drops the speaking state
+			g.conn, g.speaking = conn, false
+			return conn, m.speak(g)
+		}
+		log.Warn("failed to join voice channel", "guildID", gID, "channelID", cID, "attempt", attempt+1, "err", err)
+	}
+	return nil, err
+}
+
+func (m *voiceConnections) speak(g *guildVoice) error {
+	if g.speaking {
+		return nil
+	}
+	if err := g.conn.speaking(true); err != nil {
+		return err
+	}
+	g.speaking = true
+	return nil
+}
+
+// touch records a send and restarts the timers that turn off speaking and disconnect once idle
+func (m *voiceConnections) touch(g *guildVoice, gID string) {
+	g.lastSend = time.Now()
+	if g.quietTimer == nil {
+		g.quietTimer = time.AfterFunc(m.speakingTimeout, func() { m.quiet(gID) })
+		g.idleTimer = time.AfterFunc(m.idleTimeout, func() { m.expire(gID) })
+		return
+	}
+	g.quietTimer.Reset(m.speakingTimeout)
+	g.idleTimer.Reset(m.idleTimeout)
+}
+
+func (m *voiceConnections) quiet(gID string) {
+	g := m.guild(gID)
+	g.Lock()
+	defer g.Unlock()
+	if !g.speaking || g.conn == nil || time.Since(g.lastSend) < m.speakingTimeout {
+		return
+	}
+	if err := g.conn.speaking(false); err != nil {
+		log.Debug("failed to stop speaking", "guildID", gID, "err", err)
+	}
+	g.speaking = false
+}
+
+func (m *voiceConnections) expire(gID string) {
+	g := m.guild(gID)
+	g.Lock()
+	defer g.Unlock()
+	if time.Since(g.lastSend) < m.idleTimeout {
+		return
+	}
+	log.Debug("disconnecting idle voice connection", "guildID", gID)
+	if err := m.disconnect(g, gID); err != nil {
+		log.Error("failed to disconnect idle voice connection", "guildID", gID, "err", err)
+	}
+}
+
+func (m *voiceConnections) Disconnect(gID string, cID pomomo.VoiceChannelID) error {
+	g := m.guild(gID)
+	g.Lock()
+	defer g.Unlock()
+	conn := m.cl.current(gID)
+	if conn == nil {
+		return nil
+	}
+	if conn.channelID() != string(cID) {
+		// moved to another session's channel
+		return nil
+	}
+	return m.disconnect(g, gID)
+}
+
+// disconnect leaves the guild's voice channel, whichever it is - g must be locked
+func (m *voiceConnections) disconnect(g *guildVoice, gID string) error {
+	g.conn, g.speaking = nil, false
+	if conn := m.cl.current(gID); conn != nil {
+		return conn.disconnect()
+	}
+	return nil
+}
+
+type discordVoiceClient struct {
+	cl *discordgo.Session
+}
+
+func (c discordVoiceClient) join(gID, cID string) (voiceConn, error) {
+	conn, err := c.cl.ChannelVoiceJoin(gID, cID, false, true)
+	if err != nil {
+		return nil, err
+	}
+	return discordVoiceConn{conn}, nil
+}
+
+func (c discordVoiceClient) current(gID string) voiceConn {
+	c.cl.RLock()
+	defer c.cl.RUnlock()
+	if conn := c.cl.VoiceConnections[gID]; conn != nil {
+		return discordVoiceConn{conn}
+	}
+	return nil
+}
+
+// discordVoiceConn compares equal for the same connection
+type discordVoiceConn struct {
+	*discordgo.VoiceConnection
+}
+
+func (c discordVoiceConn) ready(cID string) bool {
+	c.RLock()
+	defer c.RUnlock()
+	return c.Ready && c.ChannelID == cID
+}
+
+func (c discordVoiceConn) channelID() string {
+	c.RLock()
+	defer c.RUnlock()
+	return c.ChannelID
+}
+
+func (c discordVoiceConn) speaking(b bool) error {
+	return c.Speaking(b)
+}
+
+func (c discordVoiceConn) frames() chan<- []byte {
+	return c.OpusSend
+}
+
+func (c discordVoiceConn) disconnect() error {
+	return c.Disconnect()
+}
@@ -0,0 +1,362 @@
+package discordgo
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/benjamonnguyen/pomomo-go"
+)
+
+// fakeVoiceClient moves a guild's one connection between channels on join like discord does
+type fakeVoiceClient struct {
+	mu      sync.Mutex
+	conns   map[string]*fakeVoiceConn
+	created []*fakeVoiceConn
+	joins   int
+	// failJoins fails that many joins before succeeding
+	failJoins int
+	// stallAfter makes new connections stop taking frames after that many, if set
+	stallAfter int
+}
+
+func newFakeVoiceClient() *fakeVoiceClient {
+	return &fakeVoiceClient{conns: make(map[string]*fakeVoiceConn)}
+}
+
+func (c *fakeVoiceClient) join(gID, cID string) (voiceConn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.joins++
+	conn := c.conns[gID]
+	if conn == nil || conn.isDisconnected() {
+		conn = newFakeVoiceConn(c.stallAfter)
+		c.conns[gID] = conn
+		c.created = append(c.created, conn)
+	}
+	if c.failJoins > 0 {
+		// failed joins leave the connection behind
+		c.failJoins--
+		return nil, fmt.Errorf("timeout waiting for voice")
+	}
+	conn.mu.Lock()
+	conn.cID, conn.isReady = cID, true
+	conn.mu.Unlock()
+	return conn, nil
+}
+
+func (c *fakeVoiceClient) current(gID string) voiceConn {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if conn := c.conns[gID]; conn != nil && !conn.isDisconnected() {
+		return conn
+	}
+	return nil
+}
+
+func (c *fakeVoiceClient) conn(gID string) *fakeVoiceConn {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conns[gID]
+}
+
+func (c *fakeVoiceClient) joinCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.joins
+}
+
+type fakeVoiceConn struct {
+	mu           sync.Mutex
+	cID          string
+	isReady      bool
+	isSpeaking   bool
+	disconnected bool
+	stalled      bool
+	sent         [][]byte
+
+	opusSend chan []byte
+	// dead is returned by frames once the connection stalls - nothing reads it
+	dead chan []byte
+}
+
+func newFakeVoiceConn(stallAfter int) *fakeVoiceConn {
+	c := &fakeVoiceConn{opusSend: make(chan []byte), dead: make(chan []byte)}
+	go func() {
+		for f := range c.opusSend {
+			c.mu.Lock()
+			c.sent = append(c.sent, f)
+			stalled := stallAfter > 0 && len(c.sent) >= stallAfter
+			c.stalled = stalled
+			c.mu.Unlock()
+			if stalled {
+				return
+			}
+		}
+	}()
+	return c
+}
+
+func (c *fakeVoiceConn) ready(cID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.isReady && c.cID == cID
+}
+
+func (c *fakeVoiceConn) channelID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cID
+}
+
+func (c *fakeVoiceConn) speaking(b bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.isSpeaking = b
+	return nil
+}
+
+func (c *fakeVoiceConn) frames() chan<- []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stalled {
+		return c.dead
+	}
+	return c.opusSend
+}
+
+func (c *fakeVoiceConn) disconnect() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.disconnected, c.isReady, c.isSpeaking = true, false, false
+	return nil
+}
+
+func (c *fakeVoiceConn) isDisconnected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.disconnected
+}
+
+func (c *fakeVoiceConn) isSpeakingNow() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.isSpeaking
+}
+
+func (c *fakeVoiceConn) sentFrames() [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([][]byte(nil), c.sent...)
+}
+
+// waitSent returns the frames the connection took once there are n - the last frame of a send is
+// recorded just after the send returns
+func (c *fakeVoiceConn) waitSent(t *testing.T, n int) [][]byte {
+	t.Helper()
+	eventually(t, fmt.Sprintf("%d frames", n), func() bool { return len(c.sentFrames()) >= n })
+	time.Sleep(10 * time.Millisecond) // in case more come
+	return c.sentFrames()
+}
+
+func testPackets(tag string, n int) [][]byte {
+	packets := make([][]byte, n)
+	for i := range packets {
+		packets[i] = fmt.Appendf(nil, "%s%d", tag, i)
+	}
+	return packets
+}
+
+func TestVoiceConnectionsReuse(t *testing.T) {
+	cl := newFakeVoiceClient()
+	m := newVoiceConnections(cl, time.Minute)
+	ctx := context.Background()
+
+	for range 3 {
+		if err := m.Send(ctx, "g1", "c1", testPackets("a", 5)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := cl.joinCount(); n != 1 {
+		t.Errorf("joined %d times for one channel, want 1", n)
+	}
+	if n := len(cl.conn("g1").waitSent(t, 15)); n != 15 {
+		t.Errorf("sent %d frames, want 15", n)
+	}
+
+	// guilds have their own connections
+	if err := m.Send(ctx, "g2", "c3", testPackets("b", 5)); err != nil {
+		t.Fatal(err)
+	}
+	if n := cl.joinCount(); n != 2 {
+		t.Errorf("joined %d times for two guilds, want 2", n)
+	}
+
+	// the guild's connection moves to other channels
+	if err := m.Send(ctx, "g1", "c2", testPackets("c", 5)); err != nil {
+		t.Fatal(err)
+	}
+	if n := cl.joinCount(); n != 3 {
+		t.Errorf("joined %d times after moving channels, want 3", n)
+	}
+	if cID := cl.conn("g1").channelID(); cID != "c2" {
+		t.Errorf("guild connection is in %s, want c2", cID)
+	}
+
+	// only the channel the connection is in is left
+	if err := m.Disconnect("g1", "c1"); err != nil {
+		t.Fatal(err)
+	}
+	if cl.conn("g1").isDisconnected() {
+		t.Error("disconnected from c2 when leaving c1")
+	}
+	if err := m.Disconnect("g1", "c2"); err != nil {
+		t.Fatal(err)
+	}
+	if !cl.conn("g1").isDisconnected() {
+		t.Error("didn't disconnect from c2")
+	}
+}
+
+func TestVoiceConnectionsSerializeSends(t *testing.T) {
+	cl := newFakeVoiceClient()
+	m := newVoiceConnections(cl, time.Minute)
+
+	const senders, frames = 5, 20
+	var wg sync.WaitGroup
+	for i := range senders {
+		wg.Go(func() {
+			// the guild's channels share one connection too
+			cID := pomomo.VoiceChannelID(fmt.Sprintf("c%d", i%2))
+			if err := m.Send(context.Background(), "g1", cID, testPackets(fmt.Sprintf("%d-", i), frames)); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+
+	sent := cl.conn("g1").waitSent(t, senders*frames)
+	if len(sent) != senders*frames {
+		t.Fatalf("sent %d frames, want %d", len(sent), senders*frames)
+	}
+	for start := 0; start < len(sent); start += frames {
+		var sender int
+		if _, err := fmt.Sscanf(string(sent[start]), "%d-0", &sender); err != nil {
+			t.Fatalf("send starting at frame %d doesn't start with its first frame: %s", start, sent[start])
+		}
+		for i, f := range sent[start : start+frames] {
+			if want := fmt.Sprintf("%d-%d", sender, i); string(f) != want {
+				t.Fatalf("frame %d = %s, want %s - sends were interleaved", start+i, f, want)
+			}
+		}
+	}
+}
+
+func TestVoiceConnectionsReconnect(t *testing.T) {
+	t.Run("stalled connection", func(t *testing.T) {
+		cl := newFakeVoiceClient()
+		cl.stallAfter = 3
+		m := newVoiceConnections(cl, time.Minute)
+		m.frameTimeout = 20 * time.Millisecond
+		if err := m.Send(context.Background(), "g1", "c1", testPackets("a", 5)); err != nil {
+			t.Fatal(err)
+		}
+		if n := cl.joinCount(); n != 2 {
+			t.Fatalf("joined %d times, want 2", n)
+		}
+		stalled, fresh := cl.created[0], cl.created[1]
+		if !stalled.isDisconnected() {
+			t.Error("stalled connection wasn't disconnected")
+		}
+		if sent := stalled.sentFrames(); len(sent) != 3 {
+			t.Errorf("stalled connection took %q, want a0-a2", sent)
+		}
+		// the send picks up where it stalled
+		if sent := fresh.waitSent(t, 2); len(sent) != 2 || string(sent[0]) != "a3" || string(sent[1]) != "a4" {
+			t.Errorf("new connection took %q, want a3 and a4", sent)
+		}
+
+		// and the new connection is reused
+		if err := m.Send(context.Background(), "g1", "c1", testPackets("b", 1)); err != nil {
+			t.Fatal(err)
+		}
+		if n := cl.joinCount(); n != 2 {
+			t.Errorf("joined %d times, want 2", n)
+		}
+	})
+
+	t.Run("stalls again", func(t *testing.T) {
+		cl := newFakeVoiceClient()
+		cl.stallAfter = 1
+		m := newVoiceConnections(cl, time.Minute)
+		m.frameTimeout = 20 * time.Millisecond
+		if err := m.Send(context.Background(), "g1", "c1", testPackets("a", 5)); err == nil {
+			t.Error("Send() succeeded on connections that keep stalling")
+		}
+	})
+
+	t.Run("failed join", func(t *testing.T) {
+		cl := newFakeVoiceClient()
+		cl.failJoins = 1
+		m := newVoiceConnections(cl, time.Minute)
+		if err := m.Send(context.Background(), "g1", "c1", testPackets("a", 5)); err != nil {
+			t.Fatal(err)
+		}
+		if n := cl.joinCount(); n != 2 {
+			t.Errorf("joined %d times, want 2", n)
+		}
+
+		cl.failJoins = m.joinAttempts
+		if err := m.Send(context.Background(), "g2", "c2", testPackets("a", 5)); err == nil {
+			t.Error("Send() succeeded without joining")
+		}
+	})
+}
+
+func TestVoiceConnectionsIdle(t *testing.T) {
+	cl := newFakeVoiceClient()
+	m := newVoiceConnections(cl, 100*time.Millisecond)
+	m.speakingTimeout = 10 * time.Millisecond
+	ctx := context.Background()
+
+	if err := m.Send(ctx, "g1", "c1", testPackets("a", 2)); err != nil {
+		t.Fatal(err)
+	}
+	conn := cl.conn("g1")
+	eventually(t, "speaking stopped", func() bool { return !conn.isSpeakingNow() })
+
+	// sending keeps the connection alive past the timeout
+	for range 3 {
+		time.Sleep(50 * time.Millisecond)
+		if err := m.Send(ctx, "g1", "c1", testPackets("b", 2)); err != nil {
+			t.Fatal(err)
+		}
+		if conn.isDisconnected() {
+			t.Fatal("disconnected while sending")
+		}
+	}
+	if n := cl.joinCount(); n != 1 {
+		t.Errorf("joined %d times, want 1", n)
+	}
+
+	eventually(t, "idle disconnect", conn.isDisconnected)
+	if err := m.Send(ctx, "g1", "c1", testPackets("c", 2)); err != nil {
+		t.Fatal(err)
+	}
+	if n := cl.joinCount(); n != 2 {
+		t.Errorf("joined %d times after the idle disconnect, want 2", n)
+	}
+}
+
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -9,6 +9,7 @@ require (
 	github.com/bwmarrin/discordgo v0.29.0
 	github.com/charmbracelet/log v0.4.2
 	github.com/google/uuid v1.6.0
+	github.com/thesyncim/gopus v0.1.2
 	modernc.org/sqlite v1.39.1
 )
 
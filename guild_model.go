@@ -0,0 +1,81 @@
+package pomomo
+
+import (
+	"slices"
+	"time"
+)
+
+// GuildSettingsRecord holds a guild's admin-configured settings
+type GuildSettingsRecord struct {
+	GuildID string
+
+	//
+	ModeratorRoleID string // members with this role can manage any session
+	Timezone        string // IANA name that schedules run in; empty for UTC
+
+	// session defaults
+	DefaultPomodoro   time.Duration
+	DefaultShortBreak time.Duration
+	DefaultLongBreak  time.Duration
+	DefaultIntervals  int
+	DefaultNoMute     bool
+	DefaultNoDeafen   bool
+	DefaultVolume     int // alert volume percent
+
+	// limits
+	MaxSessionDuration time.Duration    // zero allows sessions to run indefinitely
+	AllowedTextCIDs    []TextChannelID  // empty allows all text channels
+	AllowedVoiceCIDs   []VoiceChannelID // empty allows all voice channels
+}
+
+type ExistingGuildSettingsRecord struct {
+	ExistingRecord[string]
+	GuildSettingsRecord
+}
+
+// NewGuildSettingsRecord returns the settings for a guild that hasn't configured anything
+func NewGuildSettingsRecord(guildID string) GuildSettingsRecord {
+	return GuildSettingsRecord{
+		GuildID:           guildID,
+		DefaultPomodoro:   20 * time.Minute,
+		DefaultShortBreak: 5 * time.Minute,
+		DefaultLongBreak:  15 * time.Minute,
+		DefaultIntervals:  4,
+		DefaultVolume:     DefaultVolume,
+	}
+}
+
+// SessionDefaults returns the settings a session starts with before command options are applied
+func (g GuildSettingsRecord) SessionDefaults() SessionSettingsRecord {
+	return SessionSettingsRecord{
+		Pomodoro:    g.DefaultPomodoro,
+		ShortBreak:  g.DefaultShortBreak,
+		LongBreak:   g.DefaultLongBreak,
+		Intervals:   g.DefaultIntervals,
+		NoMute:      g.DefaultNoMute,
+		NoDeafen:    g.DefaultNoDeafen,
+		MaxDuration: g.MaxSessionDuration,
+		BreakRatio:  DefaultBreakRatio,
+		Volume:      g.DefaultVolume,
+	}
+}
+
+// Location returns the guild's timezone, falling back to UTC if it's unset or unknown
+func (g GuildSettingsRecord) Location() *time.Location {
+	if g.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(g.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+func (g GuildSettingsRecord) AllowsTextChannel(cid TextChannelID) bool {
+	return len(g.AllowedTextCIDs) == 0 || slices.Contains(g.AllowedTextCIDs, cid)
+}
+
+func (g GuildSettingsRecord) AllowsVoiceChannel(cid VoiceChannelID) bool {
+	return len(g.AllowedVoiceCIDs) == 0 || slices.Contains(g.AllowedVoiceCIDs, cid)
+}
@@ -0,0 +1,31 @@
+package pomomo
+
+import "testing"
+
+func TestGuildSettingsAllowsChannel(t *testing.T) {
+	restricted := NewGuildSettingsRecord("g1")
+	restricted.AllowedTextCIDs = []TextChannelID{"t1", "t2"}
+	restricted.AllowedVoiceCIDs = []VoiceChannelID{"v1"}
+	tests := []struct {
+		name      string
+		settings  GuildSettingsRecord
+		text      TextChannelID
+		voice     VoiceChannelID
+		wantText  bool
+		wantVoice bool
+	}{
+		{"unrestricted", NewGuildSettingsRecord("g1"), "t3", "v3", true, true},
+		{"allowed", restricted, "t2", "v1", true, true},
+		{"not allowed", restricted, "t3", "v2", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.settings.AllowsTextChannel(tt.text); got != tt.wantText {
+				t.Errorf("AllowsTextChannel(%v) = %v, want %v", tt.text, got, tt.wantText)
+			}
+			if got := tt.settings.AllowsVoiceChannel(tt.voice); got != tt.wantVoice {
+				t.Errorf("AllowsVoiceChannel(%v) = %v, want %v", tt.voice, got, tt.wantVoice)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package pomomo
+
+import "time"
+
+// SessionPresetRecord is a named set of session settings saved by a user or for a whole guild
+type SessionPresetRecord struct {
+	GuildID string
+	UserID  string // empty for presets shared with the whole guild
+	Name    string
+
+	//
+	Pomodoro   time.Duration
+	ShortBreak time.Duration
+	LongBreak  time.Duration
+	Intervals  int
+	NoMute     bool
+	NoDeafen   bool
+}
+
+// BuiltinPresets are available in every guild
+var BuiltinPresets = []SessionPresetRecord{
+	{Name: "Classic 25/5", Pomodoro: 25 * time.Minute, ShortBreak: 5 * time.Minute, LongBreak: 15 * time.Minute, Intervals: 4},
+	{Name: "52/17", Pomodoro: 52 * time.Minute, ShortBreak: 17 * time.Minute, LongBreak: 30 * time.Minute, Intervals: 4},
+	{Name: "Deep Work 90/20", Pomodoro: 90 * time.Minute, ShortBreak: 20 * time.Minute, LongBreak: 30 * time.Minute, Intervals: 2},
+}
+
+// Apply returns settings with the preset's values applied
+func (p SessionPresetRecord) Apply(settings SessionSettingsRecord) SessionSettingsRecord {
+	settings.Pomodoro = p.Pomodoro
+	settings.ShortBreak = p.ShortBreak
+	settings.LongBreak = p.LongBreak
+	settings.Intervals = p.Intervals
+	settings.NoMute = p.NoMute
+	settings.NoDeafen = p.NoDeafen
+	return settings
+}
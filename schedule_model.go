@@ -0,0 +1,155 @@
+package pomomo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ScheduleID string
+
+// WeekdaySet is a bitmask of weekdays where bit i is set for time.Weekday(i)
+type WeekdaySet uint8
+
+const (
+	Weekdays WeekdaySet = 1<<time.Monday | 1<<time.Tuesday | 1<<time.Wednesday | 1<<time.Thursday | 1<<time.Friday
+	Weekends WeekdaySet = 1<<time.Saturday | 1<<time.Sunday
+	Everyday            = Weekdays | Weekends
+)
+
+func (w WeekdaySet) Has(day time.Weekday) bool {
+	return w&(1<<day) != 0
+}
+
+func (w WeekdaySet) String() string {
+	switch w {
+	case Everyday:
+		return "Every day"
+	case Weekdays:
+		return "Weekdays"
+	case Weekends:
+		return "Weekends"
+	}
+	var days []string
+	// weeks start on Monday
+	for i := range 7 {
+		day := time.Weekday((i + 1) % 7)
+		if w.Has(day) {
+			days = append(days, day.String()[:3])
+		}
+	}
+	return strings.Join(days, ", ")
+}
+
+var weekdayNames = map[string]WeekdaySet{
+	"daily":    Everyday,
+	"everyday": Everyday,
+	"weekdays": Weekdays,
+	"weekends": Weekends,
+}
+
+func init() {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		weekdayNames[name] = 1 << day
+		weekdayNames[name[:3]] = 1 << day
+	}
+}
+
+// ParseWeekdays parses "daily", "weekdays", "weekends" or a list of days such as "mon, wed, fri"
+func ParseWeekdays(s string) (WeekdaySet, error) {
+	var set WeekdaySet
+	for _, field := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return r == ',' || r == ' '
+	}) {
+		days, ok := weekdayNames[field]
+		if !ok {
+			return 0, fmt.Errorf("unknown day \"%s\"", field)
+		}
+		set |= days
+	}
+	if set == 0 {
+		return 0, fmt.Errorf("no days given")
+	}
+	return set, nil
+}
+
+// Recurrence fires at a local time of day on a set of weekdays
+type Recurrence struct {
+	Days         WeekdaySet
+	Hour, Minute int
+}
+
+// ParseRecurrence parses days as in ParseWeekdays and at as a 24-hour "HH:MM" time
+func ParseRecurrence(days, at string) (Recurrence, error) {
+	set, err := ParseWeekdays(days)
+	if err != nil {
+		return Recurrence{}, err
+	}
+	h, m, err := ParseTimeOfDay(at)
+	if err != nil {
+		return Recurrence{}, err
+	}
+	return Recurrence{Days: set, Hour: h, Minute: m}, nil
+}
+
+// ParseTimeOfDay parses a 24-hour "HH:MM" time
+func ParseTimeOfDay(at string) (hour, minute int, err error) {
+	h, m, ok := strings.Cut(strings.TrimSpace(at), ":")
+	hour, hErr := strconv.Atoi(h)
+	minute, mErr := strconv.Atoi(m)
+	if !ok || hErr != nil || mErr != nil || hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return 0, 0, fmt.Errorf("time must be 24-hour HH:MM, e.g. 09:00")
+	}
+	return hour, minute, nil
+}
+
+// Next returns the first fire time strictly after t in loc
+func (r Recurrence) Next(t time.Time, loc *time.Location) time.Time {
+	if r.Days == 0 {
+		return time.Time{}
+	}
+	local := t.In(loc)
+	for i := range 8 {
+		day := local.AddDate(0, 0, i)
+		next := time.Date(day.Year(), day.Month(), day.Day(), r.Hour, r.Minute, 0, 0, loc)
+		if wall := wallClock(next); wall.Hour() != r.Hour || wall.Minute() != r.Minute {
+			// the time is skipped when clocks go forward so it's moved forward by the gap
+			want := time.Date(day.Year(), day.Month(), day.Day(), r.Hour, r.Minute, 0, 0, time.UTC)
+			next = next.Add(want.Sub(wall))
+		}
+		if r.Days.Has(next.Weekday()) && next.After(t) {
+			return next
+		}
+	}
+	return time.Time{}
+}
+
+// wallClock returns t's local date and time of day as a UTC time for comparing them across offsets
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+func (r Recurrence) String() string {
+	return fmt.Sprintf("%s at %02d:%02d", r.Days, r.Hour, r.Minute)
+}
+
+// SessionScheduleRecord starts a session in a guild's channels on a recurring schedule
+type SessionScheduleRecord struct {
+	GuildID       string
+	TextCID       TextChannelID
+	VoiceCID      VoiceChannelID
+	CreatorUserID string
+
+	//
+	Recurrence Recurrence
+	PingRoleID string // role mentioned when the session starts, if any
+	PresetName string // applied over the guild defaults, if any
+	NextFireAt time.Time
+}
+
+type ExistingSessionScheduleRecord struct {
+	ExistingRecord[ScheduleID]
+	SessionScheduleRecord
+}
@@ -0,0 +1,129 @@
+package pomomo
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestParseWeekdays(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    WeekdaySet
+		wantErr bool
+	}{
+		{"daily", Everyday, false},
+		{"Everyday", Everyday, false},
+		{"weekdays", Weekdays, false},
+		{"WEEKENDS", Weekends, false},
+		{"mon, wed fri", 1<<time.Monday | 1<<time.Wednesday | 1<<time.Friday, false},
+		{"saturday,sun", Weekends, false},
+		{"weekdays, sat", Weekdays | 1<<time.Saturday, false},
+		{"mon,,mon", 1 << time.Monday, false},
+		{"", 0, true},
+		{" , ", 0, true},
+		{"funday", 0, true},
+		{"mon, tues", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseWeekdays(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseWeekdays() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseWeekdays() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRecurrence(t *testing.T) {
+	tests := []struct {
+		days, at string
+		want     Recurrence
+		wantErr  bool
+	}{
+		{"weekdays", "09:00", Recurrence{Days: Weekdays, Hour: 9}, false},
+		{"sun", " 23:59 ", Recurrence{Days: 1 << time.Sunday, Hour: 23, Minute: 59}, false},
+		{"daily", "0:05", Recurrence{Days: Everyday, Minute: 5}, false},
+		{"daily", "24:00", Recurrence{}, true},
+		{"daily", "12:60", Recurrence{}, true},
+		{"daily", "-1:00", Recurrence{}, true},
+		{"daily", "9", Recurrence{}, true},
+		{"daily", "9am", Recurrence{}, true},
+		{"daily", "", Recurrence{}, true},
+		{"someday", "09:00", Recurrence{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.days+" "+tt.at, func(t *testing.T) {
+			got, err := ParseRecurrence(tt.days, tt.at)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRecurrence() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseRecurrence() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecurrenceNext(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	santiago, err := time.LoadLocation("America/Santiago")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, ny)
+	}
+	// 2026-10-12 is a Monday
+	tests := []struct {
+		name string
+		r    Recurrence
+		t    time.Time
+		want time.Time
+	}{
+		{"later today", Recurrence{Days: Weekdays, Hour: 9}, at(2026, 10, 12, 8, 0), at(2026, 10, 12, 9, 0)},
+		{"at fire time", Recurrence{Days: Weekdays, Hour: 9}, at(2026, 10, 12, 9, 0), at(2026, 10, 13, 9, 0)},
+		{"earlier today", Recurrence{Days: Weekdays, Hour: 9}, at(2026, 10, 12, 9, 1), at(2026, 10, 13, 9, 0)},
+		{"friday to monday", Recurrence{Days: Weekdays, Hour: 9}, at(2026, 10, 16, 10, 0), at(2026, 10, 19, 9, 0)},
+		{"saturday to monday", Recurrence{Days: 1 << time.Monday, Hour: 9}, at(2026, 10, 17, 10, 0), at(2026, 10, 19, 9, 0)},
+		{"weekends", Recurrence{Days: Weekends, Hour: 9}, at(2026, 10, 12, 10, 0), at(2026, 10, 17, 9, 0)},
+		{"a week later", Recurrence{Days: 1 << time.Sunday, Hour: 9}, at(2026, 10, 18, 10, 0), at(2026, 10, 25, 9, 0)},
+		{"month wraparound", Recurrence{Days: 1 << time.Monday, Hour: 9}, at(2026, 10, 27, 10, 0), at(2026, 11, 2, 9, 0)},
+		{"year wraparound", Recurrence{Days: Everyday, Hour: 0, Minute: 30}, at(2026, 12, 31, 23, 0), at(2027, 1, 1, 0, 30)},
+		// Monday 22:00 in New York is already Tuesday in UTC
+		{"utc input", Recurrence{Days: 1 << time.Monday, Hour: 23}, at(2026, 10, 12, 22, 0).UTC(), at(2026, 10, 12, 23, 0)},
+		{"utc input next day", Recurrence{Days: 1 << time.Tuesday, Hour: 1}, at(2026, 10, 12, 22, 0).UTC(), at(2026, 10, 13, 1, 0)},
+		// clocks go forward an hour at 02:00 on 2026-03-08
+		{"spring forward", Recurrence{Days: Everyday, Hour: 9}, at(2026, 3, 7, 10, 0), at(2026, 3, 8, 9, 0)},
+		{"spring forward gap", Recurrence{Days: Everyday, Hour: 2, Minute: 30}, at(2026, 3, 8, 0, 0), at(2026, 3, 8, 3, 30)},
+		// clocks go back an hour at 02:00 on 2026-11-01 so 01:30 happens twice - only the first fires
+		{"fall back", Recurrence{Days: Everyday, Hour: 9}, at(2026, 10, 31, 10, 0), at(2026, 11, 1, 9, 0)},
+		{"fall back repeat", Recurrence{Days: Everyday, Hour: 1, Minute: 30}, at(2026, 11, 1, 0, 0), at(2026, 11, 1, 1, 30)},
+		{"fall back repeat skipped", Recurrence{Days: Everyday, Hour: 1, Minute: 30}, at(2026, 11, 1, 1, 30), at(2026, 11, 2, 1, 30)},
+		{"no days", Recurrence{Hour: 9}, at(2026, 10, 12, 8, 0), time.Time{}},
+		// clocks go forward an hour at midnight on 2026-09-06 so 00:30 would otherwise be the day before
+		{"midnight gap", Recurrence{Days: 1 << time.Sunday, Minute: 30}, time.Date(2026, 9, 5, 12, 0, 0, 0, santiago),
+			time.Date(2026, 9, 6, 1, 30, 0, 0, santiago)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc := tt.want.Location()
+			if tt.want.IsZero() {
+				loc = ny
+			}
+			got := tt.r.Next(tt.t, loc)
+			if !got.Equal(tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+			if !got.IsZero() && (got.In(loc).Day() != tt.want.Day() || got.In(loc).Hour() != tt.want.Hour() || got.In(loc).Minute() != tt.want.Minute()) {
+				t.Errorf("Next() = %v in %v, want %v", got.In(loc), loc, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package pomomo
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -11,8 +14,27 @@ const (
 	SessionRunning
 	SessionPaused
 	SessionEnded
+	// SessionLobby counts down to the first interval while participants join
+	SessionLobby
 )
 
+type SessionMode uint8
+
+const (
+	ClassicMode SessionMode = iota
+	// FlowtimeMode counts focus up until the user takes a break proportional to it
+	FlowtimeMode
+)
+
+func (m SessionMode) String() string {
+	switch m {
+	case FlowtimeMode:
+		return "Flowtime"
+	default:
+		return "Classic"
+	}
+}
+
 type SessionInterval uint8
 
 const (
@@ -20,6 +42,7 @@ const (
 	PomodoroInterval
 	ShortBreakInterval
 	LongBreakInterval
+	WarmUpInterval
 )
 
 func (i SessionInterval) String() string {
@@ -30,11 +53,105 @@ func (i SessionInterval) String() string {
 		return "Short Break"
 	case LongBreakInterval:
 		return "Long Break"
+	case WarmUpInterval:
+		return "Warm-up"
 	default:
 		panic("no matching enum for SessionInterval: " + string(i))
 	}
 }
 
+// IsBreak reports whether the interval is a short or long break
+func (i SessionInterval) IsBreak() bool {
+	return i == ShortBreakInterval || i == LongBreakInterval
+}
+
+// IntervalStep is a single interval in a custom sequence
+type IntervalStep struct {
+	Interval SessionInterval
+	Duration time.Duration
+}
+
+// IntervalSequence is an ordered list of steps that sessions loop through in place of the
+// pomodoro, short break, long break cycle
+type IntervalSequence []IntervalStep
+
+const (
+	MaxSequenceSteps    = 20
+	MaxIntervalDuration = 240 * time.Minute
+)
+
+const (
+	DefaultVolume = 100
+	MinVolume     = 10
+	MaxVolume     = 200
+)
+
+var intervalStepNames = map[string]SessionInterval{
+	"pomodoro": PomodoroInterval,
+	"focus":    PomodoroInterval,
+	"short":    ShortBreakInterval,
+	"break":    ShortBreakInterval,
+	"long":     LongBreakInterval,
+	"warmup":   WarmUpInterval,
+	"warm-up":  WarmUpInterval,
+}
+
+// ParseIntervalSequence parses comma-separated steps of an interval name and minutes,
+// e.g. "warmup 10, focus 50, break 10, focus 50, long 30"
+func ParseIntervalSequence(s string) (IntervalSequence, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var seq IntervalSequence
+	for _, part := range strings.Split(s, ",") {
+		fields := strings.Fields(strings.ToLower(part))
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid step %q: expected an interval name and minutes", strings.TrimSpace(part))
+		}
+		interval, ok := intervalStepNames[fields[0]]
+		if !ok {
+			return nil, fmt.Errorf("invalid step %q: unknown interval %q", strings.TrimSpace(part), fields[0])
+		}
+		minutes, err := strconv.Atoi(fields[1])
+		duration := time.Duration(minutes) * time.Minute
+		if err != nil || duration <= 0 || duration > MaxIntervalDuration {
+			return nil, fmt.Errorf("invalid step %q: minutes must be from 1 to %d", strings.TrimSpace(part), int(MaxIntervalDuration.Minutes()))
+		}
+		seq = append(seq, IntervalStep{Interval: interval, Duration: duration})
+	}
+	if len(seq) > MaxSequenceSteps {
+		return nil, fmt.Errorf("sequence can have at most %d steps", MaxSequenceSteps)
+	}
+	hasPomodoro := false
+	for _, step := range seq {
+		hasPomodoro = hasPomodoro || step.Interval == PomodoroInterval
+	}
+	if !hasPomodoro {
+		return nil, fmt.Errorf("sequence needs at least one focus step")
+	}
+	return seq, nil
+}
+
+// String formats the sequence so that it can be parsed by ParseIntervalSequence
+func (seq IntervalSequence) String() string {
+	parts := make([]string, 0, len(seq))
+	for _, step := range seq {
+		var name string
+		switch step.Interval {
+		case PomodoroInterval:
+			name = "focus"
+		case ShortBreakInterval:
+			name = "break"
+		case LongBreakInterval:
+			name = "long"
+		case WarmUpInterval:
+			name = "warmup"
+		}
+		parts = append(parts, fmt.Sprintf("%s %d", name, int(step.Duration.Minutes())))
+	}
+	return strings.Join(parts, ", ")
+}
+
 type (
 	SessionID      string
 	ParticipantID  string
@@ -42,16 +159,28 @@ type (
 	TextChannelID  string
 )
 
+type SessionStats struct {
+	CompletedPomodoros int
+	Skips              int
+	LongBreaks         int
+}
+
 type SessionRecord struct {
 	GuildID, MessageID string
 	VoiceCID           VoiceChannelID
 	TextCID            TextChannelID
+	HostUserID         string
+	ScheduleID         ScheduleID // schedule that started the session, if any
 
 	//
 	IntervalStartedAt    time.Time
 	TimeRemainingAtStart time.Duration
 	CurrentInterval      SessionInterval
+	SequenceIndex        int           // step of the settings' sequence, if any
+	BreakDuration        time.Duration // length of the current flowtime break
 	Status               SessionStatus
+	Stats                SessionStats
+	EndedAt              time.Time // zero until the session ends
 }
 
 type ExistingSessionRecord struct {
@@ -59,6 +188,17 @@ type ExistingSessionRecord struct {
 	SessionRecord
 }
 
+// SessionFilter narrows session queries; zero-valued fields are ignored
+type SessionFilter struct {
+	GuildID  string
+	TextCID  TextChannelID
+	VoiceCID VoiceChannelID
+	Statuses []SessionStatus
+
+	// CreatedAfter and CreatedBefore bound when the session was started
+	CreatedAfter, CreatedBefore time.Time
+}
+
 type VoiceState struct {
 	Mute, Deaf bool
 }
@@ -86,9 +226,42 @@ type SessionSettingsRecord struct {
 	Intervals  int
 	NoMute     bool
 	NoDeafen   bool
+
+	// MaxDuration ends the session once it has run this long; zero means no limit
+	MaxDuration time.Duration
+	// Sequence replaces the durations and intervals above when set
+	Sequence IntervalSequence
+
+	Mode SessionMode
+	// BreakRatio is the flowtime break length as a fraction of the focus before it
+	BreakRatio float64
+
+	// goals end the session once reached; zero values mean no goal
+	GoalRounds int       // completed pomodoros
+	GoalUntil  time.Time // time of day, already resolved to the guild's timezone
+
+	// Warning alerts participants this long before each interval ends; zero means no warning
+	Warning time.Duration
+	// Ambient loops the guild's ambient track in the voice channel during pomodoros
+	Ambient bool
+	// Volume scales the alerts as a percent of the sounds' own volume
+	Volume int
 }
 
+const DefaultBreakRatio = 0.2
+
 type ExistingSessionSettingsRecord struct {
 	ExistingRecord[SessionID]
 	SessionSettingsRecord
 }
+
+// SessionParticipantStatsRecord tracks a user's participation in a single session
+type SessionParticipantStatsRecord struct {
+	SessionID SessionID
+	UserID    string
+
+	//
+	FocusTime time.Duration
+	JoinedAt  time.Time
+	LeftAt    time.Time // zero if the user was still participating when the session ended
+}
@@ -0,0 +1,65 @@
+package pomomo
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseIntervalSequence(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    IntervalSequence
+		wantErr bool
+	}{
+		{"empty", "", nil, false},
+		{"blank", "  ", nil, false},
+		{"example", "warmup 10, focus 50, break 10, focus 50, long 30", IntervalSequence{
+			{WarmUpInterval, 10 * time.Minute},
+			{PomodoroInterval, 50 * time.Minute},
+			{ShortBreakInterval, 10 * time.Minute},
+			{PomodoroInterval, 50 * time.Minute},
+			{LongBreakInterval, 30 * time.Minute},
+		}, false},
+		{"aliases", "Warm-up 5,POMODORO 25 ,  short 5,long 15", IntervalSequence{
+			{WarmUpInterval, 5 * time.Minute},
+			{PomodoroInterval, 25 * time.Minute},
+			{ShortBreakInterval, 5 * time.Minute},
+			{LongBreakInterval, 15 * time.Minute},
+		}, false},
+		{"single focus", "focus 240", IntervalSequence{{PomodoroInterval, MaxIntervalDuration}}, false},
+		{"max steps", strings.Repeat("focus 1, ", MaxSequenceSteps-1) + "focus 1", slices.Repeat(IntervalSequence{{PomodoroInterval, time.Minute}}, MaxSequenceSteps), false},
+		{"too many steps", strings.Repeat("focus 1, ", MaxSequenceSteps) + "focus 1", nil, true},
+		{"no focus", "warmup 10, break 5, long 15", nil, true},
+		{"missing minutes", "focus 25, break", nil, true},
+		{"extra field", "focus 25 min", nil, true},
+		{"empty step", "focus 25,, break 5", nil, true},
+		{"trailing comma", "focus 25,", nil, true},
+		{"unknown interval", "focus 25, nap 20", nil, true},
+		{"zero minutes", "focus 0", nil, true},
+		{"negative minutes", "focus -5", nil, true},
+		{"over max", "focus 241", nil, true},
+		{"fractional minutes", "focus 2.5", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseIntervalSequence(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseIntervalSequence() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ParseIntervalSequence() = %v, want %v", got, tt.want)
+			}
+			if got == nil {
+				return
+			}
+			// String formats sequences so that they parse back the same
+			again, err := ParseIntervalSequence(got.String())
+			if err != nil || !slices.Equal(again, got) {
+				t.Errorf("ParseIntervalSequence(%q) = %v, %v, want %v", got.String(), again, err, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package pomomo
+
+import "time"
+
+// AlertSound identifies a sound that guilds can upload - an alert replacing the default or the
+// ambient track looped during pomodoros
+type AlertSound string
+
+const (
+	PomodoroSound   AlertSound = "pomodoro"
+	ShortBreakSound AlertSound = "short_break"
+	LongBreakSound  AlertSound = "long_break"
+	WarningSound    AlertSound = "warning"
+	AmbientSound    AlertSound = "ambient"
+)
+
+var AlertSounds = []AlertSound{PomodoroSound, ShortBreakSound, LongBreakSound, WarningSound, AmbientSound}
+
+func (a AlertSound) String() string {
+	switch a {
+	case PomodoroSound:
+		return "Pomodoro"
+	case ShortBreakSound:
+		return "Short Break"
+	case LongBreakSound:
+		return "Long Break"
+	case WarningSound:
+		return "Warning"
+	case AmbientSound:
+		return "Ambient"
+	default:
+		return string(a)
+	}
+}
+
+// GuildSoundRecord is a guild's upload replacing one of the default alert sounds or providing its ambient track
+type GuildSoundRecord struct {
+	GuildID        string
+	Sound          AlertSound
+	UploaderUserID string
+	FileName       string
+
+	//
+	DCA      []byte // opus frames in the DCA format, i.e. each prefixed with its int16 length
+	Duration time.Duration
+}
@@ -0,0 +1,173 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	txStdLib "github.com/Thiht/transactor/stdlib"
+	"github.com/charmbracelet/log"
+
+	"github.com/benjamonnguyen/deadsimple/db/sqliteutil"
+	"github.com/benjamonnguyen/pomomo-go"
+)
+
+const (
+	SelectAllGuildSettings = "SELECT guild_id, moderator_role_id, timezone, default_pomodoro_duration, default_short_break_duration, default_long_break_duration, default_intervals, default_no_mute, default_no_deafen, default_volume, max_session_duration, allowed_text_channel_ids, allowed_voice_channel_ids, created_at, updated_at FROM guild_settings"
+)
+
+type guildSettingsEntity struct {
+	GuildID                   string
+	ModeratorRoleID           string
+	Timezone                  string
+	DefaultPomodoroDuration   int
+	DefaultShortBreakDuration int
+	DefaultLongBreakDuration  int
+	DefaultIntervals          int
+	DefaultNoMute             bool
+	DefaultNoDeafen           bool
+	DefaultVolume             int
+	MaxSessionDuration        int
+	AllowedTextChannelIDs     string // comma-separated
+	AllowedVoiceChannelIDs    string // comma-separated
+	CreatedAt                 int64
+	UpdatedAt                 int64
+}
+
+type guildSettingsRepo struct {
+	dbGetter txStdLib.DBGetter
+	l        log.Logger
+}
+
+func NewGuildSettingsRepo(dbGetter txStdLib.DBGetter, logger log.Logger) *guildSettingsRepo {
+	return &guildSettingsRepo{
+		dbGetter: dbGetter,
+		l:        logger,
+	}
+}
+
+// UpsertGuildSettings creates or replaces the guild's settings
+func (r *guildSettingsRepo) UpsertGuildSettings(ctx context.Context, settings pomomo.GuildSettingsRecord) (pomomo.ExistingGuildSettingsRecord, error) {
+	if settings.GuildID == "" {
+		return pomomo.ExistingGuildSettingsRecord{}, fmt.Errorf("provide required field 'GuildID'")
+	}
+
+	existingRecord := pomomo.ExistingGuildSettingsRecord{
+		GuildSettingsRecord: settings,
+		ExistingRecord:      pomomo.NewExistingRecord[string](settings.GuildID),
+	}
+	e := mapToGuildSettingsEntity(existingRecord)
+	args := []any{
+		e.GuildID,
+		e.ModeratorRoleID,
+		e.Timezone,
+		e.DefaultPomodoroDuration,
+		e.DefaultShortBreakDuration,
+		e.DefaultLongBreakDuration,
+		e.DefaultIntervals,
+		e.DefaultNoMute,
+		e.DefaultNoDeafen,
+		e.DefaultVolume,
+		e.MaxSessionDuration,
+		e.AllowedTextChannelIDs,
+		e.AllowedVoiceChannelIDs,
+		e.CreatedAt,
+		e.UpdatedAt,
+	}
+	query := "INSERT INTO guild_settings (guild_id, moderator_role_id, timezone, default_pomodoro_duration, default_short_break_duration, default_long_break_duration, default_intervals, default_no_mute, default_no_deafen, default_volume, max_session_duration, allowed_text_channel_ids, allowed_voice_channel_ids, created_at, updated_at) VALUES " + sqliteutil.GenerateParameters(len(args)) +
+		" ON CONFLICT (guild_id) DO UPDATE SET moderator_role_id = excluded.moderator_role_id, timezone = excluded.timezone, default_pomodoro_duration = excluded.default_pomodoro_duration, default_short_break_duration = excluded.default_short_break_duration, default_long_break_duration = excluded.default_long_break_duration, default_intervals = excluded.default_intervals, default_no_mute = excluded.default_no_mute, default_no_deafen = excluded.default_no_deafen, default_volume = excluded.default_volume, max_session_duration = excluded.max_session_duration, allowed_text_channel_ids = excluded.allowed_text_channel_ids, allowed_voice_channel_ids = excluded.allowed_voice_channel_ids, updated_at = excluded.updated_at"
+	r.l.Debug("upserting guild settings", "query", query, "args", args)
+	if _, err := r.dbGetter(ctx).ExecContext(ctx, query, args...); err != nil {
+		return pomomo.ExistingGuildSettingsRecord{}, err
+	}
+
+	return r.GetGuildSettings(ctx, settings.GuildID)
+}
+
+func (r *guildSettingsRepo) GetGuildSettings(ctx context.Context, guildID string) (pomomo.ExistingGuildSettingsRecord, error) {
+	if guildID == "" {
+		return pomomo.ExistingGuildSettingsRecord{}, fmt.Errorf("provide guildID")
+	}
+
+	row := r.dbGetter(ctx).QueryRowContext(ctx, SelectAllGuildSettings+" WHERE guild_id = ?", guildID)
+	return extractGuildSettings(row)
+}
+
+func extractGuildSettings(s sqliteutil.Scannable) (pomomo.ExistingGuildSettingsRecord, error) {
+	var e guildSettingsEntity
+	if err := s.Scan(&e.GuildID, &e.ModeratorRoleID, &e.Timezone, &e.DefaultPomodoroDuration, &e.DefaultShortBreakDuration, &e.DefaultLongBreakDuration, &e.DefaultIntervals, &e.DefaultNoMute, &e.DefaultNoDeafen, &e.DefaultVolume, &e.MaxSessionDuration, &e.AllowedTextChannelIDs, &e.AllowedVoiceChannelIDs, &e.CreatedAt, &e.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return pomomo.ExistingGuildSettingsRecord{}, ErrNotFound
+		}
+		return pomomo.ExistingGuildSettingsRecord{}, err
+	}
+
+	return mapToExistingGuildSettingsRecord(e), nil
+}
+
+func mapToGuildSettingsEntity(settings pomomo.ExistingGuildSettingsRecord) guildSettingsEntity {
+	return guildSettingsEntity{
+		GuildID:                   settings.GuildID,
+		ModeratorRoleID:           settings.ModeratorRoleID,
+		Timezone:                  settings.Timezone,
+		DefaultPomodoroDuration:   int(settings.DefaultPomodoro.Seconds()),
+		DefaultShortBreakDuration: int(settings.DefaultShortBreak.Seconds()),
+		DefaultLongBreakDuration:  int(settings.DefaultLongBreak.Seconds()),
+		DefaultIntervals:          settings.DefaultIntervals,
+		DefaultNoMute:             settings.DefaultNoMute,
+		DefaultNoDeafen:           settings.DefaultNoDeafen,
+		DefaultVolume:             settings.DefaultVolume,
+		MaxSessionDuration:        int(settings.MaxSessionDuration.Seconds()),
+		AllowedTextChannelIDs:     joinIDs(settings.AllowedTextCIDs),
+		AllowedVoiceChannelIDs:    joinIDs(settings.AllowedVoiceCIDs),
+		CreatedAt:                 settings.CreatedAt.Unix(),
+		UpdatedAt:                 settings.UpdatedAt.Unix(),
+	}
+}
+
+func mapToExistingGuildSettingsRecord(e guildSettingsEntity) pomomo.ExistingGuildSettingsRecord {
+	return pomomo.ExistingGuildSettingsRecord{
+		ExistingRecord: pomomo.ExistingRecord[string]{
+			ID:        e.GuildID,
+			CreatedAt: time.Unix(e.CreatedAt, 0),
+			UpdatedAt: time.Unix(e.UpdatedAt, 0),
+		},
+		GuildSettingsRecord: pomomo.GuildSettingsRecord{
+			GuildID:            e.GuildID,
+			ModeratorRoleID:    e.ModeratorRoleID,
+			Timezone:           e.Timezone,
+			DefaultPomodoro:    time.Duration(e.DefaultPomodoroDuration) * time.Second,
+			DefaultShortBreak:  time.Duration(e.DefaultShortBreakDuration) * time.Second,
+			DefaultLongBreak:   time.Duration(e.DefaultLongBreakDuration) * time.Second,
+			DefaultIntervals:   e.DefaultIntervals,
+			DefaultNoMute:      e.DefaultNoMute,
+			DefaultNoDeafen:    e.DefaultNoDeafen,
+			DefaultVolume:      e.DefaultVolume,
+			MaxSessionDuration: time.Duration(e.MaxSessionDuration) * time.Second,
+			AllowedTextCIDs:    splitIDs[pomomo.TextChannelID](e.AllowedTextChannelIDs),
+			AllowedVoiceCIDs:   splitIDs[pomomo.VoiceChannelID](e.AllowedVoiceChannelIDs),
+		},
+	}
+}
+
+func joinIDs[T ~string](ids []T) string {
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, string(id))
+	}
+	return strings.Join(parts, ",")
+}
+
+func splitIDs[T ~string](joined string) []T {
+	if joined == "" {
+		return nil
+	}
+	var ids []T
+	for _, id := range strings.Split(joined, ",") {
+		ids = append(ids, T(id))
+	}
+	return ids
+}
@@ -0,0 +1,145 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	txStdLib "github.com/Thiht/transactor/stdlib"
+	"github.com/charmbracelet/log"
+
+	"github.com/benjamonnguyen/deadsimple/db/sqliteutil"
+	"github.com/benjamonnguyen/pomomo-go"
+)
+
+const (
+	SelectAllPresets = "SELECT guild_id, user_id, name, pomodoro_duration, short_break_duration, long_break_duration, intervals, no_mute, no_deafen FROM session_presets"
+)
+
+type presetEntity struct {
+	GuildID            string
+	UserID             string
+	Name               string
+	PomodoroDuration   int
+	ShortBreakDuration int
+	LongBreakDuration  int
+	Intervals          int
+	NoMute             bool
+	NoDeafen           bool
+}
+
+type presetRepo struct {
+	dbGetter txStdLib.DBGetter
+	l        log.Logger
+}
+
+func NewPresetRepo(dbGetter txStdLib.DBGetter, logger log.Logger) *presetRepo {
+	return &presetRepo{
+		dbGetter: dbGetter,
+		l:        logger,
+	}
+}
+
+// UpsertPreset saves the preset, replacing any preset with the same name and scope
+func (r *presetRepo) UpsertPreset(ctx context.Context, preset pomomo.SessionPresetRecord) error {
+	if preset.GuildID == "" || preset.Name == "" {
+		return fmt.Errorf("provide required fields 'GuildID' and 'Name'")
+	}
+
+	now := time.Now().Unix()
+	e := mapToPresetEntity(preset)
+	args := []any{
+		e.GuildID,
+		e.UserID,
+		e.Name,
+		e.PomodoroDuration,
+		e.ShortBreakDuration,
+		e.LongBreakDuration,
+		e.Intervals,
+		e.NoMute,
+		e.NoDeafen,
+		now,
+		now,
+	}
+	query := "INSERT INTO session_presets (guild_id, user_id, name, pomodoro_duration, short_break_duration, long_break_duration, intervals, no_mute, no_deafen, created_at, updated_at) VALUES " + sqliteutil.GenerateParameters(len(args)) +
+		" ON CONFLICT (guild_id, user_id, name) DO UPDATE SET pomodoro_duration = excluded.pomodoro_duration, short_break_duration = excluded.short_break_duration, long_break_duration = excluded.long_break_duration, intervals = excluded.intervals, no_mute = excluded.no_mute, no_deafen = excluded.no_deafen, updated_at = excluded.updated_at"
+	r.l.Debug("upserting preset", "query", query, "args", args)
+	_, err := r.dbGetter(ctx).ExecContext(ctx, query, args...)
+	return err
+}
+
+// DeletePreset returns ErrNotFound if there's no preset with the name and scope
+func (r *presetRepo) DeletePreset(ctx context.Context, guildID, userID, name string) error {
+	query := "DELETE FROM session_presets WHERE guild_id = ? AND user_id = ? AND name = ?"
+	args := []any{guildID, userID, name}
+	r.l.Debug("deleting preset", "query", query, "args", args)
+	res, err := r.dbGetter(ctx).ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetPresets returns the user's presets followed by the guild's shared presets
+func (r *presetRepo) GetPresets(ctx context.Context, guildID, userID string) ([]pomomo.SessionPresetRecord, error) {
+	if guildID == "" {
+		return nil, fmt.Errorf("provide guildID")
+	}
+
+	query := SelectAllPresets + " WHERE guild_id = ? AND user_id IN (?, '') ORDER BY user_id = '', name"
+	args := []any{guildID, userID}
+	r.l.Debug("getting presets", "query", query, "args", args)
+	rows, err := r.dbGetter(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint
+
+	var presets []pomomo.SessionPresetRecord
+	for rows.Next() {
+		var e presetEntity
+		if err := rows.Scan(&e.GuildID, &e.UserID, &e.Name, &e.PomodoroDuration, &e.ShortBreakDuration, &e.LongBreakDuration, &e.Intervals, &e.NoMute, &e.NoDeafen); err != nil {
+			return nil, err
+		}
+		presets = append(presets, mapToPresetRecord(e))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return presets, nil
+}
+
+func mapToPresetEntity(p pomomo.SessionPresetRecord) presetEntity {
+	return presetEntity{
+		GuildID:            p.GuildID,
+		UserID:             p.UserID,
+		Name:               p.Name,
+		PomodoroDuration:   int(p.Pomodoro.Seconds()),
+		ShortBreakDuration: int(p.ShortBreak.Seconds()),
+		LongBreakDuration:  int(p.LongBreak.Seconds()),
+		Intervals:          p.Intervals,
+		NoMute:             p.NoMute,
+		NoDeafen:           p.NoDeafen,
+	}
+}
+
+func mapToPresetRecord(e presetEntity) pomomo.SessionPresetRecord {
+	return pomomo.SessionPresetRecord{
+		GuildID:    e.GuildID,
+		UserID:     e.UserID,
+		Name:       e.Name,
+		Pomodoro:   time.Duration(e.PomodoroDuration) * time.Second,
+		ShortBreak: time.Duration(e.ShortBreakDuration) * time.Second,
+		LongBreak:  time.Duration(e.LongBreakDuration) * time.Second,
+		Intervals:  e.Intervals,
+		NoMute:     e.NoMute,
+		NoDeafen:   e.NoDeafen,
+	}
+}
@@ -0,0 +1,54 @@
+package sqlite
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/benjamonnguyen/pomomo-go"
+)
+
+func TestGetPresets(t *testing.T) {
+	ctx := context.Background()
+	r := NewPresetRepo(openTestDB(t), *log.Default())
+	for _, p := range []pomomo.SessionPresetRecord{
+		{GuildID: "g1", Name: "Reading", Pomodoro: 30 * time.Minute},
+		{GuildID: "g1", UserID: "u1", Name: "Study", Pomodoro: 45 * time.Minute},
+		{GuildID: "g1", Name: "Exam", Pomodoro: 60 * time.Minute},
+		{GuildID: "g1", UserID: "u1", Name: "Coding", Pomodoro: 90 * time.Minute},
+		{GuildID: "g1", UserID: "u2", Name: "Other", Pomodoro: 25 * time.Minute},
+		{GuildID: "g2", Name: "Elsewhere", Pomodoro: 25 * time.Minute},
+		// replaces the first save
+		{GuildID: "g1", UserID: "u1", Name: "Study", Pomodoro: 50 * time.Minute},
+	} {
+		if err := r.UpsertPreset(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	presets, err := r.GetPresets(ctx, "g1", "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range presets {
+		got = append(got, p.Name)
+	}
+	// the user's presets come first so that they shadow the guild's
+	if want := []string{"Coding", "Study", "Exam", "Reading"}; !slices.Equal(got, want) {
+		t.Errorf("GetPresets() = %v, want %v", got, want)
+	}
+	if presets[1].Pomodoro != 50*time.Minute {
+		t.Errorf("Study Pomodoro = %v, want the upserted 50m", presets[1].Pomodoro)
+	}
+
+	if err := r.DeletePreset(ctx, "g1", "u1", "Study"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.DeletePreset(ctx, "g1", "u1", "Study"); err != ErrNotFound {
+		t.Errorf("DeletePreset() again error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,217 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	txStdLib "github.com/Thiht/transactor/stdlib"
+	"github.com/charmbracelet/log"
+	"github.com/google/uuid"
+
+	"github.com/benjamonnguyen/deadsimple/db/sqliteutil"
+	"github.com/benjamonnguyen/pomomo-go"
+)
+
+const (
+	SelectAllSchedules = "SELECT id, guild_id, text_channel_id, voice_channel_id, creator_user_id, weekdays, hour, minute, ping_role_id, preset_name, next_fire_at, created_at, updated_at FROM session_schedules"
+)
+
+type scheduleEntity struct {
+	ID             string
+	GuildID        string
+	TextChannelID  string
+	VoiceChannelID string
+	CreatorUserID  string
+	Weekdays       uint8
+	Hour           int
+	Minute         int
+	PingRoleID     string
+	PresetName     string
+	NextFireAt     int64
+	CreatedAt      int64
+	UpdatedAt      int64
+}
+
+type scheduleRepo struct {
+	dbGetter txStdLib.DBGetter
+	l        log.Logger
+}
+
+func NewScheduleRepo(dbGetter txStdLib.DBGetter, logger log.Logger) *scheduleRepo {
+	return &scheduleRepo{
+		dbGetter: dbGetter,
+		l:        logger,
+	}
+}
+
+func (r *scheduleRepo) InsertSchedule(ctx context.Context, schedule pomomo.SessionScheduleRecord) (pomomo.ExistingSessionScheduleRecord, error) {
+	if schedule.GuildID == "" || schedule.TextCID == "" || schedule.VoiceCID == "" {
+		return pomomo.ExistingSessionScheduleRecord{}, fmt.Errorf("provide required fields 'GuildID', 'TextCID' and 'VoiceCID'")
+	}
+
+	existingRecord := pomomo.ExistingSessionScheduleRecord{
+		SessionScheduleRecord: schedule,
+		ExistingRecord:        pomomo.NewExistingRecord[pomomo.ScheduleID](uuid.NewString()),
+	}
+	e := mapToScheduleEntity(existingRecord)
+	args := []any{
+		e.ID,
+		e.GuildID,
+		e.TextChannelID,
+		e.VoiceChannelID,
+		e.CreatorUserID,
+		e.Weekdays,
+		e.Hour,
+		e.Minute,
+		e.PingRoleID,
+		e.PresetName,
+		e.NextFireAt,
+		e.CreatedAt,
+		e.UpdatedAt,
+	}
+	query := "INSERT INTO session_schedules (id, guild_id, text_channel_id, voice_channel_id, creator_user_id, weekdays, hour, minute, ping_role_id, preset_name, next_fire_at, created_at, updated_at) VALUES " + sqliteutil.GenerateParameters(len(args))
+	r.l.Debug("creating schedule", "query", query, "args", args)
+	if _, err := r.dbGetter(ctx).ExecContext(ctx, query, args...); err != nil {
+		return pomomo.ExistingSessionScheduleRecord{}, err
+	}
+
+	return existingRecord, nil
+}
+
+// UpdateNextFireAt returns ErrNotFound if the schedule has been deleted
+func (r *scheduleRepo) UpdateNextFireAt(ctx context.Context, id pomomo.ScheduleID, next time.Time) error {
+	query := "UPDATE session_schedules SET next_fire_at = ?, updated_at = ? WHERE id = ?"
+	args := []any{next.Unix(), time.Now().Unix(), id}
+	r.l.Debug("updating schedule next fire", "query", query, "args", args)
+	res, err := r.dbGetter(ctx).ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteSchedule returns ErrNotFound if the guild has no schedule with the id
+func (r *scheduleRepo) DeleteSchedule(ctx context.Context, guildID string, id pomomo.ScheduleID) (pomomo.ExistingSessionScheduleRecord, error) {
+	existing, err := r.GetSchedule(ctx, id)
+	if err != nil {
+		return existing, err
+	}
+	if existing.GuildID != guildID {
+		return pomomo.ExistingSessionScheduleRecord{}, ErrNotFound
+	}
+
+	query := "DELETE FROM session_schedules WHERE id = ?"
+	r.l.Debug("deleting schedule", "query", query, "id", id)
+	if _, err := r.dbGetter(ctx).ExecContext(ctx, query, id); err != nil {
+		return pomomo.ExistingSessionScheduleRecord{}, err
+	}
+	return existing, nil
+}
+
+func (r *scheduleRepo) GetSchedule(ctx context.Context, id pomomo.ScheduleID) (pomomo.ExistingSessionScheduleRecord, error) {
+	if id == "" {
+		return pomomo.ExistingSessionScheduleRecord{}, fmt.Errorf("provide id")
+	}
+
+	row := r.dbGetter(ctx).QueryRowContext(ctx, SelectAllSchedules+" WHERE id = ?", id)
+	return extractSchedule(row)
+}
+
+// GetSchedules returns the guild's schedules, soonest first
+func (r *scheduleRepo) GetSchedules(ctx context.Context, guildID string) ([]pomomo.ExistingSessionScheduleRecord, error) {
+	if guildID == "" {
+		return nil, fmt.Errorf("provide guildID")
+	}
+	return r.querySchedules(ctx, SelectAllSchedules+" WHERE guild_id = ? ORDER BY next_fire_at", guildID)
+}
+
+// GetDueSchedules returns schedules across all guilds whose next fire time is at or before t
+func (r *scheduleRepo) GetDueSchedules(ctx context.Context, t time.Time) ([]pomomo.ExistingSessionScheduleRecord, error) {
+	return r.querySchedules(ctx, SelectAllSchedules+" WHERE next_fire_at <= ? ORDER BY next_fire_at", t.Unix())
+}
+
+func (r *scheduleRepo) querySchedules(ctx context.Context, query string, args ...any) ([]pomomo.ExistingSessionScheduleRecord, error) {
+	r.l.Debug("getting schedules", "query", query, "args", args)
+	rows, err := r.dbGetter(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint
+
+	var schedules []pomomo.ExistingSessionScheduleRecord
+	for rows.Next() {
+		s, err := extractSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+func extractSchedule(s sqliteutil.Scannable) (pomomo.ExistingSessionScheduleRecord, error) {
+	var e scheduleEntity
+	if err := s.Scan(&e.ID, &e.GuildID, &e.TextChannelID, &e.VoiceChannelID, &e.CreatorUserID, &e.Weekdays, &e.Hour, &e.Minute, &e.PingRoleID, &e.PresetName, &e.NextFireAt, &e.CreatedAt, &e.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return pomomo.ExistingSessionScheduleRecord{}, ErrNotFound
+		}
+		return pomomo.ExistingSessionScheduleRecord{}, err
+	}
+
+	return mapToExistingScheduleRecord(e), nil
+}
+
+func mapToScheduleEntity(schedule pomomo.ExistingSessionScheduleRecord) scheduleEntity {
+	return scheduleEntity{
+		ID:             string(schedule.ID),
+		GuildID:        schedule.GuildID,
+		TextChannelID:  string(schedule.TextCID),
+		VoiceChannelID: string(schedule.VoiceCID),
+		CreatorUserID:  schedule.CreatorUserID,
+		Weekdays:       uint8(schedule.Recurrence.Days),
+		Hour:           schedule.Recurrence.Hour,
+		Minute:         schedule.Recurrence.Minute,
+		PingRoleID:     schedule.PingRoleID,
+		PresetName:     schedule.PresetName,
+		NextFireAt:     schedule.NextFireAt.Unix(),
+		CreatedAt:      schedule.CreatedAt.Unix(),
+		UpdatedAt:      schedule.UpdatedAt.Unix(),
+	}
+}
+
+func mapToExistingScheduleRecord(e scheduleEntity) pomomo.ExistingSessionScheduleRecord {
+	return pomomo.ExistingSessionScheduleRecord{
+		ExistingRecord: pomomo.ExistingRecord[pomomo.ScheduleID]{
+			ID:        pomomo.ScheduleID(e.ID),
+			CreatedAt: time.Unix(e.CreatedAt, 0),
+			UpdatedAt: time.Unix(e.UpdatedAt, 0),
+		},
+		SessionScheduleRecord: pomomo.SessionScheduleRecord{
+			GuildID:       e.GuildID,
+			TextCID:       pomomo.TextChannelID(e.TextChannelID),
+			VoiceCID:      pomomo.VoiceChannelID(e.VoiceChannelID),
+			CreatorUserID: e.CreatorUserID,
+			Recurrence: pomomo.Recurrence{
+				Days:   pomomo.WeekdaySet(e.Weekdays),
+				Hour:   e.Hour,
+				Minute: e.Minute,
+			},
+			PingRoleID: e.PingRoleID,
+			PresetName: e.PresetName,
+			NextFireAt: time.Unix(e.NextFireAt, 0),
+		},
+	}
+}
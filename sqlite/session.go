@@ -6,6 +6,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	txStdLib "github.com/Thiht/transactor/stdlib"
@@ -17,8 +18,8 @@ import (
 )
 
 const (
-	SelectAllSessions = "SELECT id, guild_id, text_channel_id, voice_channel_id, message_id, interval_started_at, time_remaining_at_start, current_interval, status, created_at, updated_at FROM sessions"
-	SelectAllSettings = "SELECT session_id, pomodoro_duration, short_break_duration, long_break_duration, intervals, no_mute, no_deafen, created_at, updated_at FROM session_settings"
+	SelectAllSessions = "SELECT id, guild_id, text_channel_id, voice_channel_id, message_id, host_user_id, schedule_id, interval_started_at, time_remaining_at_start, current_interval, sequence_index, break_duration, status, completed_pomodoros, skips, long_breaks, ended_at, created_at, updated_at FROM sessions"
+	SelectAllSettings = "SELECT session_id, pomodoro_duration, short_break_duration, long_break_duration, intervals, no_mute, no_deafen, max_duration, sequence, mode, break_ratio, goal_rounds, goal_until, warning, ambient, volume, created_at, updated_at FROM session_settings"
 )
 
 type sessionEntity struct {
@@ -27,10 +28,18 @@ type sessionEntity struct {
 	TextChannelID          string
 	VoiceChannelID         string
 	MessageID              string
+	HostUserID             string
+	ScheduleID             string
 	IntervalStartedAt      int64
 	TimeRemainingAtStartMS int64
 	CurrentInterval        uint8
+	SequenceIndex          int
+	BreakDuration          int
 	Status                 uint8
+	CompletedPomodoros     int
+	Skips                  int
+	LongBreaks             int
+	EndedAt                int64 // zero until the session ends
 	CreatedAt              int64
 	UpdatedAt              int64
 }
@@ -43,6 +52,15 @@ type sessionSettingsEntity struct {
 	Intervals          int
 	NoMute             bool
 	NoDeafen           bool
+	MaxDuration        int
+	Sequence           string
+	Mode               uint8
+	BreakRatio         float64
+	GoalRounds         int
+	GoalUntil          int64 // zero for no end time
+	Warning            int
+	Ambient            bool
+	Volume             int
 	CreatedAt          int64
 	UpdatedAt          int64
 }
@@ -76,14 +94,22 @@ func (r *sessionRepo) InsertSession(ctx context.Context, session pomomo.SessionR
 		e.TextChannelID,
 		e.VoiceChannelID,
 		e.MessageID,
+		e.HostUserID,
+		e.ScheduleID,
 		e.IntervalStartedAt,
 		e.TimeRemainingAtStartMS,
 		e.CurrentInterval,
+		e.SequenceIndex,
+		e.BreakDuration,
 		e.Status,
+		e.CompletedPomodoros,
+		e.Skips,
+		e.LongBreaks,
+		e.EndedAt,
 		e.CreatedAt,
 		e.UpdatedAt,
 	}
-	query := "INSERT INTO sessions (id, guild_id, text_channel_id, voice_channel_id, message_id, interval_started_at, time_remaining_at_start, current_interval, status, created_at, updated_at) VALUES " + sqliteutil.GenerateParameters(len(args))
+	query := "INSERT INTO sessions (id, guild_id, text_channel_id, voice_channel_id, message_id, host_user_id, schedule_id, interval_started_at, time_remaining_at_start, current_interval, sequence_index, break_duration, status, completed_pomodoros, skips, long_breaks, ended_at, created_at, updated_at) VALUES " + sqliteutil.GenerateParameters(len(args))
 	r.l.Debug("creating session", "query", query, "args", args)
 	_, err := db.ExecContext(ctx, query, args...)
 	if err != nil {
@@ -103,16 +129,24 @@ func (r *sessionRepo) UpdateSession(ctx context.Context, id pomomo.SessionID, s
 	existing.UpdatedAt = time.Now()
 	e := mapToSessionEntity(existing)
 
-	query := "UPDATE sessions SET guild_id = ?, text_channel_id = ?, voice_channel_id = ?, message_id = ?, interval_started_at = ?, time_remaining_at_start = ?, current_interval = ?, status = ?, updated_at = ? WHERE id = ?"
+	query := "UPDATE sessions SET guild_id = ?, text_channel_id = ?, voice_channel_id = ?, message_id = ?, host_user_id = ?, schedule_id = ?, interval_started_at = ?, time_remaining_at_start = ?, current_interval = ?, sequence_index = ?, break_duration = ?, status = ?, completed_pomodoros = ?, skips = ?, long_breaks = ?, ended_at = ?, updated_at = ? WHERE id = ?"
 	args := []any{
 		e.GuildID,
 		e.TextChannelID,
 		e.VoiceChannelID,
 		e.MessageID,
+		e.HostUserID,
+		e.ScheduleID,
 		e.IntervalStartedAt,
 		e.TimeRemainingAtStartMS,
 		e.CurrentInterval,
+		e.SequenceIndex,
+		e.BreakDuration,
 		e.Status,
+		e.CompletedPomodoros,
+		e.Skips,
+		e.LongBreaks,
+		e.EndedAt,
 		e.UpdatedAt,
 		e.ID,
 	}
@@ -155,19 +189,44 @@ func (r *sessionRepo) GetSession(ctx context.Context, id pomomo.SessionID) (pomo
 	return extractSession(row)
 }
 
-func (r *sessionRepo) GetSessionsByStatus(ctx context.Context, statuses ...pomomo.SessionStatus) ([]pomomo.ExistingSessionRecord, error) {
-	if len(statuses) == 0 {
-		return nil, nil
+// GetSessions returns sessions matching the filter, most recently created first
+func (r *sessionRepo) GetSessions(ctx context.Context, filter pomomo.SessionFilter) ([]pomomo.ExistingSessionRecord, error) {
+	var conditions []string
+	var args []any
+	if filter.GuildID != "" {
+		conditions = append(conditions, "guild_id = ?")
+		args = append(args, filter.GuildID)
+	}
+	if filter.TextCID != "" {
+		conditions = append(conditions, "text_channel_id = ?")
+		args = append(args, string(filter.TextCID))
+	}
+	if filter.VoiceCID != "" {
+		conditions = append(conditions, "voice_channel_id = ?")
+		args = append(args, string(filter.VoiceCID))
+	}
+	if len(filter.Statuses) > 0 {
+		conditions = append(conditions, "status IN "+sqliteutil.GenerateParameters(len(filter.Statuses)))
+		for _, s := range filter.Statuses {
+			args = append(args, uint8(s))
+		}
+	}
+	if !filter.CreatedAfter.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.CreatedAfter.Unix())
+	}
+	if !filter.CreatedBefore.IsZero() {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, filter.CreatedBefore.Unix())
 	}
 
-	db := r.dbGetter(ctx)
-	query := fmt.Sprintf("%s WHERE status IN %s", SelectAllSessions, sqliteutil.GenerateParameters(len(statuses)))
-	log.Debug("getting sessions by status", "query", query, "statuses", statuses)
-	var statusInts []any
-	for _, s := range statuses {
-		statusInts = append(statusInts, uint8(s))
+	query := SelectAllSessions
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
 	}
-	rows, err := db.QueryContext(ctx, query, statusInts...)
+	query += " ORDER BY created_at DESC"
+	r.l.Debug("getting sessions", "query", query, "args", args)
+	rows, err := r.dbGetter(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -187,6 +246,28 @@ func (r *sessionRepo) GetSessionsByStatus(ctx context.Context, statuses ...pomom
 	return sessions, nil
 }
 
+// DeleteEndedSessions deletes sessions, along with their dependent rows, that ended before the cutoff
+func (r *sessionRepo) DeleteEndedSessions(ctx context.Context, endedBefore time.Time) (int64, error) {
+	db := r.dbGetter(ctx)
+	const ended = "SELECT id FROM sessions WHERE status = ? AND ended_at > 0 AND ended_at < ?"
+	args := []any{uint8(pomomo.SessionEnded), endedBefore.Unix()}
+	for _, table := range []string{"session_settings", "session_participant_stats"} {
+		query := fmt.Sprintf("DELETE FROM %s WHERE session_id IN (%s)", table, ended)
+		r.l.Debug("deleting ended session rows", "query", query, "args", args)
+		if _, err := db.ExecContext(ctx, query, args...); err != nil {
+			return 0, err
+		}
+	}
+
+	query := "DELETE FROM sessions WHERE status = ? AND ended_at > 0 AND ended_at < ?"
+	r.l.Debug("deleting ended sessions", "query", query, "args", args)
+	res, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
 func (r *sessionRepo) InsertSettings(ctx context.Context, settings pomomo.SessionSettingsRecord) (pomomo.ExistingSessionSettingsRecord, error) {
 	if settings.SessionID == "" {
 		return pomomo.ExistingSessionSettingsRecord{}, fmt.Errorf("provide required field 'SessionID'")
@@ -208,10 +289,19 @@ func (r *sessionRepo) InsertSettings(ctx context.Context, settings pomomo.Sessio
 		e.Intervals,
 		e.NoMute,
 		e.NoDeafen,
+		e.MaxDuration,
+		e.Sequence,
+		e.Mode,
+		e.BreakRatio,
+		e.GoalRounds,
+		e.GoalUntil,
+		e.Warning,
+		e.Ambient,
+		e.Volume,
 		e.CreatedAt,
 		e.UpdatedAt,
 	}
-	query := "INSERT INTO session_settings (session_id, pomodoro_duration, short_break_duration, long_break_duration, intervals, no_mute, no_deafen, created_at, updated_at) VALUES " + sqliteutil.GenerateParameters(len(args))
+	query := "INSERT INTO session_settings (session_id, pomodoro_duration, short_break_duration, long_break_duration, intervals, no_mute, no_deafen, max_duration, sequence, mode, break_ratio, goal_rounds, goal_until, warning, ambient, volume, created_at, updated_at) VALUES " + sqliteutil.GenerateParameters(len(args))
 	r.l.Debug("creating session settings", "query", query, "args", args)
 	_, err := db.ExecContext(ctx, query, args...)
 	if err != nil {
@@ -221,6 +311,45 @@ func (r *sessionRepo) InsertSettings(ctx context.Context, settings pomomo.Sessio
 	return existingRecord, nil
 }
 
+func (r *sessionRepo) UpdateSettings(ctx context.Context, id pomomo.SessionID, settings pomomo.SessionSettingsRecord) (pomomo.ExistingSessionSettingsRecord, error) {
+	existing, err := r.GetSettings(ctx, id)
+	if err != nil {
+		return existing, err
+	}
+
+	existing.SessionSettingsRecord = settings
+	existing.SessionID = id
+	existing.UpdatedAt = time.Now()
+	e := mapToSessionSettingsEntity(existing)
+
+	query := "UPDATE session_settings SET pomodoro_duration = ?, short_break_duration = ?, long_break_duration = ?, intervals = ?, no_mute = ?, no_deafen = ?, max_duration = ?, sequence = ?, mode = ?, break_ratio = ?, goal_rounds = ?, goal_until = ?, warning = ?, ambient = ?, volume = ?, updated_at = ? WHERE session_id = ?"
+	args := []any{
+		e.PomodoroDuration,
+		e.ShortBreakDuration,
+		e.LongBreakDuration,
+		e.Intervals,
+		e.NoMute,
+		e.NoDeafen,
+		e.MaxDuration,
+		e.Sequence,
+		e.Mode,
+		e.BreakRatio,
+		e.GoalRounds,
+		e.GoalUntil,
+		e.Warning,
+		e.Ambient,
+		e.Volume,
+		e.UpdatedAt,
+		e.SessionID,
+	}
+	r.l.Debug("updating session settings", "query", query, "args", args)
+	if _, err := r.dbGetter(ctx).ExecContext(ctx, query, args...); err != nil {
+		return pomomo.ExistingSessionSettingsRecord{}, err
+	}
+
+	return existing, nil
+}
+
 func (r *sessionRepo) DeleteSettings(ctx context.Context, id pomomo.SessionID) (pomomo.ExistingSessionSettingsRecord, error) {
 	existing, err := r.GetSettings(ctx, id)
 	if err != nil {
@@ -253,7 +382,7 @@ func (r *sessionRepo) GetSettings(ctx context.Context, id pomomo.SessionID) (pom
 
 func extractSession(s sqliteutil.Scannable) (pomomo.ExistingSessionRecord, error) {
 	var e sessionEntity
-	if err := s.Scan(&e.ID, &e.GuildID, &e.TextChannelID, &e.VoiceChannelID, &e.MessageID, &e.IntervalStartedAt, &e.TimeRemainingAtStartMS, &e.CurrentInterval, &e.Status, &e.CreatedAt, &e.UpdatedAt); err != nil {
+	if err := s.Scan(&e.ID, &e.GuildID, &e.TextChannelID, &e.VoiceChannelID, &e.MessageID, &e.HostUserID, &e.ScheduleID, &e.IntervalStartedAt, &e.TimeRemainingAtStartMS, &e.CurrentInterval, &e.SequenceIndex, &e.BreakDuration, &e.Status, &e.CompletedPomodoros, &e.Skips, &e.LongBreaks, &e.EndedAt, &e.CreatedAt, &e.UpdatedAt); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return pomomo.ExistingSessionRecord{}, ErrNotFound
 		}
@@ -265,33 +394,55 @@ func extractSession(s sqliteutil.Scannable) (pomomo.ExistingSessionRecord, error
 
 func extractSessionSettings(s sqliteutil.Scannable) (pomomo.ExistingSessionSettingsRecord, error) {
 	var e sessionSettingsEntity
-	if err := s.Scan(&e.SessionID, &e.PomodoroDuration, &e.ShortBreakDuration, &e.LongBreakDuration, &e.Intervals, &e.NoMute, &e.NoDeafen, &e.CreatedAt, &e.UpdatedAt); err != nil {
+	if err := s.Scan(&e.SessionID, &e.PomodoroDuration, &e.ShortBreakDuration, &e.LongBreakDuration, &e.Intervals, &e.NoMute, &e.NoDeafen, &e.MaxDuration, &e.Sequence, &e.Mode, &e.BreakRatio, &e.GoalRounds, &e.GoalUntil, &e.Warning, &e.Ambient, &e.Volume, &e.CreatedAt, &e.UpdatedAt); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return pomomo.ExistingSessionSettingsRecord{}, ErrNotFound
 		}
 		return pomomo.ExistingSessionSettingsRecord{}, err
 	}
 
-	return mapToExistingSessionSettingsRecord(e), nil
+	settings := mapToExistingSessionSettingsRecord(e)
+	seq, err := pomomo.ParseIntervalSequence(e.Sequence)
+	if err != nil {
+		return pomomo.ExistingSessionSettingsRecord{}, fmt.Errorf("invalid sequence for session %s: %w", e.SessionID, err)
+	}
+	settings.Sequence = seq
+	return settings, nil
 }
 
 func mapToSessionEntity(session pomomo.ExistingSessionRecord) sessionEntity {
+	var endedAt int64
+	if !session.EndedAt.IsZero() {
+		endedAt = session.EndedAt.Unix()
+	}
 	return sessionEntity{
 		ID:                     string(session.ID),
 		GuildID:                session.GuildID,
 		TextChannelID:          string(session.TextCID),
 		VoiceChannelID:         string(session.VoiceCID),
 		MessageID:              session.MessageID,
+		HostUserID:             session.HostUserID,
+		ScheduleID:             string(session.ScheduleID),
 		IntervalStartedAt:      session.IntervalStartedAt.Unix(),
 		TimeRemainingAtStartMS: session.TimeRemainingAtStart.Milliseconds(),
 		CurrentInterval:        uint8(session.CurrentInterval),
+		SequenceIndex:          session.SequenceIndex,
+		BreakDuration:          int(session.BreakDuration.Seconds()),
 		Status:                 uint8(session.Status),
+		CompletedPomodoros:     session.Stats.CompletedPomodoros,
+		Skips:                  session.Stats.Skips,
+		LongBreaks:             session.Stats.LongBreaks,
+		EndedAt:                endedAt,
 		CreatedAt:              session.CreatedAt.Unix(),
 		UpdatedAt:              session.UpdatedAt.Unix(),
 	}
 }
 
 func mapToSessionSettingsEntity(settings pomomo.ExistingSessionSettingsRecord) sessionSettingsEntity {
+	var goalUntil int64
+	if !settings.GoalUntil.IsZero() {
+		goalUntil = settings.GoalUntil.Unix()
+	}
 	return sessionSettingsEntity{
 		SessionID:          string(settings.SessionID),
 		PomodoroDuration:   int(settings.Pomodoro.Seconds()),
@@ -300,12 +451,25 @@ func mapToSessionSettingsEntity(settings pomomo.ExistingSessionSettingsRecord) s
 		Intervals:          settings.Intervals,
 		NoMute:             settings.NoMute,
 		NoDeafen:           settings.NoDeafen,
+		MaxDuration:        int(settings.MaxDuration.Seconds()),
+		Sequence:           settings.Sequence.String(),
+		Mode:               uint8(settings.Mode),
+		BreakRatio:         settings.BreakRatio,
+		GoalRounds:         settings.GoalRounds,
+		GoalUntil:          goalUntil,
+		Warning:            int(settings.Warning.Seconds()),
+		Ambient:            settings.Ambient,
+		Volume:             settings.Volume,
 		CreatedAt:          settings.CreatedAt.Unix(),
 		UpdatedAt:          settings.UpdatedAt.Unix(),
 	}
 }
 
 func mapToExistingSessionRecord(e sessionEntity) pomomo.ExistingSessionRecord {
+	var endedAt time.Time
+	if e.EndedAt != 0 {
+		endedAt = time.Unix(e.EndedAt, 0)
+	}
 	return pomomo.ExistingSessionRecord{
 		ExistingRecord: pomomo.ExistingRecord[pomomo.SessionID]{
 			ID:        pomomo.SessionID(e.ID),
@@ -317,16 +481,30 @@ func mapToExistingSessionRecord(e sessionEntity) pomomo.ExistingSessionRecord {
 			TextCID:              pomomo.TextChannelID(e.TextChannelID),
 			VoiceCID:             pomomo.VoiceChannelID(e.VoiceChannelID),
 			MessageID:            e.MessageID,
+			HostUserID:           e.HostUserID,
+			ScheduleID:           pomomo.ScheduleID(e.ScheduleID),
 			IntervalStartedAt:    time.Unix(int64(e.IntervalStartedAt), 0),
 			TimeRemainingAtStart: time.Duration(e.TimeRemainingAtStartMS) * time.Millisecond,
 			CurrentInterval:      pomomo.SessionInterval(e.CurrentInterval),
+			SequenceIndex:        e.SequenceIndex,
+			BreakDuration:        time.Duration(e.BreakDuration) * time.Second,
 			Status:               pomomo.SessionStatus(e.Status),
+			Stats: pomomo.SessionStats{
+				CompletedPomodoros: e.CompletedPomodoros,
+				Skips:              e.Skips,
+				LongBreaks:         e.LongBreaks,
+			},
+			EndedAt: endedAt,
 			// NoDeafen moved to settings
 		},
 	}
 }
 
 func mapToExistingSessionSettingsRecord(e sessionSettingsEntity) pomomo.ExistingSessionSettingsRecord {
+	var goalUntil time.Time
+	if e.GoalUntil != 0 {
+		goalUntil = time.Unix(e.GoalUntil, 0)
+	}
 	return pomomo.ExistingSessionSettingsRecord{
 		ExistingRecord: pomomo.ExistingRecord[pomomo.SessionID]{
 			ID:        pomomo.SessionID(e.SessionID),
@@ -341,6 +519,15 @@ func mapToExistingSessionSettingsRecord(e sessionSettingsEntity) pomomo.Existing
 			Intervals:  e.Intervals,
 			NoMute:     e.NoMute,
 			NoDeafen:   e.NoDeafen,
+
+			MaxDuration: time.Duration(e.MaxDuration) * time.Second,
+			Mode:        pomomo.SessionMode(e.Mode),
+			BreakRatio:  e.BreakRatio,
+			GoalRounds:  e.GoalRounds,
+			GoalUntil:   goalUntil,
+			Warning:     time.Duration(e.Warning) * time.Second,
+			Ambient:     e.Ambient,
+			Volume:      e.Volume,
 		},
 	}
 }
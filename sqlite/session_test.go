@@ -0,0 +1,149 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	txStdLib "github.com/Thiht/transactor/stdlib"
+	"github.com/charmbracelet/log"
+	_ "modernc.org/sqlite"
+
+	"github.com/benjamonnguyen/pomomo-go"
+)
+
+// openTestDB opens an in-memory database with the bot's migrations applied
+func openTestDB(t *testing.T) txStdLib.DBGetter {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection would get its own in-memory database
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+
+	migrations, err := filepath.Glob("../cmd/bot/migrations/*.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(migrations)
+	for _, m := range migrations {
+		b, err := os.ReadFile(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec(string(b)); err != nil {
+			t.Fatalf("failed migration %s: %v", filepath.Base(m), err)
+		}
+	}
+	_, dbGetter := txStdLib.NewTransactor(db, txStdLib.NestedTransactionsSavepoints)
+	return dbGetter
+}
+
+func insertTestSession(t *testing.T, r *sessionRepo, guildID string, status pomomo.SessionStatus, endedAt time.Time) pomomo.ExistingSessionRecord {
+	t.Helper()
+	ctx := context.Background()
+	s, err := r.InsertSession(ctx, pomomo.SessionRecord{
+		GuildID:  guildID,
+		TextCID:  "t1",
+		VoiceCID: "v1",
+		Status:   status,
+		EndedAt:  endedAt,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.InsertSettings(ctx, pomomo.SessionSettingsRecord{SessionID: s.ID, Pomodoro: 25 * time.Minute}); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestDeleteEndedSessions(t *testing.T) {
+	ctx := context.Background()
+	r := NewSessionRepo(openTestDB(t), *log.Default())
+	now := time.Now()
+
+	old := insertTestSession(t, r, "g1", pomomo.SessionEnded, now.Add(-10*24*time.Hour))
+	// updated since it ended, which doesn't extend its retention
+	if _, err := r.UpdateSession(ctx, old.ID, old.SessionRecord); err != nil {
+		t.Fatal(err)
+	}
+	recent := insertTestSession(t, r, "g1", pomomo.SessionEnded, now.Add(-time.Hour))
+	running := insertTestSession(t, r, "g1", pomomo.SessionRunning, time.Time{})
+	// sessions that aren't ended are kept whatever their end time says
+	stale := insertTestSession(t, r, "g1", pomomo.SessionPaused, now.Add(-10*24*time.Hour))
+
+	n, err := r.DeleteEndedSessions(ctx, now.Add(-7*24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("DeleteEndedSessions() = %d, want 1", n)
+	}
+	if _, err := r.GetSession(ctx, old.ID); err != ErrNotFound {
+		t.Errorf("GetSession() for the pruned session error = %v, want ErrNotFound", err)
+	}
+	if _, err := r.GetSettings(ctx, old.ID); err != ErrNotFound {
+		t.Errorf("GetSettings() for the pruned session error = %v, want ErrNotFound", err)
+	}
+	for _, s := range []pomomo.ExistingSessionRecord{recent, running, stale} {
+		got, err := r.GetSession(ctx, s.ID)
+		if err != nil {
+			t.Errorf("GetSession() for a kept session: %v", err)
+		}
+		if !got.EndedAt.Equal(s.EndedAt.Truncate(time.Second)) {
+			t.Errorf("EndedAt = %v, want %v", got.EndedAt, s.EndedAt.Truncate(time.Second))
+		}
+		if _, err := r.GetSettings(ctx, s.ID); err != nil {
+			t.Errorf("GetSettings() for a kept session: %v", err)
+		}
+	}
+}
+
+func TestGetSessions(t *testing.T) {
+	ctx := context.Background()
+	r := NewSessionRepo(openTestDB(t), *log.Default())
+	ended := insertTestSession(t, r, "g1", pomomo.SessionEnded, time.Now())
+	running := insertTestSession(t, r, "g1", pomomo.SessionRunning, time.Time{})
+	other := insertTestSession(t, r, "g2", pomomo.SessionPaused, time.Time{})
+
+	tests := []struct {
+		name   string
+		filter pomomo.SessionFilter
+		want   []pomomo.ExistingSessionRecord
+	}{
+		{"all", pomomo.SessionFilter{}, []pomomo.ExistingSessionRecord{ended, running, other}},
+		{"guild", pomomo.SessionFilter{GuildID: "g1"}, []pomomo.ExistingSessionRecord{ended, running}},
+		{"statuses", pomomo.SessionFilter{Statuses: []pomomo.SessionStatus{pomomo.SessionRunning, pomomo.SessionPaused}}, []pomomo.ExistingSessionRecord{running, other}},
+		{"guild and status", pomomo.SessionFilter{GuildID: "g2", Statuses: []pomomo.SessionStatus{pomomo.SessionRunning}}, nil},
+		{"created after", pomomo.SessionFilter{CreatedAfter: time.Now().Add(time.Hour)}, nil},
+		{"created before", pomomo.SessionFilter{CreatedBefore: time.Now().Add(time.Hour)}, []pomomo.ExistingSessionRecord{ended, running, other}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.GetSessions(ctx, tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var gotIDs, wantIDs []pomomo.SessionID
+			for _, s := range got {
+				gotIDs = append(gotIDs, s.ID)
+			}
+			for _, s := range tt.want {
+				wantIDs = append(wantIDs, s.ID)
+			}
+			// created in the same second, so the order isn't deterministic
+			slices.Sort(gotIDs)
+			slices.Sort(wantIDs)
+			if !slices.Equal(gotIDs, wantIDs) {
+				t.Errorf("GetSessions() = %v, want %v", gotIDs, wantIDs)
+			}
+		})
+	}
+}
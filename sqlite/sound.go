@@ -0,0 +1,137 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	txStdLib "github.com/Thiht/transactor/stdlib"
+	"github.com/charmbracelet/log"
+
+	"github.com/benjamonnguyen/deadsimple/db/sqliteutil"
+	"github.com/benjamonnguyen/pomomo-go"
+)
+
+const (
+	SelectAllSounds = "SELECT guild_id, sound, uploader_user_id, file_name, dca, duration_ms FROM guild_sounds"
+)
+
+type soundEntity struct {
+	GuildID        string
+	Sound          string
+	UploaderUserID string
+	FileName       string
+	DCA            []byte
+	DurationMS     int64
+}
+
+type soundRepo struct {
+	dbGetter txStdLib.DBGetter
+	l        log.Logger
+}
+
+func NewSoundRepo(dbGetter txStdLib.DBGetter, logger log.Logger) *soundRepo {
+	return &soundRepo{
+		dbGetter: dbGetter,
+		l:        logger,
+	}
+}
+
+// UpsertSound saves the guild's sound, replacing any previous upload for the same alert
+func (r *soundRepo) UpsertSound(ctx context.Context, sound pomomo.GuildSoundRecord) error {
+	if sound.GuildID == "" || sound.Sound == "" || len(sound.DCA) == 0 {
+		return fmt.Errorf("provide required fields 'GuildID', 'Sound' and 'DCA'")
+	}
+
+	now := time.Now().Unix()
+	e := mapToSoundEntity(sound)
+	args := []any{
+		e.GuildID,
+		e.Sound,
+		e.UploaderUserID,
+		e.FileName,
+		e.DCA,
+		e.DurationMS,
+		now,
+		now,
+	}
+	query := "INSERT INTO guild_sounds (guild_id, sound, uploader_user_id, file_name, dca, duration_ms, created_at, updated_at) VALUES " + sqliteutil.GenerateParameters(len(args)) +
+		" ON CONFLICT (guild_id, sound) DO UPDATE SET uploader_user_id = excluded.uploader_user_id, file_name = excluded.file_name, dca = excluded.dca, duration_ms = excluded.duration_ms, updated_at = excluded.updated_at"
+	// don't log the audio
+	r.l.Debug("upserting sound", "query", query, "guildID", e.GuildID, "sound", e.Sound, "bytes", len(e.DCA))
+	_, err := r.dbGetter(ctx).ExecContext(ctx, query, args...)
+	return err
+}
+
+// DeleteSounds deletes the guild's uploads for the given alerts, or all of them if none are given,
+// and returns how many were deleted
+func (r *soundRepo) DeleteSounds(ctx context.Context, guildID string, sounds ...pomomo.AlertSound) (int64, error) {
+	if guildID == "" {
+		return 0, fmt.Errorf("provide guildID")
+	}
+
+	query := "DELETE FROM guild_sounds WHERE guild_id = ?"
+	args := []any{guildID}
+	if len(sounds) > 0 {
+		query += " AND sound IN (" + strings.TrimSuffix(strings.Repeat("?, ", len(sounds)), ", ") + ")"
+		for _, s := range sounds {
+			args = append(args, string(s))
+		}
+	}
+	r.l.Debug("deleting sounds", "query", query, "args", args)
+	res, err := r.dbGetter(ctx).ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (r *soundRepo) GetSounds(ctx context.Context, guildID string) ([]pomomo.GuildSoundRecord, error) {
+	if guildID == "" {
+		return nil, fmt.Errorf("provide guildID")
+	}
+
+	query := SelectAllSounds + " WHERE guild_id = ?"
+	r.l.Debug("getting sounds", "query", query, "guildID", guildID)
+	rows, err := r.dbGetter(ctx).QueryContext(ctx, query, guildID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint
+
+	var sounds []pomomo.GuildSoundRecord
+	for rows.Next() {
+		var e soundEntity
+		if err := rows.Scan(&e.GuildID, &e.Sound, &e.UploaderUserID, &e.FileName, &e.DCA, &e.DurationMS); err != nil {
+			return nil, err
+		}
+		sounds = append(sounds, mapToSoundRecord(e))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return sounds, nil
+}
+
+func mapToSoundEntity(s pomomo.GuildSoundRecord) soundEntity {
+	return soundEntity{
+		GuildID:        s.GuildID,
+		Sound:          string(s.Sound),
+		UploaderUserID: s.UploaderUserID,
+		FileName:       s.FileName,
+		DCA:            s.DCA,
+		DurationMS:     s.Duration.Milliseconds(),
+	}
+}
+
+func mapToSoundRecord(e soundEntity) pomomo.GuildSoundRecord {
+	return pomomo.GuildSoundRecord{
+		GuildID:        e.GuildID,
+		Sound:          pomomo.AlertSound(e.Sound),
+		UploaderUserID: e.UploaderUserID,
+		FileName:       e.FileName,
+		DCA:            e.DCA,
+		Duration:       time.Duration(e.DurationMS) * time.Millisecond,
+	}
+}
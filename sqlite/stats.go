@@ -0,0 +1,294 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	txStdLib "github.com/Thiht/transactor/stdlib"
+	"github.com/charmbracelet/log"
+
+	"github.com/benjamonnguyen/deadsimple/db/sqliteutil"
+	"github.com/benjamonnguyen/pomomo-go"
+)
+
+const (
+	SelectStatsSummary = "SELECT COALESCE(SUM(focus_duration), 0), COALESCE(SUM(completed_pomodoros), 0), COALESCE(SUM(breaks_taken), 0), COUNT(DISTINCT day), COUNT(DISTINCT user_id) FROM participant_stats"
+	SelectAllStreaks   = "SELECT guild_id, user_id, current_streak, longest_streak, last_active_day FROM participant_streaks"
+
+	SelectAllSessionParticipantStats = "SELECT session_id, user_id, focus_duration, joined_at, left_at FROM session_participant_stats"
+)
+
+type participantStatsEntity struct {
+	GuildID            string
+	UserID             string
+	Day                int64
+	FocusDuration      int
+	CompletedPomodoros int
+	BreaksTaken        int
+}
+
+type participantStatsRepo struct {
+	dbGetter txStdLib.DBGetter
+	l        log.Logger
+}
+
+func NewParticipantStatsRepo(dbGetter txStdLib.DBGetter, logger log.Logger) *participantStatsRepo {
+	return &participantStatsRepo{
+		dbGetter: dbGetter,
+		l:        logger,
+	}
+}
+
+// AddParticipantStats increments the user's stats for the record's day
+func (r *participantStatsRepo) AddParticipantStats(ctx context.Context, stats pomomo.ParticipantStatsRecord) error {
+	if stats.GuildID == "" || stats.UserID == "" || stats.Day.IsZero() {
+		return fmt.Errorf("provide required fields 'GuildID', 'UserID', and 'Day'")
+	}
+
+	now := time.Now().Unix()
+	e := mapToParticipantStatsEntity(stats)
+	args := []any{
+		e.GuildID,
+		e.UserID,
+		e.Day,
+		e.FocusDuration,
+		e.CompletedPomodoros,
+		e.BreaksTaken,
+		now,
+		now,
+	}
+	query := "INSERT INTO participant_stats (guild_id, user_id, day, focus_duration, completed_pomodoros, breaks_taken, created_at, updated_at) VALUES " + sqliteutil.GenerateParameters(len(args)) +
+		" ON CONFLICT (guild_id, user_id, day) DO UPDATE SET focus_duration = focus_duration + excluded.focus_duration, completed_pomodoros = completed_pomodoros + excluded.completed_pomodoros, breaks_taken = breaks_taken + excluded.breaks_taken, updated_at = excluded.updated_at"
+	r.l.Debug("adding participant stats", "query", query, "args", args)
+	_, err := r.dbGetter(ctx).ExecContext(ctx, query, args...)
+	return err
+}
+
+// GetUserStats sums the user's stats in the guild from the since day onwards
+func (r *participantStatsRepo) GetUserStats(ctx context.Context, guildID, userID string, since time.Time) (pomomo.StatsSummary, error) {
+	if guildID == "" || userID == "" {
+		return pomomo.StatsSummary{}, fmt.Errorf("provide guildID and userID")
+	}
+
+	query := SelectStatsSummary + " WHERE guild_id = ? AND user_id = ? AND day >= ?"
+	args := []any{guildID, userID, pomomo.StatsDay(since).Unix()}
+	r.l.Debug("getting user stats", "query", query, "args", args)
+	row := r.dbGetter(ctx).QueryRowContext(ctx, query, args...)
+	return extractStatsSummary(row)
+}
+
+// GetGuildStats sums the stats of all guild members from the since day onwards
+func (r *participantStatsRepo) GetGuildStats(ctx context.Context, guildID string, since time.Time) (pomomo.StatsSummary, error) {
+	if guildID == "" {
+		return pomomo.StatsSummary{}, fmt.Errorf("provide guildID")
+	}
+
+	query := SelectStatsSummary + " WHERE guild_id = ? AND day >= ?"
+	args := []any{guildID, pomomo.StatsDay(since).Unix()}
+	r.l.Debug("getting guild stats", "query", query, "args", args)
+	row := r.dbGetter(ctx).QueryRowContext(ctx, query, args...)
+	return extractStatsSummary(row)
+}
+
+// GetLeaderboard ranks guild members by the metric from the since day onwards
+func (r *participantStatsRepo) GetLeaderboard(ctx context.Context, guildID string, metric pomomo.LeaderboardMetric, since time.Time, limit int) ([]pomomo.LeaderboardEntry, error) {
+	if guildID == "" {
+		return nil, fmt.Errorf("provide guildID")
+	}
+
+	orderBy := "focus"
+	if metric == pomomo.LeaderboardPomodoros {
+		orderBy = "pomodoros"
+	}
+	query := fmt.Sprintf("SELECT user_id, SUM(focus_duration) AS focus, SUM(completed_pomodoros) AS pomodoros FROM participant_stats WHERE guild_id = ? AND day >= ? GROUP BY user_id ORDER BY %s DESC LIMIT ?", orderBy)
+	args := []any{guildID, pomomo.StatsDay(since).Unix(), limit}
+	r.l.Debug("getting leaderboard", "query", query, "args", args)
+	rows, err := r.dbGetter(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint
+
+	var entries []pomomo.LeaderboardEntry
+	for rows.Next() {
+		var e pomomo.LeaderboardEntry
+		var focusDuration int64
+		if err := rows.Scan(&e.UserID, &focusDuration, &e.CompletedPomodoros); err != nil {
+			return nil, err
+		}
+		e.FocusTime = time.Duration(focusDuration) * time.Second
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// UpdateStreak marks day as active, extending the streak if the previous day was also active
+func (r *participantStatsRepo) UpdateStreak(ctx context.Context, guildID, userID string, day time.Time) error {
+	if guildID == "" || userID == "" {
+		return fmt.Errorf("provide guildID and userID")
+	}
+
+	now := time.Now().Unix()
+	d := pomomo.StatsDay(day).Unix()
+	const nextStreak = "CASE WHEN last_active_day >= excluded.last_active_day THEN current_streak WHEN last_active_day = excluded.last_active_day - 86400 THEN current_streak + 1 ELSE 1 END"
+	query := "INSERT INTO participant_streaks (guild_id, user_id, current_streak, longest_streak, last_active_day, created_at, updated_at) VALUES (?, ?, 1, 1, ?, ?, ?)" +
+		" ON CONFLICT (guild_id, user_id) DO UPDATE SET current_streak = " + nextStreak + ", longest_streak = MAX(longest_streak, " + nextStreak + "), last_active_day = MAX(last_active_day, excluded.last_active_day), updated_at = excluded.updated_at"
+	args := []any{guildID, userID, d, now, now}
+	r.l.Debug("updating streak", "query", query, "args", args)
+	_, err := r.dbGetter(ctx).ExecContext(ctx, query, args...)
+	return err
+}
+
+// GetStreak returns ErrNotFound if the user has never completed a pomodoro in the guild
+func (r *participantStatsRepo) GetStreak(ctx context.Context, guildID, userID string) (pomomo.ParticipantStreakRecord, error) {
+	if guildID == "" || userID == "" {
+		return pomomo.ParticipantStreakRecord{}, fmt.Errorf("provide guildID and userID")
+	}
+
+	row := r.dbGetter(ctx).QueryRowContext(ctx, SelectAllStreaks+" WHERE guild_id = ? AND user_id = ?", guildID, userID)
+	return extractStreak(row)
+}
+
+// GetStreakLeaderboard ranks guild members by current streak, skipping broken streaks
+func (r *participantStatsRepo) GetStreakLeaderboard(ctx context.Context, guildID string, limit int) ([]pomomo.ParticipantStreakRecord, error) {
+	if guildID == "" {
+		return nil, fmt.Errorf("provide guildID")
+	}
+
+	query := SelectAllStreaks + " WHERE guild_id = ? AND last_active_day >= ? ORDER BY current_streak DESC, longest_streak DESC LIMIT ?"
+	args := []any{guildID, pomomo.StatsDay(time.Now()).AddDate(0, 0, -1).Unix(), limit}
+	r.l.Debug("getting streak leaderboard", "query", query, "args", args)
+	rows, err := r.dbGetter(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint
+
+	var streaks []pomomo.ParticipantStreakRecord
+	for rows.Next() {
+		streak, err := extractStreak(rows)
+		if err != nil {
+			return nil, err
+		}
+		streaks = append(streaks, streak)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return streaks, nil
+}
+
+// RecordSessionJoin records when the user first joined the session and clears any previous leave
+func (r *participantStatsRepo) RecordSessionJoin(ctx context.Context, sessionID pomomo.SessionID, userID string, at time.Time) error {
+	if sessionID == "" || userID == "" {
+		return fmt.Errorf("provide sessionID and userID")
+	}
+
+	now := time.Now().Unix()
+	query := "INSERT INTO session_participant_stats (session_id, user_id, focus_duration, joined_at, left_at, created_at, updated_at) VALUES (?, ?, 0, ?, 0, ?, ?)" +
+		" ON CONFLICT (session_id, user_id) DO UPDATE SET left_at = 0, updated_at = excluded.updated_at"
+	args := []any{sessionID, userID, at.Unix(), now, now}
+	r.l.Debug("recording session join", "query", query, "args", args)
+	_, err := r.dbGetter(ctx).ExecContext(ctx, query, args...)
+	return err
+}
+
+func (r *participantStatsRepo) RecordSessionLeave(ctx context.Context, sessionID pomomo.SessionID, userID string, at time.Time) error {
+	if sessionID == "" || userID == "" {
+		return fmt.Errorf("provide sessionID and userID")
+	}
+
+	query := "UPDATE session_participant_stats SET left_at = ?, updated_at = ? WHERE session_id = ? AND user_id = ?"
+	args := []any{at.Unix(), time.Now().Unix(), sessionID, userID}
+	r.l.Debug("recording session leave", "query", query, "args", args)
+	_, err := r.dbGetter(ctx).ExecContext(ctx, query, args...)
+	return err
+}
+
+func (r *participantStatsRepo) AddSessionFocus(ctx context.Context, sessionID pomomo.SessionID, userID string, focus time.Duration) error {
+	if sessionID == "" || userID == "" {
+		return fmt.Errorf("provide sessionID and userID")
+	}
+
+	now := time.Now().Unix()
+	query := "INSERT INTO session_participant_stats (session_id, user_id, focus_duration, joined_at, left_at, created_at, updated_at) VALUES (?, ?, ?, ?, 0, ?, ?)" +
+		" ON CONFLICT (session_id, user_id) DO UPDATE SET focus_duration = focus_duration + excluded.focus_duration, updated_at = excluded.updated_at"
+	args := []any{sessionID, userID, int(focus.Seconds()), now, now, now}
+	r.l.Debug("adding session focus", "query", query, "args", args)
+	_, err := r.dbGetter(ctx).ExecContext(ctx, query, args...)
+	return err
+}
+
+// GetSessionParticipantStats returns everyone that participated in the session, most focused first
+func (r *participantStatsRepo) GetSessionParticipantStats(ctx context.Context, sessionID pomomo.SessionID) ([]pomomo.SessionParticipantStatsRecord, error) {
+	if sessionID == "" {
+		return nil, fmt.Errorf("provide sessionID")
+	}
+
+	query := SelectAllSessionParticipantStats + " WHERE session_id = ? ORDER BY focus_duration DESC, joined_at"
+	r.l.Debug("getting session participant stats", "query", query, "session_id", sessionID)
+	rows, err := r.dbGetter(ctx).QueryContext(ctx, query, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint
+
+	var records []pomomo.SessionParticipantStatsRecord
+	for rows.Next() {
+		var record pomomo.SessionParticipantStatsRecord
+		var focusDuration, joinedAt, leftAt int64
+		if err := rows.Scan(&record.SessionID, &record.UserID, &focusDuration, &joinedAt, &leftAt); err != nil {
+			return nil, err
+		}
+		record.FocusTime = time.Duration(focusDuration) * time.Second
+		record.JoinedAt = time.Unix(joinedAt, 0)
+		if leftAt != 0 {
+			record.LeftAt = time.Unix(leftAt, 0)
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func extractStreak(s sqliteutil.Scannable) (pomomo.ParticipantStreakRecord, error) {
+	var r pomomo.ParticipantStreakRecord
+	var lastActiveDay int64
+	if err := s.Scan(&r.GuildID, &r.UserID, &r.CurrentStreak, &r.LongestStreak, &lastActiveDay); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return pomomo.ParticipantStreakRecord{}, ErrNotFound
+		}
+		return pomomo.ParticipantStreakRecord{}, err
+	}
+	r.LastActiveDay = time.Unix(lastActiveDay, 0).UTC()
+	return r, nil
+}
+
+func extractStatsSummary(s sqliteutil.Scannable) (pomomo.StatsSummary, error) {
+	var focusDuration int64
+	var summary pomomo.StatsSummary
+	if err := s.Scan(&focusDuration, &summary.CompletedPomodoros, &summary.BreaksTaken, &summary.ActiveDays, &summary.Members); err != nil {
+		return pomomo.StatsSummary{}, err
+	}
+	summary.FocusTime = time.Duration(focusDuration) * time.Second
+	return summary, nil
+}
+
+func mapToParticipantStatsEntity(stats pomomo.ParticipantStatsRecord) participantStatsEntity {
+	return participantStatsEntity{
+		GuildID:            stats.GuildID,
+		UserID:             stats.UserID,
+		Day:                pomomo.StatsDay(stats.Day).Unix(),
+		FocusDuration:      int(stats.FocusTime.Seconds()),
+		CompletedPomodoros: stats.CompletedPomodoros,
+		BreaksTaken:        stats.BreaksTaken,
+	}
+}
@@ -0,0 +1,210 @@
+package sqlite
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/benjamonnguyen/pomomo-go"
+)
+
+func addTestStats(t *testing.T, r *participantStatsRepo, stats ...pomomo.ParticipantStatsRecord) {
+	t.Helper()
+	for _, s := range stats {
+		if err := r.AddParticipantStats(context.Background(), s); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestParticipantStatsSummary(t *testing.T) {
+	ctx := context.Background()
+	r := NewParticipantStatsRepo(openTestDB(t), *log.Default())
+	day1 := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	addTestStats(t, r,
+		// stats within a day are bucketed into it
+		pomomo.ParticipantStatsRecord{GuildID: "g1", UserID: "u1", Day: day1.Add(9 * time.Hour), FocusTime: 30 * time.Minute, CompletedPomodoros: 1},
+		pomomo.ParticipantStatsRecord{GuildID: "g1", UserID: "u1", Day: day1.Add(23*time.Hour + 59*time.Minute), FocusTime: 20 * time.Minute, CompletedPomodoros: 1},
+		pomomo.ParticipantStatsRecord{GuildID: "g1", UserID: "u1", Day: day2, FocusTime: 10 * time.Minute, BreaksTaken: 1},
+		pomomo.ParticipantStatsRecord{GuildID: "g1", UserID: "u2", Day: day2.Add(time.Hour), FocusTime: 50 * time.Minute, CompletedPomodoros: 2},
+		pomomo.ParticipantStatsRecord{GuildID: "g2", UserID: "u1", Day: day2, FocusTime: time.Hour, CompletedPomodoros: 2},
+	)
+
+	tests := []struct {
+		name string
+		get  func() (pomomo.StatsSummary, error)
+		want pomomo.StatsSummary
+	}{
+		{"user", func() (pomomo.StatsSummary, error) { return r.GetUserStats(ctx, "g1", "u1", day1) },
+			pomomo.StatsSummary{FocusTime: time.Hour, CompletedPomodoros: 2, BreaksTaken: 1, ActiveDays: 2, Members: 1}},
+		// since is rounded down to the start of its day
+		{"user since", func() (pomomo.StatsSummary, error) { return r.GetUserStats(ctx, "g1", "u1", day2.Add(12*time.Hour)) },
+			pomomo.StatsSummary{FocusTime: 10 * time.Minute, BreaksTaken: 1, ActiveDays: 1, Members: 1}},
+		{"user in other guild", func() (pomomo.StatsSummary, error) { return r.GetUserStats(ctx, "g2", "u1", day1) },
+			pomomo.StatsSummary{FocusTime: time.Hour, CompletedPomodoros: 2, ActiveDays: 1, Members: 1}},
+		{"no stats", func() (pomomo.StatsSummary, error) { return r.GetUserStats(ctx, "g2", "u2", day1) },
+			pomomo.StatsSummary{}},
+		{"guild", func() (pomomo.StatsSummary, error) { return r.GetGuildStats(ctx, "g1", day1) },
+			pomomo.StatsSummary{FocusTime: 110 * time.Minute, CompletedPomodoros: 4, BreaksTaken: 1, ActiveDays: 2, Members: 2}},
+		{"guild since", func() (pomomo.StatsSummary, error) { return r.GetGuildStats(ctx, "g1", day2) },
+			pomomo.StatsSummary{FocusTime: time.Hour, CompletedPomodoros: 2, BreaksTaken: 1, ActiveDays: 1, Members: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.get()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("summary = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetLeaderboard(t *testing.T) {
+	ctx := context.Background()
+	r := NewParticipantStatsRepo(openTestDB(t), *log.Default())
+	today := pomomo.StatsDay(time.Now())
+	addTestStats(t, r,
+		pomomo.ParticipantStatsRecord{GuildID: "g1", UserID: "u1", Day: today, FocusTime: 60 * time.Minute, CompletedPomodoros: 1},
+		pomomo.ParticipantStatsRecord{GuildID: "g1", UserID: "u2", Day: today, FocusTime: 30 * time.Minute, CompletedPomodoros: 3},
+		pomomo.ParticipantStatsRecord{GuildID: "g1", UserID: "u3", Day: today, FocusTime: 20 * time.Minute, CompletedPomodoros: 1},
+		// summed across days in range
+		pomomo.ParticipantStatsRecord{GuildID: "g1", UserID: "u3", Day: today.AddDate(0, 0, -1), FocusTime: 25 * time.Minute, CompletedPomodoros: 1},
+		// out of range
+		pomomo.ParticipantStatsRecord{GuildID: "g1", UserID: "u4", Day: today.AddDate(0, 0, -7), FocusTime: 5 * time.Hour, CompletedPomodoros: 10},
+		// other guild
+		pomomo.ParticipantStatsRecord{GuildID: "g2", UserID: "u5", Day: today, FocusTime: 5 * time.Hour, CompletedPomodoros: 10},
+	)
+	since := pomomo.StatsRangeWeek.Since(time.Now())
+
+	tests := []struct {
+		name   string
+		metric pomomo.LeaderboardMetric
+		limit  int
+		want   []pomomo.LeaderboardEntry
+	}{
+		{"focus", pomomo.LeaderboardFocus, 10, []pomomo.LeaderboardEntry{
+			{UserID: "u1", FocusTime: 60 * time.Minute, CompletedPomodoros: 1},
+			{UserID: "u3", FocusTime: 45 * time.Minute, CompletedPomodoros: 2},
+			{UserID: "u2", FocusTime: 30 * time.Minute, CompletedPomodoros: 3},
+		}},
+		{"pomodoros", pomomo.LeaderboardPomodoros, 10, []pomomo.LeaderboardEntry{
+			{UserID: "u2", FocusTime: 30 * time.Minute, CompletedPomodoros: 3},
+			{UserID: "u3", FocusTime: 45 * time.Minute, CompletedPomodoros: 2},
+			{UserID: "u1", FocusTime: 60 * time.Minute, CompletedPomodoros: 1},
+		}},
+		{"limit", pomomo.LeaderboardFocus, 2, []pomomo.LeaderboardEntry{
+			{UserID: "u1", FocusTime: 60 * time.Minute, CompletedPomodoros: 1},
+			{UserID: "u3", FocusTime: 45 * time.Minute, CompletedPomodoros: 2},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.GetLeaderboard(ctx, "g1", tt.metric, since, tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("GetLeaderboard() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateStreak(t *testing.T) {
+	ctx := context.Background()
+	r := NewParticipantStatsRepo(openTestDB(t), *log.Default())
+	day := func(n int) time.Time {
+		return time.Date(2026, 10, 12, 12, 0, 0, 0, time.UTC).AddDate(0, 0, n)
+	}
+	tests := []struct {
+		name        string
+		days        []int
+		wantCurrent int
+		wantLongest int
+		wantLast    int
+	}{
+		{"first day", []int{0}, 1, 1, 0},
+		{"same day", []int{0, 0}, 1, 1, 0},
+		{"consecutive days", []int{0, 1, 2}, 3, 3, 2},
+		{"broken", []int{0, 1, 2, 4}, 1, 3, 4},
+		{"broken then longer", []int{0, 1, 3, 4, 5}, 3, 3, 5},
+		// days recorded late don't reset the streak
+		{"earlier day", []int{0, 1, 2, 1}, 3, 3, 2},
+		{"earlier gap day", []int{0, 2, 1}, 1, 1, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, d := range tt.days {
+				if err := r.UpdateStreak(ctx, "g1", tt.name, day(d)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := r.GetStreak(ctx, "g1", tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.CurrentStreak != tt.wantCurrent || got.LongestStreak != tt.wantLongest {
+				t.Errorf("streak = %d longest %d, want %d longest %d", got.CurrentStreak, got.LongestStreak, tt.wantCurrent, tt.wantLongest)
+			}
+			if want := pomomo.StatsDay(day(tt.wantLast)); !got.LastActiveDay.Equal(want) {
+				t.Errorf("LastActiveDay = %v, want %v", got.LastActiveDay, want)
+			}
+		})
+	}
+
+	if _, err := r.GetStreak(ctx, "g2", "first day"); err != ErrNotFound {
+		t.Errorf("GetStreak() in another guild error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestGetStreakLeaderboard(t *testing.T) {
+	ctx := context.Background()
+	r := NewParticipantStatsRepo(openTestDB(t), *log.Default())
+	today := time.Now()
+	streak := func(guildID, userID string, days ...int) {
+		for _, d := range days {
+			if err := r.UpdateStreak(ctx, guildID, userID, today.AddDate(0, 0, d)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	streak("g1", "u1", -2, -1, 0)
+	// still unbroken until the end of today
+	streak("g1", "u2", -2, -1)
+	streak("g1", "u3", -8, -7, -6, -5, -1, 0)
+	// broken
+	streak("g1", "u4", -7, -6, -5, -4, -3, -2)
+	streak("g2", "u5", -3, -2, -1, 0)
+
+	tests := []struct {
+		name  string
+		limit int
+		want  []string
+	}{
+		// ties go to the longest streak
+		{"all", 10, []string{"u1", "u3", "u2"}},
+		{"limit", 2, []string{"u1", "u3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			streaks, err := r.GetStreakLeaderboard(ctx, "g1", tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, s := range streaks {
+				got = append(got, s.UserID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("GetStreakLeaderboard() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
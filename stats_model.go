@@ -0,0 +1,97 @@
+package pomomo
+
+import "time"
+
+// ParticipantStatsRecord holds a user's stats for a guild, bucketed by UTC day
+type ParticipantStatsRecord struct {
+	GuildID, UserID string
+	Day             time.Time
+
+	//
+	FocusTime          time.Duration
+	CompletedPomodoros int
+	BreaksTaken        int
+}
+
+// StatsDay returns the start of the UTC day that t falls in
+func StatsDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// StatsSummary aggregates participant stats over a range of days
+type StatsSummary struct {
+	FocusTime          time.Duration
+	CompletedPomodoros int
+	BreaksTaken        int
+	ActiveDays         int
+	Members            int
+}
+
+type StatsRange string
+
+const (
+	StatsRangeDay   StatsRange = "day"
+	StatsRangeWeek  StatsRange = "week"
+	StatsRangeMonth StatsRange = "month"
+	StatsRangeAll   StatsRange = "all"
+)
+
+// Since returns the first stats day included in the range
+func (r StatsRange) Since(now time.Time) time.Time {
+	today := StatsDay(now)
+	switch r {
+	case StatsRangeDay:
+		return today
+	case StatsRangeWeek:
+		return today.AddDate(0, 0, -6)
+	case StatsRangeMonth:
+		return today.AddDate(0, 0, -29)
+	default:
+		return time.Unix(0, 0).UTC()
+	}
+}
+
+func (r StatsRange) String() string {
+	switch r {
+	case StatsRangeDay:
+		return "Today"
+	case StatsRangeWeek:
+		return "Past 7 days"
+	case StatsRangeMonth:
+		return "Past 30 days"
+	default:
+		return "All time"
+	}
+}
+
+// ParticipantStreakRecord tracks consecutive days with at least one completed pomodoro
+type ParticipantStreakRecord struct {
+	GuildID, UserID string
+
+	//
+	CurrentStreak int
+	LongestStreak int
+	LastActiveDay time.Time
+}
+
+// Streak returns the current streak as of now, which is broken if a day was missed
+func (r ParticipantStreakRecord) Streak(now time.Time) int {
+	if r.LastActiveDay.Before(StatsDay(now).AddDate(0, 0, -1)) {
+		return 0
+	}
+	return r.CurrentStreak
+}
+
+type LeaderboardMetric string
+
+const (
+	LeaderboardFocus     LeaderboardMetric = "focus"
+	LeaderboardPomodoros LeaderboardMetric = "pomodoros"
+	LeaderboardStreak    LeaderboardMetric = "streak"
+)
+
+type LeaderboardEntry struct {
+	UserID             string
+	FocusTime          time.Duration
+	CompletedPomodoros int
+}